@@ -0,0 +1,185 @@
+package testtxt
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+var archiveFormats = map[string]bool{
+	"(tar)": true,
+	"(tgz)": true,
+	"(zip)": true,
+}
+
+// packArchives removes entries below an archive from l and stores
+// them in the data of the archive.
+func packArchives(l []*fileEntry) ([]*fileEntry, error) {
+	var archives []*fileEntry
+	for _, e := range l {
+		if e.archive != "" {
+			archives = append(archives, e)
+		}
+	}
+	if archives == nil {
+		return l, nil
+	}
+	members := make(map[*fileEntry][]*fileEntry)
+	var result []*fileEntry
+ENTRY:
+	for _, e := range l {
+		name := path.Clean(e.name)
+		for _, a := range archives {
+			prefix := path.Clean(a.name) + "/"
+			if !strings.HasPrefix(name, prefix) {
+				continue
+			}
+			if e.archive != "" {
+				return nil, fmt.Errorf(
+					"archive %s at line %d must not be placed inside archive %s",
+					e.name, e.line, a.name)
+			}
+			m := *e
+			m.name = strings.TrimPrefix(name, prefix)
+			members[a] = append(members[a], &m)
+			continue ENTRY
+		}
+		result = append(result, e)
+	}
+	for _, a := range archives {
+		data, err := packArchive(a.archive, members[a])
+		if err != nil {
+			return nil, fmt.Errorf("can't create archive %s at line %d: %v",
+				a.name, a.line, err)
+		}
+		a.data = data
+	}
+	return result, nil
+}
+
+// packArchive returns an archive of given format with entries l.
+// Entries without modification time get the Unix epoch as time,
+// such that the archive is reproducible. In zip format, the exact time
+// is only available from the extended timestamp field, because MS-DOS
+// time starts in 1980.
+func packArchive(format string, l []*fileEntry) (string, error) {
+	var b bytes.Buffer
+	var err error
+	switch format {
+	case "tar":
+		err = writeTar(&b, l)
+	case "tgz":
+		w := gzip.NewWriter(&b)
+		if err = writeTar(w, l); err == nil {
+			err = w.Close()
+		}
+	case "zip":
+		err = writeZip(&b, l)
+	}
+	return b.String(), err
+}
+
+// memberData returns mode and content of archive entry e.
+func memberData(e *fileEntry) (fs.FileMode, []byte, error) {
+	mode := fs.FileMode(0644)
+	switch {
+	case e.dir:
+		mode = 0755
+	case e.link != "":
+		mode = 0777
+	}
+	if e.modeSet {
+		mode = e.mode
+	}
+	switch {
+	case e.link != "":
+		return mode, []byte(e.link), nil
+	case e.from != "":
+		data, err := os.ReadFile(e.from)
+		return mode, data, err
+	}
+	return mode, []byte(e.data), nil
+}
+
+func writeTar(w io.Writer, l []*fileEntry) error {
+	tw := tar.NewWriter(w)
+	for _, e := range l {
+		mode, data, err := memberData(e)
+		if err != nil {
+			return err
+		}
+		h := &tar.Header{
+			Name:    e.name,
+			Mode:    int64(mode),
+			ModTime: time.Unix(0, 0),
+		}
+		if !e.mtime.IsZero() {
+			h.ModTime = e.mtime
+		}
+		switch {
+		case e.dir:
+			h.Typeflag = tar.TypeDir
+			h.Name += "/"
+		case e.link != "":
+			h.Typeflag = tar.TypeSymlink
+			h.Linkname = e.link
+		default:
+			h.Typeflag = tar.TypeReg
+			h.Size = int64(len(data))
+		}
+		if err := tw.WriteHeader(h); err != nil {
+			return err
+		}
+		if h.Typeflag == tar.TypeReg {
+			if _, err := tw.Write(data); err != nil {
+				return err
+			}
+		}
+	}
+	return tw.Close()
+}
+
+func writeZip(w io.Writer, l []*fileEntry) error {
+	zw := zip.NewWriter(w)
+	for _, e := range l {
+		mode, data, err := memberData(e)
+		if err != nil {
+			return err
+		}
+		h := &zip.FileHeader{
+			Name:     e.name,
+			Method:   zip.Deflate,
+			Modified: time.Unix(0, 0).UTC(),
+		}
+		if !e.mtime.IsZero() {
+			h.Modified = e.mtime
+		}
+		switch {
+		case e.dir:
+			h.Name += "/"
+			h.Method = zip.Store
+			mode |= fs.ModeDir
+		case e.link != "":
+			mode |= fs.ModeSymlink
+		}
+		h.SetMode(mode)
+		fw, err := zw.CreateHeader(h)
+		if err != nil {
+			return err
+		}
+		if !e.dir {
+			if _, err := fw.Write(data); err != nil {
+				return err
+			}
+		}
+	}
+	return zw.Close()
+}
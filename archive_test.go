@@ -0,0 +1,139 @@
+package testtxt
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// describeMember describes an entry of an archive in a single line.
+func describeMember(name string, mode fmt.Stringer, mtime time.Time,
+	content string,
+) string {
+	return fmt.Sprintf("%s %s %s %q", name, mode,
+		mtime.UTC().Format(time.RFC3339), content)
+}
+
+// readArchive describes each entry of archive data of given format.
+func readArchive(t *testing.T, format, data string) []string {
+	t.Helper()
+	var result []string
+	if format == "zip" {
+		r, err := zip.NewReader(strings.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range r.File {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			result = append(result,
+				describeMember(f.Name, f.Mode(), f.Modified, string(b)))
+		}
+		return result
+	}
+	var r io.Reader = strings.NewReader(data)
+	if format == "tgz" {
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = zr
+	}
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b bytes.Buffer
+		if _, err := io.Copy(&b, tr); err != nil {
+			t.Fatal(err)
+		}
+		content := b.String()
+		if h.Typeflag == tar.TypeSymlink {
+			content = h.Linkname
+		}
+		result = append(result,
+			describeMember(h.Name, h.FileInfo().Mode(), h.ModTime, content))
+	}
+	return result
+}
+
+func TestArchive(t *testing.T) {
+	members := "---- %[1]s/a\nx\n" +
+		"---- %[1]s/bin/run 0755 @2020-01-02T03:04:05Z\n#!/bin/sh\n" +
+		"---- %[1]s/empty (dir)\n" +
+		"---- %[1]s/l -> a\n"
+	want := []string{
+		`a -rw-r--r-- 1970-01-01T00:00:00Z "x\n"`,
+		`bin/run -rwxr-xr-x 2020-01-02T03:04:05Z "#!/bin/sh\n"`,
+		`empty/ drwxr-xr-x 1970-01-01T00:00:00Z ""`,
+		`l Lrwxrwxrwx 1970-01-01T00:00:00Z "a"`,
+	}
+	for _, format := range []string{"tar", "tgz", "zip"} {
+		t.Run(format, func(t *testing.T) {
+			name := "x." + format
+			input := fmt.Sprintf("---- %s (%s)\n", name, format) +
+				fmt.Sprintf(members, name) + "---- other\ny\n"
+			m, err := SplitFiles(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m["other"] != "y\n" || len(m) != 2 {
+				t.Errorf("unexpected files %v", m)
+			}
+			got := readArchive(t, format, m[name])
+			if d := cmp.Diff(want, got); d != "" {
+				t.Error(d)
+			}
+			// Archive is reproducible.
+			m2, err := SplitFiles(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m[name] != m2[name] {
+				t.Error("archive differs when created again")
+			}
+		})
+	}
+}
+
+func TestArchiveError(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{
+			input: "---- x.tar (tar)\nx\n",
+			err:   "unexpected content for x.tar at line 1",
+		},
+		{
+			input: "---- x.tar (tar)\n---- x.tar/y.zip (zip)\n",
+			err:   "archive x.tar/y.zip at line 2 must not be placed inside archive x.tar",
+		},
+	}
+	for _, tc := range tests {
+		_, err := SplitFiles(tc.input)
+		if err == nil || err.Error() != tc.err {
+			t.Errorf("got error %v, want %q", err, tc.err)
+		}
+	}
+}
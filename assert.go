@@ -0,0 +1,40 @@
+package testtxt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// Assert compares expected and got like Diff and reports a difference
+// as error of t. It reports whether both are equal.
+func Assert(t testing.TB, expected, got any, opts ...CompareOption) bool {
+	t.Helper()
+	if d := Diff(expected, got, opts...); d != "" {
+		reportDiff(t, d)
+		return false
+	}
+	return true
+}
+
+// Diff returns differences between expected and got or the empty
+// string if both are equal.
+// Strings are normalized as given by option Normalize and compared
+// line by line, with differences shown as unified diff.
+// Other values are compared by cmp.Diff with options given by option
+// CmpOptions.
+// Options for diff rendering apply to both.
+func Diff(expected, got any, opts ...CompareOption) string {
+	c := newCompareConfig(opts)
+	e, ok1 := expected.(string)
+	g, ok2 := got.(string)
+	if ok1 && ok2 {
+		return c.diff("expected", "got", c.normalize(e), c.normalize(g))
+	}
+	d := cmp.Diff(expected, got, c.cmpOptions...)
+	if d == "" {
+		return ""
+	}
+	return c.render(strings.TrimPrefix(d, "\n"))
+}
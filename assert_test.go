@@ -0,0 +1,93 @@
+package testtxt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestDiff(t *testing.T) {
+	type point struct{ X, y int }
+	tests := []struct {
+		name     string
+		opts     []CompareOption
+		expected any
+		got      any
+		want     string // prefix of diff
+	}{
+		{
+			name:     "equal strings",
+			opts:     []CompareOption{Normalize(CollapseSpace)},
+			expected: "a  b\n",
+			got:      "a b \n",
+		},
+		{
+			name:     "strings differ",
+			expected: "a\nb\n",
+			got:      "a\nc\n",
+			want:     "--- expected\n+++ got\n@@ -1,2 +1,2 @@\n a\n-b\n+c\n",
+		},
+		{
+			name:     "string and other type",
+			expected: "1",
+			got:      1,
+			want:     "  any(\n",
+		},
+		{
+			name:     "equal values",
+			expected: []int{1, 2},
+			got:      []int{1, 2},
+		},
+		{
+			name:     "values differ",
+			expected: map[string]int{"a": 1},
+			got:      map[string]int{"a": 2},
+			want:     "  map[string]int{\n",
+		},
+		{
+			name:     "cmp options",
+			opts:     []CompareOption{CmpOptions(cmpopts.IgnoreUnexported(point{}))},
+			expected: point{X: 1, y: 1},
+			got:      point{X: 1, y: 2},
+		},
+		{
+			name:     "truncated",
+			opts:     []CompareOption{MaxDiffLines(1)},
+			expected: map[string]int{"a": 1},
+			got:      map[string]int{"a": 2},
+			want: "  map[string]int{\n" +
+				"... 3 more lines not shown, see option MaxDiffLines\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// cmp.Diff randomly uses non breaking spaces.
+			got := strings.ReplaceAll(
+				Diff(tc.expected, tc.got, tc.opts...), "\u00a0", " ")
+			if (got == "") != (tc.want == "") || !strings.HasPrefix(got, tc.want) {
+				t.Errorf("got diff\n%s\nwant prefix\n%s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAssert(t *testing.T) {
+	var ok bool
+	r := record(t, func(r *recorder) {
+		ok = Assert(r, []string{"a"}, []string{"b"})
+	})
+	if ok {
+		t.Error("got result true")
+	}
+	want := "error:   []string{\n- \t\"a\",\n+ \t\"b\",\n  }\n"
+	got := strings.ReplaceAll(strings.Join(r.msgs, ""), "\u00a0", " ")
+	if d := cmp.Diff(want, got); d != "" {
+		t.Error(d)
+	}
+	r = record(t, func(r *recorder) { ok = Assert(r, 1, 1) })
+	if !ok || r.msgs != nil {
+		t.Errorf("got %v %q", ok, r.msgs)
+	}
+}
@@ -0,0 +1,134 @@
+package testtxt
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Block is a test or a template definition in the source of a file of
+// test descriptions, as returned by SplitBlocks.
+type Block struct {
+	// Unexpanded text of title attribute, empty for template.
+	Title string
+	// Name of template, empty for test.
+	Templ string
+	// Byte offsets of block in source. Comment lines directly in
+	// front of the block belong to it. Empty lines and other
+	// comments belong to the preceding block.
+	Start, End int
+	// Text is the source of the block. Multi line text of the last
+	// attribute, that was only terminated by the next block, is
+	// terminated by =END= here, such that Text can be moved.
+	Text string
+	// Names of templates called in block, in order of first call.
+	Calls []string
+}
+
+// Calls of templates like [[name]] or [[name data]].
+var templCallRE = regexp.MustCompile(`\[\[([^\s\]]*)`)
+
+// SplitBlocks splits src, the source of a file of test descriptions,
+// into tests and templates. Source in front of the first block, e.g.
+// a header comment, is src[:l[0].Start].
+// It is an error, if a template is defined between attributes of a
+// test.
+func SplitBlocks(src []byte) ([]*Block, error) {
+	s := &state{src: src, rest: src}
+	s.titleAttr = s.firstAttr()
+	var l []*Block
+	var cur *Block
+	// Offset after end of text of last definition.
+	contentEnd := 0
+	// Last text of cur is only terminated by the next block.
+	open := false
+	start := func(b *Block, pos int) {
+		b.Start = commentStart(src, contentEnd, pos)
+		if cur != nil {
+			cur.End = b.Start
+			cur.Text = closeBlock(src[cur.Start:cur.End], open)
+		}
+		cur = b
+		l = append(l, b)
+	}
+	for {
+		name, pos, err := s.readDef()
+		if err != nil {
+			return nil, err
+		}
+		if name == "" {
+			break
+		}
+		if name == "SUBST" {
+			s.skipLine()
+			continue
+		}
+		if name == "TEMPL" {
+			tName, _, err := s.readTemplName()
+			if err != nil {
+				return nil, err
+			}
+			start(&Block{Templ: tName}, pos)
+		}
+		multi := strings.TrimSpace(s.getLine()) == ""
+		text, _ := s.readText()
+		if base, _, _ := strings.Cut(name, ":"); base == s.titleAttr {
+			start(&Block{Title: text}, pos)
+		} else if cur == nil || name != "TEMPL" && cur.Templ != "" {
+			line, _ := s.position(pos)
+			return nil, fmt.Errorf("=%s= at line %d doesn't belong to a test",
+				name, line)
+		}
+		open = multi
+		if bytes.HasSuffix(src[:s.offset()], []byte("=END=")) {
+			s.skipLine()
+			open = false
+		}
+		end := s.offset()
+		if s.applySubst(text); s.offset() != end {
+			open = false
+		}
+		for _, m := range templCallRE.FindAllStringSubmatch(text, -1) {
+			if !slices.Contains(cur.Calls, m[1]) {
+				cur.Calls = append(cur.Calls, m[1])
+			}
+		}
+		contentEnd = s.offset()
+	}
+	if cur != nil {
+		cur.End = len(src)
+		cur.Text = closeBlock(src[cur.Start:], open)
+	}
+	return l, nil
+}
+
+// commentStart returns the offset of the first comment line, that
+// directly precedes the line with offset pos, but not before offset
+// limit. If no such line exists, the start of line of pos is returned.
+func commentStart(src []byte, limit, pos int) int {
+	start := bytes.LastIndexByte(src[:pos], '\n') + 1
+	for start > limit {
+		prev := bytes.LastIndexByte(src[:start-1], '\n') + 1
+		line := bytes.TrimSpace(src[prev:start])
+		if prev < limit || len(line) == 0 || line[0] != '#' {
+			break
+		}
+		start = prev
+	}
+	return start
+}
+
+// closeBlock returns source b of a block. If open is set, the last
+// text of b is terminated by =END=.
+func closeBlock(b []byte, open bool) string {
+	text := string(b)
+	if !open {
+		return text
+	}
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	return text + "=END=\n"
+}
@@ -0,0 +1,58 @@
+package testtxt
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSplitBlocks(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []Block
+		err  string
+	}{
+		{
+			name: "tests and templates",
+			src: "# header\n\n=TEMPL=t\nx [[u]]\n=END=\n" +
+				"# comment of a\n=TITLE=a\n=IN=\n[[t 1]]\n[[t]]\n=END=\n\n" +
+				"=TITLE=b\n=IN=\ny\n",
+			want: []Block{
+				{Templ: "t", Start: 10, End: 33,
+					Text: "=TEMPL=t\nx [[u]]\n=END=\n", Calls: []string{"u"}},
+				{Title: "a", Start: 33, End: 83,
+					Text:  "# comment of a\n=TITLE=a\n=IN=\n[[t 1]]\n[[t]]\n=END=\n\n",
+					Calls: []string{"t"}},
+				{Title: "b", Start: 83, End: 99,
+					Text: "=TITLE=b\n=IN=\ny\n=END=\n"},
+			},
+		},
+		{
+			name: "template inside test",
+			src:  "=TITLE=a\n=TEMPL=t\nx\n=IN=y\n",
+			err:  "=IN= at line 4 doesn't belong to a test",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			l, err := SplitBlocks([]byte(tc.src))
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Errorf("got error %v, want %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []Block
+			for _, b := range l {
+				got = append(got, *b)
+			}
+			if d := cmp.Diff(tc.want, got); d != "" {
+				t.Error(d)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package testtxt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"reflect"
+	"testing"
+)
+
+// RunResult is the captured outcome of a command or function.
+type RunResult struct {
+	Stdout string
+	Stderr string
+	Exit   int
+}
+
+// CaptureCmd runs cmd and captures its standard output, standard error
+// and exit code. Fields Stdout and Stderr of cmd are overwritten.
+// An error is returned, if cmd couldn't be run; a non zero exit code
+// isn't an error.
+func CaptureCmd(cmd *exec.Cmd) (RunResult, error) {
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	r := RunResult{Stdout: stdout.String(), Stderr: stderr.String()}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		r.Exit = exitErr.ExitCode()
+		err = nil
+	}
+	return r, err
+}
+
+// CaptureFunc calls fn with writers for standard output and standard
+// error and captures their content together with the exit code
+// returned by fn.
+func CaptureFunc(fn func(stdout, stderr io.Writer) int) RunResult {
+	var stdout, stderr bytes.Buffer
+	exit := fn(&stdout, &stderr)
+	return RunResult{Stdout: stdout.String(), Stderr: stderr.String(),
+		Exit: exit}
+}
+
+// CheckStreams compares got with expected values from fields Stdout,
+// Stderr and Exit of descr, typically a test description with
+// attributes =STDOUT=, =STDERR= and =EXIT=. Parameter descr is a
+// struct or pointer to struct; a RunResult may be used as well.
+// Fields Stdout and Stderr must have type string or Expected, field
+// Exit must have type int. Missing fields aren't compared.
+// Each mismatch is reported separately as error of t.
+// It reports whether all values matched.
+func CheckStreams(t testing.TB, descr any, got RunResult,
+	opts ...CompareOption,
+) bool {
+	t.Helper()
+	v := reflect.Indirect(reflect.ValueOf(descr))
+	if v.Kind() != reflect.Struct {
+		t.Fatalf("expecting struct or pointer to struct, got %T", descr)
+	}
+	c := newCompareConfig(opts)
+	ok := true
+	for _, s := range []struct{ field, label, got string }{
+		{"Stdout", "stdout", got.Stdout},
+		{"Stderr", "stderr", got.Stderr},
+	} {
+		f := v.FieldByName(s.field)
+		if !f.IsValid() {
+			continue
+		}
+		var exp Expected
+		switch x := f.Interface().(type) {
+		case string:
+			exp.Text = x
+		case Expected:
+			exp = x
+		default:
+			t.Fatalf("unexpected type %T of field %s", x, s.field)
+		}
+		if !c.match(t, s.label, exp, s.got) {
+			ok = false
+		}
+	}
+	if f := v.FieldByName("Exit"); f.IsValid() {
+		if f.Kind() != reflect.Int {
+			t.Fatalf("unexpected type %v of field Exit", f.Type())
+		}
+		if exit := int(f.Int()); exit != got.Exit {
+			reportDiff(t, fmt.Sprintf("exit code: expected %d, got %d",
+				exit, got.Exit))
+			ok = false
+		}
+	}
+	return ok
+}
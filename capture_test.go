@@ -0,0 +1,123 @@
+package testtxt
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCaptureCmd(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip(err)
+	}
+	got, err := CaptureCmd(exec.Command(sh, "-c", "echo out; echo err >&2; exit 3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := RunResult{Stdout: "out\n", Stderr: "err\n", Exit: 3}
+	if d := cmp.Diff(want, got); d != "" {
+		t.Error(d)
+	}
+	if _, err := CaptureCmd(exec.Command("/nonexistent/cmd")); err == nil {
+		t.Error("expected error for missing command")
+	}
+}
+
+func TestCheckStreams(t *testing.T) {
+	got := CaptureFunc(func(stdout, stderr io.Writer) int {
+		fmt.Fprint(stdout, "a  b\nc\n")
+		fmt.Fprint(stderr, "warning: x\n")
+		return 1
+	})
+	type descr struct {
+		Stdout Expected
+		Stderr string
+		Exit   int
+	}
+	tests := []struct {
+		name  string
+		opts  []CompareOption
+		descr any
+		msgs  []string
+	}{
+		{
+			name: "all match",
+			descr: &descr{
+				Stdout: Expected{Text: "a  b\nc\n"},
+				Stderr: "warning: x\n",
+				Exit:   1,
+			},
+		},
+		{
+			name:  "RunResult",
+			descr: got,
+		},
+		{
+			name:  "missing fields aren't compared",
+			descr: struct{ Exit int }{1},
+		},
+		{
+			name: "each mismatch",
+			descr: descr{
+				Stdout: Expected{Text: "a  b\n"},
+				Stderr: "error: x\n",
+			},
+			msgs: []string{
+				"error: --- expected/stdout\n+++ got/stdout\n" +
+					"@@ -1 +1,2 @@\n a  b\n+c\n",
+				"error: --- expected/stderr\n+++ got/stderr\n" +
+					"@@ -1 +1 @@\n-error: x\n+warning: x\n",
+				"error: exit code: expected 0, got 1",
+			},
+		},
+		{
+			name: "label of regular expression",
+			descr: struct{ Stdout Expected }{
+				Expected{Text: "a b\nc", Mode: MatchRegexp}},
+			msgs: []string{"error: stdout: line 1 doesn't match regular " +
+				"expression\npattern: \"a b\\n\"\ngot:     \"a  b\\n\""},
+		},
+		{
+			name: "label and normalized fragments",
+			opts: []CompareOption{Normalize(CollapseSpace)},
+			descr: struct{ Stdout, Stderr Expected }{
+				Expected{Text: "a    b\n!c\n", Mode: MatchContains},
+				Expected{Text: "error", Mode: MatchContains},
+			},
+			msgs: []string{
+				"error: stdout: unexpected fragment: \"c\"",
+				"log: stdout: got:\na b\nc\n",
+				"error: stderr: missing fragment: \"error\"",
+				"log: stderr: got:\nwarning: x\n",
+			},
+		},
+		{
+			name:  "no struct",
+			descr: 1,
+			msgs:  []string{"fatal: expecting struct or pointer to struct, got int"},
+		},
+		{
+			name:  "invalid type",
+			descr: struct{ Stdout []byte }{},
+			msgs:  []string{"fatal: unexpected type []uint8 of field Stdout"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var ok bool
+			r := record(t, func(r *recorder) {
+				ok = CheckStreams(r, tc.descr, got, tc.opts...)
+			})
+			if ok != (tc.msgs == nil) {
+				t.Errorf("got result %v", ok)
+			}
+			if d := cmp.Diff(tc.msgs, r.msgs); d != "" {
+				t.Error(d)
+			}
+		})
+	}
+}
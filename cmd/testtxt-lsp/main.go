@@ -0,0 +1,29 @@
+// Command testtxt-lsp is a language server for files of test
+// descriptions of package testtxt. It communicates by the Language
+// Server Protocol over standard input and output and supports:
+//   - diagnostics of testtxt.Lint while editing,
+//   - go to definition of templates,
+//   - completion of attribute and template names,
+//   - hover showing the expanded text of template calls.
+//
+// Settings are passed as initializationOptions:
+//
+//	{"templates": ["common.tmpl"], "schema": "tests.go", "type": "Test"}
+//
+// Attribute names are completed from the struct type given by schema
+// and type, and from attributes used in the current file.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+)
+
+func main() {
+	s := newServer(bufio.NewReader(os.Stdin), os.Stdout)
+	if err := s.serve(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// message is a JSON-RPC request, response or notification.
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *rpcError        `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error codes of JSON-RPC.
+const (
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+type position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type rng struct {
+	Start position `json:"start"`
+	End   position `json:"end"`
+}
+
+type location struct {
+	URI   string `json:"uri"`
+	Range rng    `json:"range"`
+}
+
+type textDocumentPositionParams struct {
+	TextDocument struct {
+		URI string `json:"uri"`
+	} `json:"textDocument"`
+	Position position `json:"position"`
+}
+
+type diagnostic struct {
+	Range    rng    `json:"range"`
+	Severity int    `json:"severity"`
+	Code     string `json:"code,omitempty"`
+	Source   string `json:"source"`
+	Message  string `json:"message"`
+}
+
+type completionItem struct {
+	Label string `json:"label"`
+	// 10 is "Property", 15 is "Snippet".
+	Kind       int    `json:"kind"`
+	InsertText string `json:"insertText,omitempty"`
+}
+
+type hover struct {
+	Contents struct {
+		Kind  string `json:"kind"`
+		Value string `json:"value"`
+	} `json:"contents"`
+	Range *rng `json:"range,omitempty"`
+}
+
+// maxMessageSize limits the size of a message, that is read.
+const maxMessageSize = 64 << 20
+
+// readMessage reads a message with header "Content-Length" from r.
+func readMessage(r *bufio.Reader) (*message, error) {
+	h, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(h.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length: %v", err)
+	}
+	if n <= 0 || n > maxMessageSize {
+		return nil, fmt.Errorf("invalid Content-Length: %d", n)
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	m := new(message)
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// writeMessage writes m with header "Content-Length" to w.
+func writeMessage(w io.Writer, m *message) error {
+	m.JSONRPC = "2.0"
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "Content-Length: %d\r\n\r\n%s", len(data), data)
+	return err
+}
+
+// uriToPath returns the file name of URI with scheme "file".
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+// pathToURI returns URI with scheme "file" for file name.
+func pathToURI(file string) string {
+	if abs, err := filepath.Abs(file); err == nil {
+		file = abs
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(file)}).String()
+}
+
+// offset returns the byte offset in text of position p, where
+// characters are counted in UTF-16 code units.
+func offset(text string, p position) int {
+	off := 0
+	for i := 0; i < p.Line; i++ {
+		j := strings.IndexByte(text[off:], '\n')
+		if j == -1 {
+			return len(text)
+		}
+		off += j + 1
+	}
+	for n := 0; n < p.Character && off < len(text) && text[off] != '\n'; {
+		r, size := utf8.DecodeRuneInString(text[off:])
+		n += utf16Len(r)
+		off += size
+	}
+	return off
+}
+
+// toPosition returns the position of byte offset off in text.
+func toPosition(text string, off int) position {
+	off = min(off, len(text))
+	start := strings.LastIndexByte(text[:off], '\n') + 1
+	var p position
+	p.Line = strings.Count(text[:start], "\n")
+	for _, r := range text[start:off] {
+		p.Character += utf16Len(r)
+	}
+	return p
+}
+
+// utf16Len returns the number of UTF-16 code units of r.
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+// lineOffset returns byte offset of start of 1-based line in text.
+func lineOffset(text string, line int) int {
+	off := 0
+	for ; line > 1; line-- {
+		j := strings.IndexByte(text[off:], '\n')
+		if j == -1 {
+			return len(text)
+		}
+		off += j + 1
+	}
+	return off
+}
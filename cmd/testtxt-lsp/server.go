@@ -0,0 +1,511 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/hknutzen/testtxt"
+	"github.com/hknutzen/testtxt/internal/gostruct"
+)
+
+// settings are given as initializationOptions by the client.
+// Relative file names are taken relative to the root of the
+// workspace.
+type settings struct {
+	Templates []string `json:"templates"`
+	Schema    string   `json:"schema"`
+	Type      string   `json:"type"`
+}
+
+type server struct {
+	in  *bufio.Reader
+	out io.Writer
+	// Text of open documents by URI.
+	docs     map[string]string
+	settings settings
+	// Struct type of tests, if given by settings.
+	typ reflect.Type
+	// Attribute names of typ and prefixes of map fields like "ENV_".
+	attrs    []string
+	shutdown bool
+}
+
+// Calls of templates like [[name]] or [[name data]], as found by
+// the parser.
+var templCall = regexp.MustCompile(`(?s)\[\[.*?\]?\]\]`)
+
+// Definitions of attributes like =NAME= or =NAME:MODE=.
+var attrDef = regexp.MustCompile(`(?m)^=([A-Za-z0-9_]+(?::[A-Za-z0-9_]+)?)=`)
+
+func newServer(in *bufio.Reader, out io.Writer) *server {
+	return &server{in: in, out: out, docs: make(map[string]string)}
+}
+
+// errExit is returned by handle after notification "exit".
+var errExit = errors.New("exit")
+
+// serve handles messages until notification "exit" or end of input.
+func (s *server) serve() error {
+	for {
+		m, err := readMessage(s.in)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if m.Method == "" {
+			continue // Response to a request of server.
+		}
+		result, rErr := s.handle(m)
+		if rErr == errExit {
+			if !s.shutdown {
+				return errors.New("exit without shutdown")
+			}
+			return nil
+		}
+		if m.ID == nil {
+			continue
+		}
+		resp := &message{ID: m.ID}
+		if rErr != nil {
+			var e *rpcError
+			if !errors.As(rErr, &e) {
+				e = &rpcError{Code: codeInvalidParams, Message: rErr.Error()}
+			}
+			resp.Error = e
+		} else {
+			resp.Result, _ = json.Marshal(result)
+		}
+		if err := writeMessage(s.out, resp); err != nil {
+			return err
+		}
+	}
+}
+
+func (e *rpcError) Error() string { return e.Message }
+
+// handle handles request or notification m and returns the result.
+func (s *server) handle(m *message) (any, error) {
+	switch m.Method {
+	case "initialize":
+		return s.initialize(m.Params)
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "exit":
+		return nil, errExit
+	case "textDocument/didOpen":
+		var p struct {
+			TextDocument struct {
+				URI  string `json:"uri"`
+				Text string `json:"text"`
+			} `json:"textDocument"`
+		}
+		if err := json.Unmarshal(m.Params, &p); err != nil {
+			return nil, err
+		}
+		s.docs[p.TextDocument.URI] = p.TextDocument.Text
+		return nil, s.publish(p.TextDocument.URI)
+	case "textDocument/didChange":
+		var p struct {
+			TextDocument struct {
+				URI string `json:"uri"`
+			} `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+		}
+		if err := json.Unmarshal(m.Params, &p); err != nil {
+			return nil, err
+		}
+		if n := len(p.ContentChanges); n > 0 {
+			s.docs[p.TextDocument.URI] = p.ContentChanges[n-1].Text
+		}
+		return nil, s.publish(p.TextDocument.URI)
+	case "textDocument/didSave":
+		// Other open files may use changed templates.
+		for uri := range s.docs {
+			if err := s.publish(uri); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	case "textDocument/didClose":
+		var p textDocumentPositionParams
+		if err := json.Unmarshal(m.Params, &p); err != nil {
+			return nil, err
+		}
+		delete(s.docs, p.TextDocument.URI)
+		return nil, s.notify("textDocument/publishDiagnostics", map[string]any{
+			"uri": p.TextDocument.URI, "diagnostics": []diagnostic{},
+		})
+	case "textDocument/definition":
+		return s.withPosition(m.Params, s.definition)
+	case "textDocument/hover":
+		return s.withPosition(m.Params, s.hover)
+	case "textDocument/completion":
+		return s.withPosition(m.Params, s.completion)
+	}
+	if m.ID == nil || strings.HasPrefix(m.Method, "$/") {
+		return nil, nil
+	}
+	return nil, &rpcError{Code: codeMethodNotFound,
+		Message: "unsupported method " + m.Method}
+}
+
+// initialize reads settings and returns capabilities of server.
+func (s *server) initialize(params json.RawMessage) (any, error) {
+	var p struct {
+		RootURI               string          `json:"rootUri"`
+		InitializationOptions json.RawMessage `json:"initializationOptions"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	if p.InitializationOptions != nil {
+		err := json.Unmarshal(p.InitializationOptions, &s.settings)
+		if err != nil {
+			return nil, err
+		}
+	}
+	root := ""
+	if p.RootURI != "" {
+		root = uriToPath(p.RootURI)
+	}
+	abs := func(file string) string {
+		if file == "" || filepath.IsAbs(file) {
+			return file
+		}
+		return filepath.Join(root, file)
+	}
+	for i, t := range s.settings.Templates {
+		s.settings.Templates[i] = abs(t)
+	}
+	if file := abs(s.settings.Schema); file != "" {
+		typ, err := gostruct.Load(file, s.settings.Type)
+		if err != nil {
+			return nil, err
+		}
+		s.typ = typ
+		if s.attrs, err = schemaAttrs(typ); err != nil {
+			return nil, err
+		}
+	}
+	return map[string]any{
+		"capabilities": map[string]any{
+			"textDocumentSync":   1, // Full text on each change.
+			"definitionProvider": true,
+			"hoverProvider":      true,
+			"completionProvider": map[string]any{
+				"triggerCharacters": []string{"=", "["},
+			},
+		},
+		"serverInfo": map[string]any{"name": "testtxt-lsp"},
+	}, nil
+}
+
+// schemaAttrs returns names of attributes of struct type typ, as given
+// by testtxt.GenerateSchema. Fields of type map[string]string give a
+// prefix like "ENV_".
+func schemaAttrs(typ reflect.Type) ([]string, error) {
+	data, err := testtxt.GenerateSchema(reflect.New(typ).Interface())
+	if err != nil {
+		return nil, err
+	}
+	var schema struct {
+		Items struct {
+			Properties        map[string]any `json:"properties"`
+			PatternProperties map[string]any `json:"patternProperties"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, err
+	}
+	var l []string
+	for name := range schema.Items.Properties {
+		l = append(l, name)
+	}
+	for pattern := range schema.Items.PatternProperties {
+		prefix, _, _ := strings.Cut(strings.TrimPrefix(pattern, "^"), "[")
+		l = append(l, prefix)
+	}
+	slices.Sort(l)
+	return l, nil
+}
+
+// notify sends a notification to the client.
+func (s *server) notify(method string, params any) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return writeMessage(s.out, &message{Method: method, Params: data})
+}
+
+// withPosition calls fn with document and byte offset of position
+// given in params.
+func (s *server) withPosition(params json.RawMessage,
+	fn func(uri, text string, off int) (any, error),
+) (any, error) {
+	var p textDocumentPositionParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	text, found := s.docs[p.TextDocument.URI]
+	if !found {
+		return nil, nil
+	}
+	return fn(p.TextDocument.URI, text, offset(text, p.Position))
+}
+
+// options returns options for parsing documents.
+func (s *server) options() []testtxt.Option {
+	var opts []testtxt.Option
+	for _, f := range s.settings.Templates {
+		opts = append(opts, testtxt.WithTemplateFile(f))
+	}
+	return opts
+}
+
+// withTempFile writes text into a temporary file, that gets the
+// extension of file, and calls fn with its name.
+func withTempFile(file, text string, fn func(tmp string)) error {
+	f, err := os.CreateTemp("", "testtxt-lsp-*"+filepath.Ext(file))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(text)
+	if err2 := f.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		return err
+	}
+	fn(f.Name())
+	return nil
+}
+
+// publish sends diagnostics of testtxt.Lint for document uri.
+func (s *server) publish(uri string) error {
+	text := s.docs[uri]
+	l := []diagnostic{}
+	err := withTempFile(uriToPath(uri), text, func(tmp string) {
+		var target any
+		if s.typ != nil {
+			target = reflect.New(reflect.SliceOf(s.typ)).Interface()
+		}
+		for _, d := range testtxt.Lint(tmp, target, s.options()...) {
+			l = append(l, toDiagnostic(d, tmp, text))
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return s.notify("textDocument/publishDiagnostics", map[string]any{
+		"uri": uri, "diagnostics": l,
+	})
+}
+
+// toDiagnostic converts d of temporary file tmp with given text.
+// Diagnostics of other files, e.g. template files, are shown at the
+// start of text.
+func toDiagnostic(d testtxt.Diagnostic, tmp, text string) diagnostic {
+	r := diagnostic{Source: "testtxt", Code: d.Code, Message: d.Message}
+	r.Severity = 1 // Error
+	if d.Severity == testtxt.SeverityWarning {
+		r.Severity = 2
+	}
+	if d.File != tmp {
+		r.Message = d.String()
+		return r
+	}
+	if d.Line == 0 {
+		return r
+	}
+	start := lineOffset(text, d.Line) + max(d.Column-1, 0)
+	end := start
+	if d.EndLine > 0 {
+		end = lineOffset(text, d.EndLine) + max(d.EndColumn-1, 0)
+	}
+	if end <= start {
+		end = start + strings.IndexByte(text[min(start, len(text)):]+"\n", '\n')
+	}
+	r.Range = rng{toPosition(text, start), toPosition(text, end)}
+	return r
+}
+
+// findCall returns the call of a template in text at byte offset off.
+func findCall(text string, off int) (call string, start, end int) {
+	for _, m := range templCall.FindAllStringIndex(text, -1) {
+		if m[0] <= off && off <= m[1] {
+			return text[m[0]:m[1]], m[0], m[1]
+		}
+	}
+	return "", 0, 0
+}
+
+// callName returns the name of the template of call [[name data]].
+func callName(call string) string {
+	name, _, _ := strings.Cut(strings.TrimSpace(call[2:len(call)-2]), " ")
+	name, _, _ = strings.Cut(name, "\n")
+	return strings.TrimSpace(name)
+}
+
+// definition returns the location of the template called at off.
+func (s *server) definition(uri, text string, off int) (any, error) {
+	call, start, _ := findCall(text, off)
+	if call == "" {
+		return nil, nil
+	}
+	name := callName(call)
+	// Take the last definition in front of the call.
+	var found *location
+	for _, b := range templatesBefore(text, start) {
+		if b.Templ == name {
+			found = templLocation(uri, text, b)
+		}
+	}
+	if found != nil {
+		return found, nil
+	}
+	for _, file := range s.settings.Templates {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		blocks, err := testtxt.SplitBlocks(data)
+		if err != nil {
+			continue
+		}
+		for _, b := range blocks {
+			if b.Templ == name {
+				return templLocation(pathToURI(file), string(data), b), nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// templatesBefore returns definitions of templates in text, that
+// precede the line with byte offset off. A template, whose text
+// contains this line, isn't returned. Errors in text after this line
+// are ignored.
+func templatesBefore(text string, off int) []*testtxt.Block {
+	lineStart := strings.LastIndexByte(text[:off], '\n') + 1
+	blocks, err := testtxt.SplitBlocks([]byte(text[:lineStart]))
+	if err != nil {
+		return nil
+	}
+	if n := len(blocks); n > 0 && blocks[n-1].End == lineStart &&
+		!strings.HasPrefix(text[lineStart:], "=") {
+		blocks = blocks[:n-1]
+	}
+	return slices.DeleteFunc(blocks, func(b *testtxt.Block) bool {
+		return b.Templ == ""
+	})
+}
+
+// templLocation returns location of name of template b in text.
+func templLocation(uri, text string, b *testtxt.Block) *location {
+	start := b.Start + strings.Index(b.Text, "=TEMPL=")
+	end := start + len("=TEMPL=") + len(b.Templ)
+	return &location{URI: uri,
+		Range: rng{toPosition(text, start), toPosition(text, end)}}
+}
+
+// hover returns the expanded text of the template call at off.
+func (s *server) hover(uri, text string, off int) (any, error) {
+	call, start, end := findCall(text, off)
+	if call == "" {
+		return nil, nil
+	}
+	// Evaluate call with templates defined in front of it.
+	var src strings.Builder
+	for _, b := range templatesBefore(text, start) {
+		src.WriteString(b.Text)
+	}
+	fmt.Fprintf(&src, "=HOVER=\n%s\n=END=\n", call)
+	var value string
+	err := withTempFile(uriToPath(uri), src.String(), func(tmp string) {
+		l, err := testtxt.ParseTests(tmp, s.options()...)
+		switch {
+		case err != nil:
+			value = err.Error()
+		case len(l) == 1 && len(l[0].Attrs) == 1:
+			value = "```\n" + strings.TrimSuffix(l[0].Attrs[0].Text, "\n") +
+				"\n```"
+		}
+	})
+	if err != nil || value == "" {
+		return nil, err
+	}
+	h := new(hover)
+	h.Contents.Kind = "markdown"
+	h.Contents.Value = value
+	h.Range = &rng{toPosition(text, start), toPosition(text, end)}
+	return h, nil
+}
+
+// completion returns names of templates inside of "[[" and names of
+// attributes after "=" at start of line.
+func (s *server) completion(uri, text string, off int) (any, error) {
+	line := text[strings.LastIndexByte(text[:off], '\n')+1 : off]
+	l := []completionItem{}
+	if i := strings.LastIndex(line, "[["); i != -1 &&
+		!strings.Contains(line[i:], "]]") {
+		for _, name := range s.templateNames(text, off) {
+			l = append(l, completionItem{Label: name, Kind: 3})
+		}
+		return l, nil
+	}
+	if !strings.HasPrefix(line, "=") || strings.Contains(line[1:], "=") {
+		return l, nil
+	}
+	names := slices.Clone(s.attrs)
+	for _, m := range attrDef.FindAllStringSubmatch(text, -1) {
+		names = append(names, m[1])
+	}
+	names = append(names, "TEMPL", "SUBST", "END")
+	slices.Sort(names)
+	for _, name := range slices.Compact(names) {
+		insert := name
+		if !strings.HasSuffix(name, "_") {
+			insert += "="
+		}
+		l = append(l, completionItem{Label: name, Kind: 10, InsertText: insert})
+	}
+	return l, nil
+}
+
+// templateNames returns names of templates defined in text in front
+// of the line with byte offset off and in template files.
+func (s *server) templateNames(text string, off int) []string {
+	var names []string
+	for _, b := range templatesBefore(text, off) {
+		names = append(names, b.Templ)
+	}
+	for _, file := range s.settings.Templates {
+		if data, err := os.ReadFile(file); err == nil {
+			if blocks, err := testtxt.SplitBlocks(data); err == nil {
+				for _, b := range blocks {
+					if b.Templ != "" {
+						names = append(names, b.Templ)
+					}
+				}
+			}
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// request returns a request with id or a notification if id is 0.
+func request(t *testing.T, id int, method string, params any) *message {
+	t.Helper()
+	m := &message{Method: method}
+	if id != 0 {
+		raw := json.RawMessage(strconv.Itoa(id))
+		m.ID = &raw
+	}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m.Params = data
+	}
+	return m
+}
+
+// session sends messages l to a new server and returns the messages
+// written by the server, each as JSON in a single line, and the error
+// of the server.
+func session(t *testing.T, l ...*message) ([]string, error) {
+	t.Helper()
+	var in bytes.Buffer
+	for _, m := range l {
+		if err := writeMessage(&in, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var out bytes.Buffer
+	err := newServer(bufio.NewReader(&in), &out).serve()
+	var result []string
+	r := bufio.NewReader(&out)
+	for {
+		m, rErr := readMessage(r)
+		if rErr == io.EOF {
+			break
+		}
+		if rErr != nil {
+			t.Fatal(rErr)
+		}
+		data, _ := json.Marshal(m)
+		result = append(result, string(data))
+	}
+	return result, err
+}
+
+func TestReadMessage(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string // method of message
+		err   string
+	}{
+		{
+			name: "with content type",
+			input: "Content-Length: 17\r\n" +
+				"Content-Type: application/vscode-jsonrpc; charset=utf-8\r\n" +
+				"\r\n" + `{"method":"exit"}`,
+			want: "exit",
+		},
+		{
+			name:  "missing length",
+			input: "Content-Type: x\r\n\r\n{}",
+			err:   `invalid Content-Length: strconv.Atoi: parsing "": invalid syntax`,
+		},
+		{
+			name:  "zero length",
+			input: "Content-Length: 0\r\n\r\n",
+			err:   "invalid Content-Length: 0",
+		},
+		{
+			name:  "too large",
+			input: "Content-Length: 100000000\r\n\r\n",
+			err:   "invalid Content-Length: 100000000",
+		},
+		{
+			name:  "truncated",
+			input: "Content-Length: 10\r\n\r\n{}",
+			err:   "unexpected EOF",
+		},
+		{
+			name:  "invalid JSON",
+			input: "Content-Length: 2\r\n\r\n{]",
+			err:   "invalid character ']' looking for beginning of object key string",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := readMessage(bufio.NewReader(strings.NewReader(tc.input)))
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("got error %v, want %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.Method != tc.want {
+				t.Errorf("got method %q, want %q", m.Method, tc.want)
+			}
+		})
+	}
+}
+
+func TestServer(t *testing.T) {
+	dir := t.TempDir()
+	tmpl := filepath.Join(dir, "common.tmpl")
+	if err := os.WriteFile(tmpl, []byte("=TEMPL=g\nglobal\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	schema := filepath.Join(dir, "tests.go")
+	if err := os.WriteFile(schema, []byte(
+		"package p\n\ntype T struct {\n\tTitle string\n\tIn string\n"+
+			"\tEnv map[string]string\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	uri := pathToURI(filepath.Join(dir, "x.t"))
+	gURI := pathToURI(tmpl)
+	text := "=TEMPL=t\nHello {{.}}\n=END=\n" + // lines 0-2
+		"=TITLE=a\n" + // line 3
+		"=IN=[[t World]] [[g]]\n" + // line 4
+		"=OUT=x\n" + // line 5
+		"=IN=[[\n" + // line 6
+		"=I\n" // line 7
+	doc := map[string]any{"uri": uri}
+	pos := func(line, char int) map[string]any {
+		return map[string]any{"textDocument": doc,
+			"position": map[string]int{"line": line, "character": char}}
+	}
+	got, err := session(t,
+		request(t, 1, "initialize", map[string]any{
+			"rootUri": pathToURI(dir),
+			"initializationOptions": map[string]any{
+				"templates": []string{"common.tmpl"},
+				"schema":    "tests.go",
+				"type":      "T",
+			},
+		}),
+		request(t, 0, "initialized", map[string]any{}),
+		request(t, 0, "textDocument/didOpen", map[string]any{
+			"textDocument": map[string]any{"uri": uri, "text": text},
+		}),
+		request(t, 2, "textDocument/definition", pos(4, 7)),
+		request(t, 3, "textDocument/definition", pos(4, 18)),
+		request(t, 4, "textDocument/hover", pos(4, 6)),
+		request(t, 5, "textDocument/hover", pos(5, 5)),
+		request(t, 6, "textDocument/completion", pos(6, 6)),
+		request(t, 7, "textDocument/completion", pos(7, 2)),
+		request(t, 0, "textDocument/didChange", map[string]any{
+			"textDocument":   doc,
+			"contentChanges": []map[string]string{{"text": "=TITLE=b\n"}},
+		}),
+		request(t, 0, "textDocument/didClose", map[string]any{
+			"textDocument": doc,
+		}),
+		request(t, 8, "textDocument/hover", pos(0, 0)),
+		request(t, 9, "workspace/symbol", map[string]any{}),
+		request(t, 0, "$/cancelRequest", map[string]any{"id": 1}),
+		request(t, 10, "shutdown", nil),
+		request(t, 0, "exit", nil),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`{"jsonrpc":"2.0","id":1,"result":{"capabilities":{"completionProvider":{"triggerCharacters":["=","["]},"definitionProvider":true,"hoverProvider":true,"textDocumentSync":1},"serverInfo":{"name":"testtxt-lsp"}}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"diagnostics":[` +
+			`{"range":{"start":{"line":5,"character":0},"end":{"line":5,"character":6}},"severity":1,"code":"attribute","source":"testtxt","message":"unexpected =OUT="},` +
+			`{"range":{"start":{"line":6,"character":0},"end":{"line":6,"character":6}},"severity":1,"code":"attribute","source":"testtxt","message":"found multiple =IN= at lines 5 and 7"},` +
+			`{"range":{"start":{"line":7,"character":0},"end":{"line":7,"character":2}},"severity":1,"code":"syntax","source":"testtxt","message":"expected token '=...=': =I"}` +
+			`],"uri":"` + uri + `"}}`,
+		`{"jsonrpc":"2.0","id":2,"result":{"uri":"` + uri + `","range":{"start":{"line":0,"character":0},"end":{"line":0,"character":8}}}}`,
+		`{"jsonrpc":"2.0","id":3,"result":{"uri":"` + gURI + `","range":{"start":{"line":0,"character":0},"end":{"line":0,"character":8}}}}`,
+		`{"jsonrpc":"2.0","id":4,"result":{"contents":{"kind":"markdown","value":"` + "```" + `\nHello World\n` + "```" + `"},"range":{"start":{"line":4,"character":4},"end":{"line":4,"character":15}}}}`,
+		`{"jsonrpc":"2.0","id":5,"result":null}`,
+		`{"jsonrpc":"2.0","id":6,"result":[{"label":"g","kind":3},{"label":"t","kind":3}]}`,
+		`{"jsonrpc":"2.0","id":7,"result":[` +
+			`{"label":"END","kind":10,"insertText":"END="},` +
+			`{"label":"ENV_","kind":10,"insertText":"ENV_"},` +
+			`{"label":"IN","kind":10,"insertText":"IN="},` +
+			`{"label":"OUT","kind":10,"insertText":"OUT="},` +
+			`{"label":"SUBST","kind":10,"insertText":"SUBST="},` +
+			`{"label":"TEMPL","kind":10,"insertText":"TEMPL="},` +
+			`{"label":"TITLE","kind":10,"insertText":"TITLE="}]}`,
+		`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"diagnostics":[],"uri":"` + uri + `"}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"diagnostics":[],"uri":"` + uri + `"}}`,
+		`{"jsonrpc":"2.0","id":8,"result":null}`,
+		`{"jsonrpc":"2.0","id":9,"error":{"code":-32601,"message":"unsupported method workspace/symbol"}}`,
+		`{"jsonrpc":"2.0","id":10,"result":null}`,
+	}
+	if d := cmp.Diff(want, got); d != "" {
+		t.Error(d)
+	}
+}
+
+func TestExitWithoutShutdown(t *testing.T) {
+	_, err := session(t, request(t, 0, "exit", nil))
+	if err == nil || err.Error() != "exit without shutdown" {
+		t.Errorf("got error %v, want %q", err, "exit without shutdown")
+	}
+}
+
+func TestPosition(t *testing.T) {
+	text := "ab\nx😀y\n"
+	for _, tc := range []struct {
+		p   position
+		off int
+	}{
+		{position{0, 0}, 0},
+		{position{0, 2}, 2},
+		{position{1, 1}, 4},
+		// Emoji has two UTF-16 code units.
+		{position{1, 3}, 8},
+		{position{1, 4}, 9},
+		{position{2, 0}, 10},
+	} {
+		if got := offset(text, tc.p); got != tc.off {
+			t.Errorf("offset(%v) = %d, want %d", tc.p, got, tc.off)
+		}
+		if got := toPosition(text, tc.off); got != tc.p {
+			t.Errorf("toPosition(%d) = %v, want %v", tc.off, got, tc.p)
+		}
+	}
+	// Position after end of line is taken as end of line.
+	if got := offset(text, position{0, 5}); got != 2 {
+		t.Errorf("offset after end of line = %d, want 2", got)
+	}
+	if got := offset(text, position{5, 0}); got != len(text) {
+		t.Errorf("offset after end of text = %d, want %d", got, len(text))
+	}
+}
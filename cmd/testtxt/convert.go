@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/hknutzen/testtxt"
+)
+
+// convert translates between file trees in the format of input of
+// testtxt.PrepareInDir and txtar format, or between files of test
+// descriptions and JSON or YAML. Tests are exported with expanded
+// text. TOML is converted by testtxt.TOMLSource. On import from JSON or
+// YAML, text, that can't be represented, is an error, but text, that
+// looks like a template call, isn't escaped.
+func convert(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("convert", "-to format | -from format [file]", stderr)
+	to := flags.String("to", "", "convert to `format`: txtar, json, yaml")
+	from := flags.String("from", "", "convert from `format`: txtar, json, yaml, toml")
+	var templates stringList
+	flags.Var(&templates, "templates", "read templates from `file`")
+	if flags.Parse(args) != nil {
+		return 2
+	}
+	if flags.NArg() > 1 || (*to == "") == (*from == "") {
+		flags.Usage()
+		return 2
+	}
+	var data []byte
+	var err error
+	file := "<stdin>"
+	if flags.NArg() == 1 {
+		file = flags.Arg(0)
+		data, err = os.ReadFile(file)
+	} else {
+		data, err = io.ReadAll(os.Stdin)
+	}
+	if err == nil {
+		err = convertData(file, data, *to, *from, templates, stdout)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func convertData(file string, data []byte, to, from string,
+	templates []string, w io.Writer,
+) error {
+	var l []*testtxt.Test
+	switch to {
+	case "":
+	case "txtar":
+		out, err := testtxt.FormatTxtar(string(data))
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(out)
+		return err
+	case "json", "yaml":
+		if file == "<stdin>" {
+			return fmt.Errorf("file of test descriptions must be given")
+		}
+		var err error
+		if l, err = testtxt.ParseTests(file, templateOpts(templates)...); err != nil {
+			return err
+		}
+		if to == "json" {
+			return writeJSON(w, l)
+		}
+		return writeYAML(w, l)
+	default:
+		return fmt.Errorf("unsupported format: %s", to)
+	}
+	var err error
+	switch from {
+	case "txtar":
+		var s string
+		if s, err = testtxt.ParseTxtar(data); err == nil {
+			_, err = io.WriteString(w, s)
+		}
+		return err
+	case "toml":
+		out, err := testtxt.TOMLSource(data)
+		if err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+		_, err = w.Write(out)
+		return err
+	case "json":
+		l, err = readJSON(data)
+	case "yaml":
+		l, err = readYAML(data)
+	default:
+		return fmt.Errorf("unsupported format: %s", from)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+	if err := writeTests(w, l); err != nil {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hknutzen/testtxt"
+	"gopkg.in/yaml.v3"
+)
+
+// attrKey returns the key of attribute a in JSON and YAML, e.g.
+// "OUTPUT:RE" for =OUTPUT:RE=.
+func attrKey(a testtxt.Attr) string {
+	if a.Mode != "" {
+		return a.Name + ":" + a.Mode
+	}
+	return a.Name
+}
+
+// keyAttr returns the attribute for key and value.
+func keyAttr(key string, value any) testtxt.Attr {
+	name, mode, _ := strings.Cut(key, ":")
+	text, ok := value.(string)
+	if !ok && value != nil {
+		text = fmt.Sprint(value)
+	}
+	return testtxt.Attr{Name: name, Mode: mode, Text: text}
+}
+
+// writeJSON writes tests l as JSON array of objects, that map names of
+// attributes to their text in order of file.
+func writeJSON(w io.Writer, l []*testtxt.Test) error {
+	var b bytes.Buffer
+	b.WriteString("[")
+	for i, t := range l {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n  {")
+		for j, a := range t.Attrs {
+			if j > 0 {
+				b.WriteString(",")
+			}
+			fmt.Fprintf(&b, "\n    %s: %s", jsonString(attrKey(a)),
+				jsonString(a.Text))
+		}
+		b.WriteString("\n  }")
+	}
+	b.WriteString("\n]\n")
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+func jsonString(s string) string {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// readJSON reads tests from an array of JSON objects, keeping the
+// order of attributes.
+func readJSON(data []byte) ([]*testtxt.Test, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	expect := func(want json.Delim) error {
+		tok, err := dec.Token()
+		if err == nil && tok != want {
+			err = fmt.Errorf("expected %v in JSON, got %v", want, tok)
+		}
+		return err
+	}
+	if err := expect('['); err != nil {
+		return nil, err
+	}
+	var l []*testtxt.Test
+	for dec.More() {
+		if err := expect('{'); err != nil {
+			return nil, err
+		}
+		t := new(testtxt.Test)
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			var value any
+			if err := dec.Decode(&value); err != nil {
+				return nil, err
+			}
+			t.Attrs = append(t.Attrs, keyAttr(tok.(string), value))
+		}
+		if err := expect('}'); err != nil {
+			return nil, err
+		}
+		l = append(l, t)
+	}
+	return l, expect(']')
+}
+
+// writeYAML writes tests l as YAML sequence of mappings, that map names
+// of attributes to their text in order of file.
+func writeYAML(w io.Writer, l []*testtxt.Test) error {
+	seq := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, t := range l {
+		m := &yaml.Node{Kind: yaml.MappingNode}
+		for _, a := range t.Attrs {
+			v := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: a.Text}
+			if strings.Contains(a.Text, "\n") {
+				v.Style = yaml.LiteralStyle
+			}
+			m.Content = append(m.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: attrKey(a)}, v)
+		}
+		seq.Content = append(seq.Content, m)
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(seq); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// readYAML reads tests from a YAML sequence of mappings, keeping the
+// order of attributes.
+func readYAML(data []byte) ([]*testtxt.Test, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	seq := doc.Content[0]
+	if seq.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("line %d: expected sequence in YAML", seq.Line)
+	}
+	var l []*testtxt.Test
+	for _, m := range seq.Content {
+		if m.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("line %d: expected mapping in YAML", m.Line)
+		}
+		t := new(testtxt.Test)
+		for i := 0; i+1 < len(m.Content); i += 2 {
+			var value any
+			if err := m.Content[i+1].Decode(&value); err != nil {
+				return nil, err
+			}
+			t.Attrs = append(t.Attrs, keyAttr(m.Content[i].Value, value))
+		}
+		l = append(l, t)
+	}
+	return l, nil
+}
+
+// writeTests writes tests l in the format of a file of test
+// descriptions, separated by empty lines.
+func writeTests(w io.Writer, l []*testtxt.Test) error {
+	for i, t := range l {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		if err := writeTest(w, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
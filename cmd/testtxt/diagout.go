@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/hknutzen/testtxt"
+)
+
+// diagOutput prints diagnostics as text, as JSON lines, in TAP format
+// or in JUnit XML format, as selected by flags -json, -tap and -junit.
+type diagOutput struct {
+	w       io.Writer
+	asJSON  *bool
+	asTAP   *bool
+	asJUnit *bool
+	tap     *testtxt.TAPWriter
+	junit   *testtxt.JUnitWriter
+}
+
+// newDiagOutput defines flags -json, -tap and -junit in fs.
+func newDiagOutput(fs *flag.FlagSet, w io.Writer) *diagOutput {
+	return &diagOutput{
+		w:      w,
+		asJSON: fs.Bool("json", false, "print diagnostics as JSON lines"),
+		asTAP: fs.Bool("tap", false,
+			"print a TAP test point for each file"),
+		asJUnit: fs.Bool("junit", false,
+			"print JUnit XML with a test case for each diagnostic"),
+	}
+}
+
+// check reports whether flags are valid after parsing.
+func (o *diagOutput) check() bool {
+	n := 0
+	for _, b := range []*bool{o.asJSON, o.asTAP, o.asJUnit} {
+		if *b {
+			n++
+		}
+	}
+	return n <= 1
+}
+
+// file prints diagnostics l of file.
+func (o *diagOutput) file(file string, l []testtxt.Diagnostic) {
+	if *o.asJUnit {
+		if o.junit == nil {
+			o.junit = testtxt.NewJUnitWriter(o.w)
+		}
+		o.junit.Diagnostics(file, l)
+		return
+	}
+	if *o.asTAP {
+		if o.tap == nil {
+			o.tap = testtxt.NewTAPWriter(o.w)
+		}
+		o.tap.Diagnostics(file, l)
+		return
+	}
+	for _, d := range l {
+		if *o.asJSON {
+			b, _ := json.Marshal(d)
+			fmt.Fprintf(o.w, "%s\n", b)
+		} else {
+			fmt.Fprintln(o.w, d)
+		}
+	}
+}
+
+// close finishes output.
+func (o *diagOutput) close() error {
+	if o.tap != nil {
+		return o.tap.Close()
+	}
+	if o.junit != nil {
+		return o.junit.Close()
+	}
+	return nil
+}
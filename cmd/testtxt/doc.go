@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hknutzen/testtxt"
+)
+
+// doc writes a Markdown page for each file of test descriptions.
+// Each test is shown with its title, its description and each other
+// attribute as collapsible block.
+func doc(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("doc", "[-o dir] [-desc attr] file.t...", stderr)
+	outDir := flags.String("o", "",
+		"write file.md for each file.t to `dir` instead of stdout")
+	desc := flags.String("desc", "DESCRIPTION",
+		"take description of test from `attr`")
+	var templates stringList
+	flags.Var(&templates, "templates", "read templates from `file`")
+	if flags.Parse(args) != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+	exit := 0
+	for i, file := range flags.Args() {
+		page, err := docPage(file, *desc, templates)
+		if err == nil {
+			if *outDir != "" {
+				name := strings.TrimSuffix(filepath.Base(file),
+					filepath.Ext(file)) + ".md"
+				err = os.WriteFile(filepath.Join(*outDir, name), page, 0644)
+			} else {
+				if i > 0 {
+					fmt.Fprintln(stdout)
+				}
+				_, err = stdout.Write(page)
+			}
+		}
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			exit = 1
+		}
+	}
+	return exit
+}
+
+// docPage returns the Markdown page for file. The description of a
+// test is taken from attribute desc or else from comment lines in
+// front of the test.
+func docPage(file, desc string, templates []string) ([]byte, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	blocks, err := testtxt.SplitBlocks(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	l, err := testtxt.ParseTests(file, templateOpts(templates)...)
+	if err != nil {
+		return nil, err
+	}
+	var comments []string
+	for _, b := range blocks {
+		if b.Templ == "" {
+			comments = append(comments, leadingComment(b.Text))
+		}
+	}
+	var w strings.Builder
+	fmt.Fprintf(&w, "# %s\n\n", filepath.Base(file))
+	for i, t := range l {
+		fmt.Fprintf(&w, "## %s\n\n", t.Title)
+		text, found := t.Get(desc)
+		if !found && i < len(comments) {
+			text = comments[i]
+		}
+		if text = strings.TrimSpace(text); text != "" {
+			w.WriteString(text + "\n\n")
+		}
+		for _, a := range t.Attrs[1:] {
+			if a.Name == desc {
+				continue
+			}
+			fmt.Fprintf(&w, "<details><summary>%s</summary>\n\n", attrKey(a))
+			fence := codeFence(a.Text)
+			text := a.Text
+			if text != "" && !strings.HasSuffix(text, "\n") {
+				text += "\n"
+			}
+			fmt.Fprintf(&w, "%s\n%s%s\n\n</details>\n\n", fence, text, fence)
+		}
+	}
+	return []byte(strings.TrimRight(w.String(), "\n") + "\n"), nil
+}
+
+// leadingComment returns text of comment lines at start of text of a
+// block without leading "#".
+func leadingComment(text string) string {
+	var b strings.Builder
+	for _, line := range strings.SplitAfter(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, "#") {
+			break
+		}
+		b.WriteString(strings.TrimSpace(trimmed[1:]) + "\n")
+	}
+	return b.String()
+}
+
+// codeFence returns a fence of backquotes, that is longer than each
+// sequence of backquotes in text.
+func codeFence(text string) string {
+	n, longest := 0, 2
+	for _, c := range text {
+		if c == '`' {
+			n++
+			if n > longest {
+				longest = n
+			}
+		} else {
+			n = 0
+		}
+	}
+	return strings.Repeat("`", longest+1)
+}
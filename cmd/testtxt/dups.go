@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"slices"
+
+	"github.com/hknutzen/testtxt"
+)
+
+// checkDups reports titles or values of another attribute, that are
+// used by multiple tests in files below given directories.
+func checkDups(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("check-dups", "[-by attr] [-ext .t] dir|file...", stderr)
+	by := flags.String("by", "", "compare value of `attr` instead of title")
+	ext := flags.String("ext", ".t", "read files with `extension` in directories")
+	var templates stringList
+	flags.Var(&templates, "templates", "read templates from `file`")
+	if flags.Parse(args) != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+	files, err := findFiles(flags.Args(), *ext)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	locations := make(map[string][]string)
+	var keys []string
+	exit := 0
+	for _, file := range files {
+		l, err := testtxt.ParseTests(file, templateOpts(templates)...)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			exit = 1
+		}
+		for _, t := range l {
+			key, line := t.Title, t.Attrs[0].Line
+			if *by != "" {
+				var found bool
+				if key, found = t.Get(*by); !found {
+					continue
+				}
+				for _, a := range t.Attrs {
+					if a.Name == *by {
+						line = a.Line
+					}
+				}
+			}
+			if locations[key] == nil {
+				keys = append(keys, key)
+			}
+			locations[key] = append(locations[key],
+				fmt.Sprintf("%s:%d", file, line))
+		}
+	}
+	attr := "title"
+	if *by != "" {
+		attr = "=" + *by + "="
+	}
+	for _, key := range keys {
+		if l := locations[key]; len(l) > 1 {
+			fmt.Fprintf(stdout, "duplicate %s %q:\n", attr, key)
+			for _, loc := range l {
+				fmt.Fprintf(stdout, "  %s\n", loc)
+			}
+			exit = 1
+		}
+	}
+	return exit
+}
+
+// findFiles returns names of files given in args and of files with
+// extension ext below directories given in args, in lexical order.
+func findFiles(args []string, ext string) ([]string, error) {
+	var files []string
+	for _, arg := range args {
+		err := filepath.WalkDir(arg,
+			func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if !d.IsDir() && (path == arg || filepath.Ext(path) == ext) {
+					files = append(files, path)
+				}
+				return nil
+			})
+		if err != nil {
+			return nil, err
+		}
+	}
+	slices.Sort(files)
+	return slices.Compact(files), nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hknutzen/testtxt"
+)
+
+// expand prints attributes of tests with templates and substitutions
+// applied, either of all tests of file or of the test with given title.
+func expand(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("expand", "[-templates file] file.t [title]", stderr)
+	var templates stringList
+	fs.Var(&templates, "templates", "read templates from `file`")
+	if fs.Parse(args) != nil {
+		return 2
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		return 2
+	}
+	file := fs.Arg(0)
+	l, err := testtxt.ParseTests(file, templateOpts(templates)...)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	found := false
+	for _, t := range l {
+		if fs.NArg() == 2 && t.Title != fs.Arg(1) {
+			continue
+		}
+		if found {
+			fmt.Fprintln(stdout)
+		}
+		found = true
+		if err := writeTest(stdout, t); err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", file, err)
+			return 1
+		}
+	}
+	if !found && fs.NArg() == 2 {
+		fmt.Fprintf(stderr, "%s: missing test %q\n", file, fs.Arg(1))
+		return 1
+	}
+	return 0
+}
+
+// templateOpts returns options, that read templates from files.
+func templateOpts(files []string) []testtxt.Option {
+	var opts []testtxt.Option
+	for _, f := range files {
+		opts = append(opts, testtxt.WithTemplateFile(f))
+	}
+	return opts
+}
+
+// writeTest writes attributes of t to w in the format of a file of
+// test descriptions, formatted by testtxt.FormatAttr. Nothing is
+// written, if some text can't be represented.
+func writeTest(w io.Writer, t *testtxt.Test) error {
+	var b strings.Builder
+	for _, a := range t.Attrs {
+		name := a.Name
+		if a.Mode != "" {
+			name += ":" + a.Mode
+		}
+		def, err := testtxt.FormatAttr(name, a.Text)
+		if err != nil {
+			return fmt.Errorf("test %q: =%s=: %v", t.Attrs[0].Text, name, err)
+		}
+		b.WriteString(def)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
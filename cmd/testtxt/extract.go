@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hknutzen/testtxt"
+)
+
+// extract moves a test from one file to a new file, together with the
+// templates, that it uses directly or indirectly. Templates are
+// removed from the source file, if no other test uses them.
+func extract(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("extract", "title src.t dst.t", stderr)
+	if flags.Parse(args) != nil {
+		return 2
+	}
+	if flags.NArg() != 3 {
+		flags.Usage()
+		return 2
+	}
+	title, src, dst := flags.Arg(0), flags.Arg(1), flags.Arg(2)
+	if err := extractTest(title, src, dst); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func extractTest(title, src, dst string) error {
+	if _, err := os.Stat(dst); !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%s: file already exists", dst)
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	l, err := testtxt.SplitBlocks(data)
+	if err != nil {
+		return fmt.Errorf("%s: %v", src, err)
+	}
+	var test *testtxt.Block
+	templates := make(map[string]*testtxt.Block)
+	// Templates defined after the test may use other templates
+	// indirectly, hence all blocks are scanned.
+	for _, b := range l {
+		if b.Templ != "" {
+			templates[b.Templ] = b
+		} else if b.Title == title && test == nil {
+			test = b
+		}
+	}
+	if test == nil {
+		return fmt.Errorf("%s: missing test %q", src, title)
+	}
+	used := usedTemplates(templates, test.Calls)
+	var calls []string
+	for _, b := range l {
+		if b.Templ == "" && b != test {
+			calls = append(calls, b.Calls...)
+		}
+	}
+	usedRest := usedTemplates(templates, calls)
+	var out, rest strings.Builder
+	rest.Write(data[:l[0].Start])
+	for _, b := range l {
+		switch {
+		case b == test:
+			addBlock(&out, b)
+		case used[b.Templ]:
+			addBlock(&out, b)
+			if usedRest[b.Templ] {
+				addBlock(&rest, b)
+			}
+		default:
+			addBlock(&rest, b)
+		}
+	}
+	return writeParsed([]string{dst, src}, []string{out.String(), rest.String()})
+}
+
+// writeParsed writes content[i] to files[i]. No file is changed, if
+// some content can't be parsed as file of test descriptions.
+func writeParsed(files, content []string) error {
+	var tmps []string
+	defer func() {
+		for _, tmp := range tmps {
+			os.Remove(tmp)
+		}
+	}()
+	for i, file := range files {
+		f, err := os.CreateTemp(filepath.Dir(file), ".testtxt-*.t")
+		if err != nil {
+			return err
+		}
+		tmps = append(tmps, f.Name())
+		_, err = f.WriteString(content[i])
+		if err2 := f.Close(); err == nil {
+			err = err2
+		}
+		if err == nil {
+			err = os.Chmod(f.Name(), 0644)
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := testtxt.ParseTests(f.Name()); err != nil {
+			return fmt.Errorf("%s would become invalid, no file changed:\n%s",
+				file, strings.ReplaceAll(err.Error(), f.Name(), file))
+		}
+	}
+	for i, file := range files {
+		if err := os.Rename(tmps[i], file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// usedTemplates returns names of templates, that are called directly
+// by calls or indirectly by these templates.
+func usedTemplates(templates map[string]*testtxt.Block, calls []string,
+) map[string]bool {
+	used := make(map[string]bool)
+	for len(calls) > 0 {
+		name := calls[0]
+		calls = calls[1:]
+		if used[name] {
+			continue
+		}
+		used[name] = true
+		if t := templates[name]; t != nil {
+			calls = append(calls, t.Calls...)
+		}
+	}
+	return used
+}
+
+// addBlock appends text of b to w, separated by an empty line from
+// preceding text.
+func addBlock(w *strings.Builder, b *testtxt.Block) {
+	if s := w.String(); s != "" && !strings.HasSuffix(s, "\n\n") {
+		w.WriteString("\n")
+	}
+	w.WriteString(b.Text)
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/hknutzen/testtxt"
+)
+
+// factorSeg is a multi line text of an attribute, that is used
+// literally, i.e. without templates or substitutions.
+type factorSeg struct {
+	test  int      // index of test
+	first int      // index of first line of text in source
+	lines []string // lines of text without line endings
+}
+
+// factorOcc is an occurrence of repeated lines in a factorSeg.
+type factorOcc struct {
+	seg *factorSeg
+	off int // index of first line in seg.lines
+}
+
+// factorGroup is a sequence of n lines occurring repeatedly.
+type factorGroup struct {
+	name string
+	n    int
+	occs []factorOcc
+}
+
+// factor finds sequences of lines, that are repeated in text of
+// attributes of a file, and proposes to replace them by calls of
+// templates. With option -w, templates are defined in front of the
+// first test, that uses them, and repeated lines are replaced by
+// calls.
+func factor(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("factor", "[-min n] [-prefix name] [-w] file.t", stderr)
+	minLines := flags.Int("min", 5, "minimum `number` of repeated lines")
+	prefix := flags.String("prefix", "common",
+		"`prefix` of names of new templates")
+	write := flags.Bool("w", false, "write result to file")
+	var templates stringList
+	flags.Var(&templates, "templates", "read templates from `file`")
+	if flags.Parse(args) != nil {
+		return 2
+	}
+	if flags.NArg() != 1 || *minLines < 1 {
+		flags.Usage()
+		return 2
+	}
+	file := flags.Arg(0)
+	data, err := os.ReadFile(file)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	parsed, err := testtxt.ParseTests(file, templateOpts(templates)...)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	blocks, err := testtxt.SplitBlocks(data)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", file, err)
+		return 1
+	}
+	raw := strings.Split(string(data), "\n")
+	groups := findRepeated(factorSegs(parsed, raw), *minLines)
+	used := make(map[string]bool)
+	for _, b := range blocks {
+		used[b.Templ] = true
+	}
+	for _, t := range templates {
+		l, err := testtxt.SplitBlocks(readOrEmpty(t))
+		if err == nil {
+			for _, b := range l {
+				used[b.Templ] = true
+			}
+		}
+	}
+	i := 1
+	for _, g := range groups {
+		for ; used[fmt.Sprint(*prefix, i)]; i++ {
+		}
+		g.name = fmt.Sprint(*prefix, i)
+		used[g.name] = true
+	}
+	if !*write {
+		for _, g := range groups {
+			fmt.Fprintf(stdout, "%s: %d lines repeated %d times at lines",
+				g.name, g.n, len(g.occs))
+			for _, o := range g.occs {
+				fmt.Fprintf(stdout, " %d", o.seg.first+o.off+1)
+			}
+			fmt.Fprintln(stdout)
+		}
+		return 0
+	}
+	if groups == nil {
+		return 0
+	}
+	out := applyFactor(data, raw, blocks, groups)
+	if err := os.WriteFile(file, out, 0644); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// readOrEmpty returns content of file or nil if it can't be read.
+func readOrEmpty(file string) []byte {
+	data, _ := os.ReadFile(file)
+	return data
+}
+
+// factorSegs returns multi line texts of tests l, that are found
+// literally in raw lines of source. Lines, that would be changed by
+// moving them into a template, aren't taken.
+func factorSegs(l []*testtxt.Test, raw []string) []*factorSeg {
+	var result []*factorSeg
+	for i, t := range l {
+		for _, a := range t.Attrs {
+			if !strings.HasSuffix(a.Text, "\n") {
+				continue
+			}
+			lines := strings.Split(strings.TrimSuffix(a.Text, "\n"), "\n")
+			if a.Line+len(lines) > len(raw) ||
+				!slices.Equal(lines, raw[a.Line:a.Line+len(lines)]) {
+				continue
+			}
+			if slices.ContainsFunc(lines, func(s string) bool {
+				return strings.Contains(s, "{{") || strings.Contains(s, "[[") ||
+					strings.HasSuffix(s, "\r")
+			}) {
+				continue
+			}
+			result = append(result, &factorSeg{test: i, first: a.Line,
+				lines: lines})
+		}
+	}
+	return result
+}
+
+// findRepeated returns groups of at least minLines lines, that occur
+// repeatedly in segs, without overlap. Groups saving most lines are
+// taken first.
+func findRepeated(segs []*factorSeg, minLines int) []*factorGroup {
+	taken := make(map[*factorSeg][]bool)
+	for _, s := range segs {
+		taken[s] = make([]bool, len(s.lines))
+	}
+	free := func(o factorOcc, n int) bool {
+		return o.off+n <= len(o.seg.lines) && !slices.Contains(
+			taken[o.seg][o.off:o.off+n], true)
+	}
+	var result []*factorGroup
+	for {
+		windows := make(map[string][]factorOcc)
+		var keys []string
+		for _, s := range segs {
+			for off := 0; off+minLines <= len(s.lines); off++ {
+				o := factorOcc{s, off}
+				if !free(o, minLines) {
+					continue
+				}
+				key := strings.Join(s.lines[off:off+minLines], "\n")
+				if windows[key] == nil {
+					keys = append(keys, key)
+				}
+				windows[key] = append(windows[key], o)
+			}
+		}
+		var best *factorGroup
+		for _, key := range keys {
+			occs := windows[key]
+			if len(occs) < 2 {
+				continue
+			}
+			n := extendOccs(occs, minLines, free)
+			occs = nonOverlapping(occs, n)
+			if len(occs) < 2 {
+				continue
+			}
+			if best == nil || n*(len(occs)-1) > best.n*(len(best.occs)-1) {
+				best = &factorGroup{n: n, occs: occs}
+			}
+		}
+		if best == nil {
+			return result
+		}
+		for _, o := range best.occs {
+			for i := 0; i < best.n; i++ {
+				taken[o.seg][o.off+i] = true
+			}
+		}
+		result = append(result, best)
+	}
+}
+
+// extendOccs returns the number of lines, starting with n equal
+// lines, that are equal and free at all occurrences.
+func extendOccs(occs []factorOcc, n int, free func(factorOcc, int) bool,
+) int {
+	for {
+		o0 := occs[0]
+		for _, o := range occs {
+			if !free(o, n+1) ||
+				o.seg.lines[o.off+n] != o0.seg.lines[o0.off+n] {
+				return n
+			}
+		}
+		n++
+	}
+}
+
+// nonOverlapping removes occurrences of n lines, that overlap with a
+// preceding occurrence in the same text.
+func nonOverlapping(occs []factorOcc, n int) []factorOcc {
+	var result []factorOcc
+	for _, o := range occs {
+		if k := len(result); k > 0 && result[k-1].seg == o.seg &&
+			result[k-1].off+n > o.off {
+			continue
+		}
+		result = append(result, o)
+	}
+	return result
+}
+
+// applyFactor returns src with repeated lines of groups replaced by
+// calls of templates. Each template is defined in front of the first
+// test, that uses it.
+func applyFactor(src []byte, raw []string, blocks []*testtxt.Block,
+	groups []*factorGroup,
+) []byte {
+	// Line index of start of each test.
+	var testStart []int
+	for _, b := range blocks {
+		if b.Templ == "" {
+			testStart = append(testStart,
+				bytes.Count(src[:b.Start], []byte("\n")))
+		}
+	}
+	replace := make(map[int]*factorGroup)
+	defs := make(map[int][]*factorGroup)
+	for _, g := range groups {
+		first := len(testStart)
+		for _, o := range g.occs {
+			replace[o.seg.first+o.off] = g
+			first = min(first, o.seg.test)
+		}
+		defs[testStart[first]] = append(defs[testStart[first]], g)
+	}
+	var b strings.Builder
+	for i := 0; i < len(raw); i++ {
+		for _, g := range defs[i] {
+			o := g.occs[0]
+			fmt.Fprintf(&b, "=TEMPL=%s\n", g.name)
+			for _, line := range o.seg.lines[o.off : o.off+g.n] {
+				b.WriteString(line + "\n")
+			}
+			b.WriteString("=END=\n\n")
+		}
+		if g := replace[i]; g != nil {
+			fmt.Fprintf(&b, "[[%s]]\n", g.name)
+			i += g.n - 1
+			continue
+		}
+		b.WriteString(raw[i])
+		if i+1 < len(raw) {
+			b.WriteString("\n")
+		}
+	}
+	return []byte(b.String())
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/hknutzen/testtxt"
+)
+
+// format prints files of test descriptions in canonical format or
+// rewrites them with flag -w.
+func format(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("fmt", "[-w | -d | -l] [file.t...]", stderr)
+	write := fs.Bool("w", false, "write result to file instead of stdout")
+	diff := fs.Bool("d", false, "print diffs instead of formatted files")
+	list := fs.Bool("l", false, "list files, whose formatting differs")
+	if fs.Parse(args) != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		if *write {
+			fmt.Fprintln(stderr, "can't use -w with standard input")
+			return 2
+		}
+		src, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		out, err := testtxt.Format(src)
+		if err != nil {
+			fmt.Fprintf(stderr, "<stdin>: %v\n", err)
+			return 1
+		}
+		stdout.Write(out)
+		return 0
+	}
+	exit := 0
+	for _, file := range fs.Args() {
+		src, err := os.ReadFile(file)
+		if err == nil {
+			var out []byte
+			out, err = testtxt.Format(src)
+			if err == nil {
+				err = formatResult(file, src, out, *write, *diff, *list, stdout)
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", file, err)
+			exit = 1
+		}
+	}
+	return exit
+}
+
+// formatResult handles formatted content out of file with source src
+// as requested by flags.
+func formatResult(file string, src, out []byte, write, diff, list bool,
+	stdout io.Writer,
+) error {
+	changed := !bytes.Equal(src, out)
+	if list && changed {
+		fmt.Fprintln(stdout, file)
+	}
+	if diff && changed {
+		fmt.Fprintf(stdout, "diff %s\n%s", file,
+			testtxt.Diff(string(src), string(out)))
+	}
+	if write && changed {
+		return os.WriteFile(file, out, 0644)
+	}
+	if !write && !diff && !list {
+		_, err := stdout.Write(out)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	gofmt "go/format"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/hknutzen/testtxt"
+)
+
+// genField collects values of an attribute for gen-struct.
+type genField struct {
+	name   string // Go name of field
+	attr   string // name of attribute or prefix of map
+	values []string
+	mode   bool // attribute is used with mode
+	isMap  bool
+}
+
+// genStruct prints Go source with a struct type, that matches the
+// attributes used in a file of test descriptions, and a function,
+// that parses files into a slice of this type. Types of fields are
+// guessed from values of attributes.
+func genStruct(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("gen-struct",
+		"[-package name] [-type name] [-map prefix]... file.t", stderr)
+	pkg := flags.String("package", "tests", "`name` of generated package")
+	typeName := flags.String("type", "Test", "`name` of generated type")
+	maps := stringList{"ENV"}
+	flags.Var(&maps, "map",
+		"collect attributes =`PREFIX`_*= in field of type map[string]string")
+	var templates stringList
+	flags.Var(&templates, "templates", "read templates from `file`")
+	if flags.Parse(args) != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	file := flags.Arg(0)
+	l, err := testtxt.ParseTests(file, templateOpts(templates)...)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	var fields []*genField
+	byName := make(map[string]*genField)
+	for _, t := range l {
+		for _, a := range t.Attrs {
+			key, isMap := a.Name, false
+			for _, p := range maps {
+				if strings.HasPrefix(a.Name, p+"_") {
+					key, isMap = p, true
+				}
+			}
+			f := byName[key]
+			if f == nil {
+				f = &genField{name: goName(key), attr: key, isMap: isMap}
+				byName[key] = f
+				fields = append(fields, f)
+			}
+			f.values = append(f.values, a.Text)
+			f.mode = f.mode || a.Mode != ""
+		}
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Scaffold created by \"testtxt gen-struct %s\".\n\n", file)
+	fmt.Fprintf(&b, "package %s\n\n", *pkg)
+	fmt.Fprintf(&b, "import (\n\"time\"\n\n\"github.com/hknutzen/testtxt\"\n)\n\n")
+	fmt.Fprintf(&b, "// %s is a test described in %s.\n", *typeName, file)
+	fmt.Fprintf(&b, "type %s struct {\n", *typeName)
+	for _, f := range fields {
+		fmt.Fprintf(&b, "%s %s\n", f.name, f.goType())
+	}
+	fmt.Fprintf(&b, "}\n\n")
+	fmt.Fprintf(&b, "// parse%[1]ss parses file into a slice of %[1]s.\n",
+		*typeName)
+	fmt.Fprintf(&b, "func parse%[1]ss(file string) ([]%[1]s, error) {\n",
+		*typeName)
+	fmt.Fprintf(&b, "var l []%s\n", *typeName)
+	fmt.Fprintf(&b, "err := testtxt.ParseFile(file, &l)\n")
+	fmt.Fprintf(&b, "return l, err\n}\n")
+	src := b.Bytes()
+	if !slices.ContainsFunc(fields, func(f *genField) bool {
+		return f.goType() == "time.Duration"
+	}) {
+		src = bytes.Replace(src,
+			[]byte("import (\n\"time\"\n\n\"github.com/hknutzen/testtxt\"\n)"),
+			[]byte("import \"github.com/hknutzen/testtxt\""), 1)
+	}
+	out, err := gofmt.Source(src)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	stdout.Write(out)
+	return 0
+}
+
+// goType returns the type of field f, guessed from its values.
+func (f *genField) goType() string {
+	switch {
+	case f.isMap:
+		return "map[string]string"
+	case f.mode:
+		return "testtxt.Expected"
+	case allValues(f.values, func(v string) bool { return v == "" }):
+		return "bool"
+	case allValues(f.values, func(v string) bool {
+		_, err := strconv.Atoi(v)
+		return err == nil
+	}):
+		return "int"
+	case allValues(f.values, func(v string) bool {
+		_, err := time.ParseDuration(v)
+		return err == nil && v != "0"
+	}):
+		return "time.Duration"
+	}
+	return "string"
+}
+
+// allValues reports whether each value of l satisfies fn.
+func allValues(l []string, fn func(string) bool) bool {
+	return !slices.ContainsFunc(l, func(v string) bool { return !fn(v) })
+}
+
+// goName returns the name of a struct field for attribute name,
+// e.g. "FileName" for FILE_NAME.
+func goName(name string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		for i, r := range part {
+			if i == 0 {
+				b.WriteRune(unicode.ToUpper(r))
+			} else {
+				b.WriteRune(unicode.ToLower(r))
+			}
+		}
+	}
+	return b.String()
+}
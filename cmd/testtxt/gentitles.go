@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	gofmt "go/format"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/hknutzen/testtxt"
+)
+
+// genTitles prints Go source with a constant for the title of each
+// test in given files and a map from titles to files. It is intended
+// to be run by go generate, e.g.
+//
+//	//go:generate go run github.com/hknutzen/testtxt/cmd/testtxt gen-titles -o titles_test.go testdata/cmd.t
+//
+// such that test code, that refers to a test by its title, doesn't
+// compile any longer, if the test is renamed.
+func genTitles(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("gen-titles",
+		"[-o file.go] [-package name] [-prefix name] file.t...", stderr)
+	output := flags.String("o", "", "write to `file` instead of stdout")
+	pkg := flags.String("package", os.Getenv("GOPACKAGE"),
+		"`name` of generated package, default is $GOPACKAGE of go generate")
+	prefix := flags.String("prefix", "Title",
+		"`prefix` of names of constants; lower case gives unexported names")
+	var templates stringList
+	flags.Var(&templates, "templates", "read templates from `file`")
+	if flags.Parse(args) != nil {
+		return 2
+	}
+	if flags.NArg() == 0 || *pkg == "" {
+		flags.Usage()
+		return 2
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by \"testtxt gen-titles %s\"; DO NOT EDIT.\n\n",
+		strings.Join(args, " "))
+	fmt.Fprintf(&b, "package %s\n\n", *pkg)
+	used := make(map[string]bool)
+	type entry struct{ name, title, file string }
+	var entries []entry
+	b.WriteString("const (\n")
+	for _, file := range flags.Args() {
+		l, err := testtxt.ParseTests(file, templateOpts(templates)...)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		for _, t := range l {
+			name := constName(*prefix, t.Title)
+			base := name
+			for i := 2; used[name]; i++ {
+				name = base + strconv.Itoa(i)
+			}
+			used[name] = true
+			fmt.Fprintf(&b, "// %s:%d\n%s = %s\n", file, t.Attrs[0].Line,
+				name, strconv.Quote(t.Title))
+			entries = append(entries, entry{name, t.Title, file})
+		}
+	}
+	b.WriteString(")\n\n")
+	fmt.Fprintf(&b, "// %sFiles maps each title to the file of its test.\n",
+		*prefix)
+	fmt.Fprintf(&b, "var %sFiles = map[string]string{\n", *prefix)
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		// Duplicate titles would give duplicate keys.
+		if !seen[e.title] {
+			seen[e.title] = true
+			fmt.Fprintf(&b, "%s: %s,\n", e.name, strconv.Quote(e.file))
+		}
+	}
+	b.WriteString("}\n")
+	out, err := gofmt.Source(b.Bytes())
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if *output != "" {
+		err = os.WriteFile(*output, out, 0644)
+	} else {
+		_, err = stdout.Write(out)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// constName returns the name of a Go constant for title, e.g.
+// "TitleCopyFile" for "copy file".
+func constName(prefix, title string) string {
+	var b strings.Builder
+	b.WriteString(prefix)
+	upper := true
+	for _, r := range title {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if b.Len() == 0 && unicode.IsDigit(r) {
+				b.WriteString("T")
+			}
+			if upper {
+				r = unicode.ToUpper(r)
+			}
+			b.WriteRune(r)
+			upper = false
+		default:
+			upper = true
+		}
+	}
+	if b.Len() == 0 {
+		b.WriteString("T")
+	}
+	return b.String()
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/hknutzen/testtxt"
+)
+
+// goTable is a slice literal of structs found in Go source.
+type goTable struct {
+	name   string // name of variable
+	fields []string
+	lit    *ast.CompositeLit
+}
+
+// importGo converts a table of a table driven Go test, i.e. a slice
+// literal of struct type, into a file of test descriptions. Fields of
+// type string and int become attributes, fields of type bool become
+// attributes without value if true. The first field is taken as
+// title. Comments in front of elements are kept.
+func importGo(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("import-go", "[-var name] file.go", stderr)
+	varName := flags.String("var", "",
+		"convert table assigned to variable `name`")
+	if flags.Parse(args) != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	file := flags.Arg(0)
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, file, nil,
+		parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	var found []*goTable
+	for _, t := range findGoTables(f) {
+		if *varName == "" || t.name == *varName {
+			found = append(found, t)
+		}
+	}
+	switch {
+	case len(found) == 0 && *varName == "":
+		fmt.Fprintf(stderr, "%s: missing slice literal of struct type\n", file)
+		return 1
+	case len(found) == 0:
+		fmt.Fprintf(stderr, "%s: missing slice literal of struct type in %s\n",
+			file, *varName)
+		return 1
+	case len(found) > 1:
+		var names []string
+		for _, t := range found {
+			names = append(names, t.name)
+		}
+		fmt.Fprintf(stderr, "%s: multiple tables, select one with -var: %s\n",
+			file, strings.Join(names, ", "))
+		return 1
+	}
+	t := found[0]
+	var b strings.Builder
+	prev := t.lit.Lbrace
+	for i, elt := range t.lit.Elts {
+		test, err := goTableTest(t.fields, elt)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", fset.Position(elt.Pos()), err)
+			return 1
+		}
+		for j, a := range test.Attrs {
+			// Change value to the text, that will be read.
+			text := a.Text
+			if !strings.Contains(text, "\n") {
+				text = strings.TrimSpace(text)
+			} else if !strings.HasSuffix(text, "\n") {
+				text += "\n"
+			}
+			if text != a.Text {
+				fmt.Fprintf(stderr,
+					"%s: warning: =%s= can't keep value %q exactly\n",
+					fset.Position(elt.Pos()), a.Name, a.Text)
+				test.Attrs[j].Text = text
+			}
+		}
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		for _, cg := range f.Comments {
+			if cg.Pos() > prev && cg.End() < elt.Pos() {
+				for _, c := range cg.List {
+					b.WriteString(goCommentLines(c.Text))
+				}
+			}
+		}
+		if err := writeTest(&b, test); err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", fset.Position(elt.Pos()), err)
+			return 1
+		}
+		prev = elt.End()
+	}
+	io.WriteString(stdout, b.String())
+	return 0
+}
+
+// findGoTables returns slice literals of struct type in f, that are
+// assigned to variables. Struct types may be given as literal or by
+// name of a type declared in f.
+func findGoTables(f *ast.File) []*goTable {
+	types := make(map[string]*ast.StructType)
+	ast.Inspect(f, func(n ast.Node) bool {
+		if ts, ok := n.(*ast.TypeSpec); ok {
+			if st, ok := ts.Type.(*ast.StructType); ok {
+				types[ts.Name.Name] = st
+			}
+		}
+		return true
+	})
+	var result []*goTable
+	add := func(name ast.Expr, value ast.Expr) {
+		id, ok1 := name.(*ast.Ident)
+		lit, ok2 := value.(*ast.CompositeLit)
+		if !ok1 || !ok2 {
+			return
+		}
+		at, ok := lit.Type.(*ast.ArrayType)
+		if !ok {
+			return
+		}
+		var st *ast.StructType
+		switch x := at.Elt.(type) {
+		case *ast.StructType:
+			st = x
+		case *ast.Ident:
+			st = types[x.Name]
+		}
+		if st == nil {
+			return
+		}
+		t := &goTable{name: id.Name, lit: lit}
+		for _, fd := range st.Fields.List {
+			for _, n := range fd.Names {
+				t.fields = append(t.fields, n.Name)
+			}
+		}
+		result = append(result, t)
+	}
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch x := n.(type) {
+		case *ast.ValueSpec:
+			for i, v := range x.Values {
+				add(x.Names[i], v)
+			}
+		case *ast.AssignStmt:
+			if len(x.Lhs) == len(x.Rhs) {
+				for i, v := range x.Rhs {
+					add(x.Lhs[i], v)
+				}
+			}
+		}
+		return true
+	})
+	return result
+}
+
+// goTableTest returns the test of element elt of a table with struct
+// fields.
+func goTableTest(fields []string, elt ast.Expr) (*testtxt.Test, error) {
+	lit, ok := elt.(*ast.CompositeLit)
+	if !ok {
+		return nil, fmt.Errorf("expected struct literal")
+	}
+	values := make(map[string]ast.Expr)
+	for i, e := range lit.Elts {
+		if kv, ok := e.(*ast.KeyValueExpr); ok {
+			if id, ok := kv.Key.(*ast.Ident); ok {
+				values[id.Name] = kv.Value
+				continue
+			}
+			return nil, fmt.Errorf("unexpected key in struct literal")
+		}
+		if i >= len(fields) {
+			return nil, fmt.Errorf("too many values in struct literal")
+		}
+		values[fields[i]] = e
+	}
+	t := new(testtxt.Test)
+	for i, name := range fields {
+		e, found := values[name]
+		if !found {
+			if i == 0 {
+				return nil, fmt.Errorf("missing title in field %s", name)
+			}
+			continue
+		}
+		text, set, err := goValue(e)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %v", name, err)
+		}
+		if set || i == 0 {
+			t.Attrs = append(t.Attrs,
+				testtxt.Attr{Name: testtxt.AttrName(name), Text: text})
+		}
+	}
+	return t, nil
+}
+
+// goValue returns the text of constant expression e. It reports false,
+// if e has the zero value and hence no attribute is needed.
+func goValue(e ast.Expr) (string, bool, error) {
+	switch x := e.(type) {
+	case *ast.BasicLit:
+		switch x.Kind {
+		case token.STRING:
+			s, err := strconv.Unquote(x.Value)
+			return s, s != "", err
+		case token.INT:
+			return x.Value, x.Value != "0", nil
+		}
+	case *ast.Ident:
+		switch x.Name {
+		case "true":
+			return "", true, nil
+		case "false":
+			return "", false, nil
+		}
+	case *ast.ParenExpr:
+		return goValue(x.X)
+	case *ast.UnaryExpr:
+		if x.Op == token.SUB {
+			if lit, ok := x.X.(*ast.BasicLit); ok && lit.Kind == token.INT {
+				return "-" + lit.Value, true, nil
+			}
+		}
+	case *ast.BinaryExpr:
+		if x.Op == token.ADD && isGoString(x) {
+			a, _, err1 := goValue(x.X)
+			b, _, err2 := goValue(x.Y)
+			if err1 == nil && err2 == nil {
+				return a + b, a+b != "", nil
+			}
+		}
+	}
+	return "", false, fmt.Errorf("unsupported value")
+}
+
+// isGoString reports whether e is a string literal or a concatenation
+// of string literals.
+func isGoString(e ast.Expr) bool {
+	switch x := e.(type) {
+	case *ast.BasicLit:
+		return x.Kind == token.STRING
+	case *ast.ParenExpr:
+		return isGoString(x.X)
+	case *ast.BinaryExpr:
+		return x.Op == token.ADD && isGoString(x.X) && isGoString(x.Y)
+	}
+	return false
+}
+
+// goCommentLines returns Go comment text as comment lines of a file
+// of test descriptions.
+func goCommentLines(text string) string {
+	text = strings.TrimPrefix(text, "//")
+	text = strings.TrimPrefix(text, "/*")
+	text = strings.TrimSuffix(text, "*/")
+	var b strings.Builder
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		b.WriteString(strings.TrimRight("# "+line, " ") + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/hknutzen/testtxt"
+)
+
+// grepMatch is a line of a test matching the pattern of grep.
+type grepMatch struct {
+	line int
+	text string
+}
+
+// grep searches raw lines of tests and expanded text of attributes
+// for a regular expression and prints matching lines together with
+// title of test.
+func grep(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("grep", "[-i] [-l] pattern file.t...", stderr)
+	ignoreCase := fs.Bool("i", false, "match case insensitive")
+	titles := fs.Bool("l", false, "print only titles of matching tests")
+	var templates stringList
+	fs.Var(&templates, "templates", "read templates from `file`")
+	if fs.Parse(args) != nil {
+		return 2
+	}
+	if fs.NArg() < 2 {
+		fs.Usage()
+		return 2
+	}
+	pattern := fs.Arg(0)
+	if *ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	exit := 1
+	for _, file := range fs.Args()[1:] {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		raw := strings.Split(string(data), "\n")
+		m := new(testtxt.SourceMap)
+		opts := append(templateOpts(templates), testtxt.WithSourceMap(m))
+		l, err := testtxt.ParseTests(file, opts...)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		for i, t := range l {
+			first := t.Attrs[0].Line
+			last := len(raw)
+			if i+1 < len(l) {
+				last = l[i+1].Attrs[0].Line - 1
+			}
+			matches := grepTest(re, m, i, t, raw[first-1:last], first)
+			if matches == nil {
+				continue
+			}
+			exit = 0
+			if *titles {
+				fmt.Fprintf(stdout, "%s:%d: %s\n", file, first, t.Title)
+				continue
+			}
+			for _, g := range matches {
+				fmt.Fprintf(stdout, "%s:%d: %s: %s\n",
+					file, g.line, t.Title, g.text)
+			}
+		}
+	}
+	return exit
+}
+
+// grepTest returns matching lines of test t with index i, ordered by
+// line number. Expanded text of attributes is searched, with lines
+// mapped back to the file by m, and raw lines of t, starting at line
+// first. Each line of file is reported only once.
+func grepTest(re *regexp.Regexp, m *testtxt.SourceMap, i int,
+	t *testtxt.Test, raw []string, first int,
+) []grepMatch {
+	var result []grepMatch
+	seen := make(map[int]bool)
+	add := func(line int, text string) {
+		if re.MatchString(text) && !seen[line] {
+			seen[line] = true
+			result = append(result, grepMatch{line, text})
+		}
+	}
+	for _, a := range t.Attrs {
+		for n, text := range strings.Split(strings.TrimSuffix(a.Text, "\n"),
+			"\n") {
+			line, ok := m.Lookup(i, a.Name, n+1)
+			if !ok {
+				line = a.Line
+			}
+			add(line, text)
+		}
+	}
+	for j, text := range raw {
+		add(first+j, strings.TrimSuffix(text, "\r"))
+	}
+	slices.SortStableFunc(result, func(a, b grepMatch) int {
+		return a.line - b.line
+	})
+	return result
+}
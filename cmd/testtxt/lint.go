@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hknutzen/testtxt"
+	"github.com/hknutzen/testtxt/internal/gostruct"
+)
+
+// Rules of lint, given by code of testtxt.Diagnostic.
+var lintRules = []string{
+	"duplicate-title",
+	"indented-definition",
+	"long-line",
+	"missing-end",
+	"mixed-line-endings",
+	"noop-subst",
+	"redefined-template",
+	"tab",
+	"unused-template",
+}
+
+// ruleFlag collects severities of rules from flags like
+// -rule tab=off or -rule unused-template=error.
+type ruleFlag map[string]string
+
+func (r ruleFlag) String() string { return fmt.Sprint(map[string]string(r)) }
+
+func (r ruleFlag) Set(s string) error {
+	code, sev, found := strings.Cut(s, "=")
+	if !found {
+		return fmt.Errorf("expected rule=severity, got %q", s)
+	}
+	if !slices.Contains(lintRules, code) {
+		return fmt.Errorf("unknown rule %q, expected one of: %s",
+			code, strings.Join(lintRules, ", "))
+	}
+	switch sev {
+	case "off", "warning", "error":
+	default:
+		return fmt.Errorf("unknown severity %q, expected off, warning or error",
+			sev)
+	}
+	r[code] = sev
+	return nil
+}
+
+// lint checks files of test descriptions by testtxt.Lint with
+// configurable rules. Exit code is 1, if some error has been found.
+func lint(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("lint",
+		"[-rule code=off|warning|error]... [-max-line n] file.t...", stderr)
+	rules := make(ruleFlag)
+	flags.Var(rules, "rule", "set `code=severity` of rule, one of: "+
+		strings.Join(lintRules, ", "))
+	maxLine := flags.Int("max-line", 0,
+		"report lines longer than `n` characters by rule long-line")
+	schema := flags.String("schema", "",
+		"Go source `file` with struct type of test descriptions")
+	typeName := flags.String("type", "", "`name` of struct type in schema file")
+	out := newDiagOutput(flags, stdout)
+	var templates stringList
+	flags.Var(&templates, "templates", "read templates from `file`")
+	if flags.Parse(args) != nil {
+		return 2
+	}
+	if flags.NArg() == 0 || !out.check() {
+		flags.Usage()
+		return 2
+	}
+	var typ reflect.Type
+	if *schema != "" {
+		t, err := gostruct.Load(*schema, *typeName)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		typ = t
+	}
+	failed := false
+	for _, file := range flags.Args() {
+		var target any
+		if typ != nil {
+			target = reflect.New(reflect.SliceOf(typ)).Interface()
+		}
+		l := testtxt.Lint(file, target, templateOpts(templates)...)
+		if *maxLine > 0 {
+			long, err := longLines(file, *maxLine)
+			if err != nil {
+				fmt.Fprintln(stderr, err)
+				return 1
+			}
+			l = append(l, long...)
+			testtxt.SortDiagnostics(l)
+		}
+		var result []testtxt.Diagnostic
+		for _, d := range l {
+			switch rules[d.Code] {
+			case "off":
+				continue
+			case "warning":
+				d.Severity = testtxt.SeverityWarning
+			case "error":
+				d.Severity = testtxt.SeverityError
+			}
+			if d.Severity == testtxt.SeverityError {
+				failed = true
+			}
+			result = append(result, d)
+		}
+		out.file(file, result)
+	}
+	if err := out.close(); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// longLines returns a warning for each line of file, that is longer
+// than limit characters.
+func longLines(file string, limit int) ([]testtxt.Diagnostic, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var l []testtxt.Diagnostic
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if n := utf8.RuneCountInString(line); n > limit {
+			l = append(l, testtxt.Diagnostic{
+				File:     file,
+				Line:     i + 1,
+				Column:   limit + 1,
+				Severity: testtxt.SeverityWarning,
+				Code:     "long-line",
+				Message: fmt.Sprintf("line has %d characters, more than %d",
+					n, limit),
+			})
+		}
+	}
+	return l, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hknutzen/testtxt"
+)
+
+// listEntry describes a test in output of list with flag -json.
+type listEntry struct {
+	File  string   `json:"file"`
+	Line  int      `json:"line"`
+	Title string   `json:"title"`
+	Tags  []string `json:"tags,omitempty"`
+	Attrs []string `json:"attrs"`
+}
+
+// list prints titles of tests, optionally with line number, tags and
+// names of attributes.
+func list(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("list", "[-n] [-tags] [-attrs] [-json] file.t...", stderr)
+	lineNo := fs.Bool("n", false, "print file and line number of each test")
+	tags := fs.Bool("tags", false, "print tags given by =TAGS=")
+	attrs := fs.Bool("attrs", false, "print names of attributes")
+	asJSON := fs.Bool("json", false, "print JSON object for each test")
+	var templates stringList
+	fs.Var(&templates, "templates", "read templates from `file`")
+	if fs.Parse(args) != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	exit := 0
+	for _, file := range fs.Args() {
+		l, err := testtxt.ParseTests(file, templateOpts(templates)...)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			exit = 1
+		}
+		for _, t := range l {
+			e := listEntry{File: file, Line: t.Attrs[0].Line, Title: t.Title}
+			if text, found := t.Get("TAGS"); found {
+				e.Tags = strings.Fields(text)
+			}
+			for _, a := range t.Attrs {
+				e.Attrs = append(e.Attrs, a.Name)
+			}
+			if *asJSON {
+				b, _ := json.Marshal(e)
+				fmt.Fprintf(stdout, "%s\n", b)
+				continue
+			}
+			var b strings.Builder
+			if *lineNo {
+				fmt.Fprintf(&b, "%s:%d: ", e.File, e.Line)
+			}
+			b.WriteString(e.Title)
+			if *tags {
+				fmt.Fprintf(&b, "\t[%s]", strings.Join(e.Tags, " "))
+			}
+			if *attrs {
+				fmt.Fprintf(&b, "\t%s", strings.Join(e.Attrs, " "))
+			}
+			fmt.Fprintln(stdout, b.String())
+		}
+	}
+	return exit
+}
@@ -0,0 +1,80 @@
+// Command testtxt checks and transforms files of test descriptions.
+//
+// Usage:
+//
+//	testtxt <command> [flags] [arguments]
+//
+// Run "testtxt <command> -h" for help on a command.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+type command struct {
+	name  string
+	short string
+	run   func(args []string, stdout, stderr io.Writer) int
+}
+
+var commands = []*command{
+	{"validate", "check files of test descriptions for errors", validate},
+	{"fmt", "format files of test descriptions", format},
+	{"expand", "print tests with templates and substitutions applied", expand},
+	{"list", "list titles of tests", list},
+	{"grep", "search raw and expanded text of tests", grep},
+	{"stats", "print statistics about tests", stats},
+	{"rename-attr", "rename attribute in files", renameAttr},
+	{"extract", "move test with used templates to new file", extract},
+	{"sort", "sort tests by title or attribute", sortTests},
+	{"check-dups", "find duplicate titles in directory trees", checkDups},
+	{"convert", "convert between formats", convert},
+	{"doc", "generate Markdown documentation of tests", doc},
+	{"lint", "check files with configurable rules", lint},
+	{"templates", "report definitions and calls of templates",
+		templatesReport},
+	{"watch", "check files whenever they change", watch},
+	{"gen-struct", "generate Go struct type for tests", genStruct},
+	{"gen-titles", "generate Go constants for titles of tests", genTitles},
+	{"import-perl", "convert legacy Perl test file", importPerl},
+	{"import-go", "convert table of table driven Go test", importGo},
+	{"factor", "replace repeated lines by templates", factor},
+	{"update", "rewrite expected text of tests in update mode", update},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command given by args and returns the exit code:
+// 0 on success, 1 if problems were found and 2 for invalid usage.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+	for _, c := range commands {
+		if c.name == args[0] {
+			return c.run(args[1:], stdout, stderr)
+		}
+	}
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		usage(stdout)
+		return 0
+	}
+	fmt.Fprintf(stderr, "testtxt: unknown command %q\n", args[0])
+	usage(stderr)
+	return 2
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: testtxt <command> [flags] [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", c.name, c.short)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hknutzen/testtxt"
+)
+
+// TestCommands runs the tests of each file testdata/<command>.t.
+// Input without file markers is stored in file "input.t".
+// Since lines of nested test descriptions can't start with "=", they
+// are written with "%" and converted by =SUBST=/%/=/. Calls of
+// templates are converted likewise from <<name>>.
+func TestCommands(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.t"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		file, err := filepath.Abs(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := strings.TrimSuffix(filepath.Base(file), ".t")
+		t.Run(name, func(t *testing.T) {
+			testtxt.RunCmd(t, file, func(args []string, _ io.Reader,
+				stdout, stderr io.Writer,
+			) int {
+				return run(args, stdout, stderr)
+			}, testtxt.Chdir(testtxt.SingleFileName("input.t")))
+		})
+	}
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/hknutzen/testtxt"
+)
+
+// perlCalls maps names of test functions of legacy Perl test files to
+// attributes of their arguments.
+type perlCalls map[string][]string
+
+func (c perlCalls) String() string { return "" }
+
+func (c perlCalls) Set(s string) error {
+	name, attrs, found := strings.Cut(s, "=")
+	if !found || name == "" || attrs == "" {
+		return fmt.Errorf("expected name=ATTR,..., got %q", s)
+	}
+	c[name] = strings.Split(attrs, ",")
+	return nil
+}
+
+// perlImporter converts a legacy Perl test file into tests.
+type perlImporter struct {
+	src   string
+	pos   int // offset in src
+	calls perlCalls
+	vars  map[string]string
+	// Comments in front of next test.
+	comments []string
+	// Offset after body of last here-document of current line.
+	heredocEnd int
+	out        strings.Builder
+	tests      int
+	problems   []string
+}
+
+var (
+	perlSeparator = regexp.MustCompile(`^#+\s*$|^#{5,}`)
+	perlSkip      = regexp.MustCompile(
+		`^(use|no|require)\s|^my\s*(\(\s*[$@%\w\s,]*\)|[$@%]\w+)\s*;|` +
+			`^done_testing\b|^1;`)
+	perlAssign  = regexp.MustCompile(`^(?:my\s+)?\$(\w+)\s*=\s*`)
+	perlHeredoc = regexp.MustCompile(`^<<(~?)(?:'(\w+)'|"(\w+)"|(\w+))`)
+	perlCall    = regexp.MustCompile(`^(\w+)\s*\(`)
+	perlVar     = regexp.MustCompile(`^\$(\w+)`)
+	perlNumber  = regexp.MustCompile(`^-?\d+(\.\d+)?`)
+	// Definitions of attributes, that must not occur in text.
+	perlDef = regexp.MustCompile(`(?m)^=[A-Za-z0-9_]+(:[A-Za-z0-9_]+)?=`)
+)
+
+// importPerl converts legacy Perl test files, that assign input and
+// expected output to variables, e.g. by here-documents, and pass them
+// to test functions like test_run($title, $in, $out), into files of
+// test descriptions. Comments are kept. Unsupported statements are
+// reported and result in exit code 1.
+func importPerl(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("import-perl", "[-call name=ATTR,...]... file.t", stderr)
+	calls := perlCalls{
+		"test_run":  {"TITLE", "INPUT", "OUTPUT", "OPTIONS"},
+		"test_err":  {"TITLE", "INPUT", "ERROR", "OPTIONS"},
+		"test_warn": {"TITLE", "INPUT", "WARNING", "OPTIONS"},
+	}
+	flags.Var(calls, "call", "map arguments of Perl function `name=ATTR,...`"+
+		" to attributes; test_run, test_err and test_warn are predefined")
+	if flags.Parse(args) != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	file := flags.Arg(0)
+	data, err := os.ReadFile(file)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	p := &perlImporter{src: string(data), calls: calls,
+		vars: make(map[string]string)}
+	p.run()
+	for _, msg := range p.problems {
+		fmt.Fprintf(stderr, "%s:%s\n", file, msg)
+	}
+	if len(p.comments) > 0 {
+		p.out.WriteString("\n" + strings.Join(p.comments, "\n") + "\n")
+	}
+	io.WriteString(stdout, p.out.String())
+	if p.problems != nil {
+		return 1
+	}
+	return 0
+}
+
+// line returns the 1-based line number of offset pos.
+func (p *perlImporter) line(pos int) int {
+	return strings.Count(p.src[:pos], "\n") + 1
+}
+
+func (p *perlImporter) problem(pos int, format string, args ...any) {
+	p.problems = append(p.problems,
+		fmt.Sprintf("%d: %s", p.line(pos), fmt.Sprintf(format, args...)))
+}
+
+// run converts statements of p.src.
+func (p *perlImporter) run() {
+	for p.pos < len(p.src) {
+		start := p.pos
+		rest := p.src[p.pos:]
+		line, _, _ := strings.Cut(rest, "\n")
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+		case trimmed == "__END__" || trimmed == "__DATA__":
+			return
+		case strings.HasPrefix(trimmed, "#"):
+			if !perlSeparator.MatchString(trimmed) &&
+				!(start == 0 && strings.HasPrefix(trimmed, "#!")) {
+				p.comments = append(p.comments, trimmed)
+			}
+		case perlSkip.MatchString(trimmed):
+		default:
+			p.pos += len(line) - len(strings.TrimLeft(line, " \t"))
+			if !p.statement() {
+				p.problem(start, "unsupported statement: %s", trimmed)
+				p.pos = start
+				p.skipLine()
+				p.pos = max(p.pos, p.heredocEnd)
+				p.heredocEnd = 0
+			}
+			// Rest of statement has been consumed.
+			continue
+		}
+		p.skipLine()
+	}
+}
+
+// skipLine advances p.pos to the start of next line.
+func (p *perlImporter) skipLine() {
+	if i := strings.IndexByte(p.src[p.pos:], '\n'); i != -1 {
+		p.pos += i + 1
+	} else {
+		p.pos = len(p.src)
+	}
+}
+
+// statement converts an assignment or a call at p.pos. It reports
+// whether the statement was supported.
+func (p *perlImporter) statement() bool {
+	rest := p.src[p.pos:]
+	if m := perlAssign.FindStringSubmatch(rest); m != nil {
+		p.pos += len(m[0])
+		v, ok := p.value()
+		if !ok || !p.endStatement() {
+			return false
+		}
+		p.vars[m[1]] = v
+		return true
+	}
+	if m := perlCall.FindStringSubmatch(rest); m != nil {
+		attrs, found := p.calls[m[1]]
+		if !found {
+			return false
+		}
+		start := p.pos
+		p.pos += len(m[0])
+		var values []string
+		for {
+			p.skipSpace()
+			if strings.HasPrefix(p.src[p.pos:], ")") {
+				p.pos++
+				break
+			}
+			v, ok := p.value()
+			if !ok {
+				return false
+			}
+			values = append(values, v)
+			p.skipSpace()
+			if strings.HasPrefix(p.src[p.pos:], ",") {
+				p.pos++
+			}
+		}
+		if !p.endStatement() {
+			return false
+		}
+		if len(values) > len(attrs) {
+			p.problem(start, "%s has %d arguments, only %d are mapped by -call",
+				m[1], len(values), len(attrs))
+			return true
+		}
+		p.addTest(start, attrs, values)
+		return true
+	}
+	return false
+}
+
+// skipSpace skips white space including newlines.
+func (p *perlImporter) skipSpace() {
+	rest := p.src[p.pos:]
+	p.pos += len(rest) - len(strings.TrimLeft(rest, " \t\r\n"))
+}
+
+// endStatement reads ";" and the rest of the line, which must be
+// empty or a comment.
+func (p *perlImporter) endStatement() bool {
+	p.skipSpace()
+	if !strings.HasPrefix(p.src[p.pos:], ";") {
+		return false
+	}
+	p.pos++
+	line, _, _ := strings.Cut(p.src[p.pos:], "\n")
+	if t := strings.TrimSpace(line); t != "" && !strings.HasPrefix(t, "#") {
+		return false
+	}
+	p.skipLine()
+	// Body of here-document follows.
+	if p.pos < p.heredocEnd {
+		p.pos = p.heredocEnd
+	}
+	p.heredocEnd = 0
+	return true
+}
+
+// value reads a variable, a string, a here-document or a number.
+func (p *perlImporter) value() (string, bool) {
+	start := p.pos
+	rest := p.src[p.pos:]
+	if m := perlVar.FindStringSubmatch(rest); m != nil {
+		p.pos += len(m[0])
+		v, found := p.vars[m[1]]
+		if !found {
+			p.problem(start, "undefined variable $%s", m[1])
+		}
+		return v, true
+	}
+	if m := perlHeredoc.FindStringSubmatch(rest); m != nil {
+		p.pos += len(m[0])
+		return p.heredoc(start, m[1] == "~", m[2]+m[3]+m[4], m[2] == "")
+	}
+	if m := perlNumber.FindString(rest); m != "" {
+		p.pos += len(m)
+		return m, true
+	}
+	if strings.HasPrefix(rest, "undef") {
+		p.pos += len("undef")
+		return "", true
+	}
+	if rest == "" || rest[0] != '\'' && rest[0] != '"' {
+		return "", false
+	}
+	q := rest[0]
+	var b strings.Builder
+	for i := 1; i < len(rest); i++ {
+		c := rest[i]
+		switch {
+		case c == q:
+			p.pos += i + 1
+			if q == '\'' {
+				return b.String(), true
+			}
+			return p.interpolate(start, b.String())
+		case c == '\\' && i+1 < len(rest) &&
+			(rest[i+1] == q || rest[i+1] == '\\'):
+			if q == '"' {
+				// Keep escape for interpolate.
+				b.WriteByte(c)
+			}
+			i++
+			b.WriteByte(rest[i])
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", false
+}
+
+// heredoc reads the body of a here-document with terminator term,
+// that starts at the next line. With indent, common indentation of
+// body is removed. If interpolate is set, escapes are interpreted.
+func (p *perlImporter) heredoc(start int, indent bool, term string,
+	interpolate bool,
+) (string, bool) {
+	body := p.heredocEnd
+	if body == 0 {
+		body = p.pos + strings.IndexByte(p.src[p.pos:], '\n') + 1
+		if body == p.pos {
+			return "", false
+		}
+	}
+	var lines []string
+	pos := body
+	for {
+		if pos >= len(p.src) {
+			p.problem(start, "missing terminator %s of here-document", term)
+			return "", false
+		}
+		line, _, _ := strings.Cut(p.src[pos:], "\n")
+		pos += len(line) + 1
+		check := line
+		if indent {
+			check = strings.TrimLeft(line, " \t")
+		}
+		if check == term {
+			if indent {
+				prefix := line[:len(line)-len(check)]
+				for i, l := range lines {
+					lines[i] = strings.TrimPrefix(l, prefix)
+				}
+			}
+			break
+		}
+		lines = append(lines, line)
+	}
+	p.heredocEnd = min(pos, len(p.src))
+	text := ""
+	if lines != nil {
+		text = strings.Join(lines, "\n") + "\n"
+	}
+	if interpolate {
+		return p.interpolate(start, text)
+	}
+	return text, true
+}
+
+// interpolate interprets escapes of double quoted string s.
+// Interpolation of variables isn't supported.
+func (p *perlImporter) interpolate(pos int, s string) (string, bool) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				b.WriteByte(s[i])
+			}
+		case (c == '$' || c == '@') && i+1 < len(s) &&
+			(s[i+1] == '_' || s[i+1] == '{' ||
+				'a' <= s[i+1]|0x20 && s[i+1]|0x20 <= 'z'):
+			name, _, _ := strings.Cut(s[i:], " ")
+			p.problem(pos, "interpolation isn't supported: %s", name)
+			return "", true
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), true
+}
+
+// addTest writes a test with attributes attrs and corresponding
+// values, preceded by pending comments.
+func (p *perlImporter) addTest(pos int, attrs, values []string) {
+	t := new(testtxt.Test)
+	for i, v := range values {
+		if i > 0 && v == "" {
+			continue
+		}
+		if perlDef.MatchString(v) {
+			p.problem(pos, "=%s= has line, that would be taken as definition",
+				attrs[i])
+			return
+		}
+		t.Attrs = append(t.Attrs, testtxt.Attr{Name: attrs[i], Text: v})
+	}
+	var b strings.Builder
+	if err := writeTest(&b, t); err != nil {
+		p.problem(pos, "%v", err)
+		return
+	}
+	if p.tests > 0 || p.out.Len() > 0 {
+		p.out.WriteString("\n")
+	}
+	for _, c := range p.comments {
+		p.out.WriteString(c + "\n")
+	}
+	p.comments = nil
+	p.out.WriteString(b.String())
+	p.tests++
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/hknutzen/testtxt"
+)
+
+// renameAttr renames an attribute in files of test descriptions.
+func renameAttr(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("rename-attr", "old new file.t...", stderr)
+	if fs.Parse(args) != nil {
+		return 2
+	}
+	if fs.NArg() < 3 {
+		fs.Usage()
+		return 2
+	}
+	old, new := fs.Arg(0), fs.Arg(1)
+	exit := 0
+	for _, file := range fs.Args()[2:] {
+		n, err := renameInFile(file, old, new)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", file, err)
+			exit = 1
+			continue
+		}
+		if n > 0 {
+			fmt.Fprintf(stdout, "%s: renamed %d times\n", file, n)
+		}
+	}
+	return exit
+}
+
+// renameInFile renames attribute old to new in file and returns the
+// number of renamed definitions. File is only written if it changes.
+func renameInFile(file, old, new string) (int, error) {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+	r := testtxt.NewRewriter(src)
+	n, err := r.RenameAttr(old, new)
+	if err != nil || n == 0 {
+		return 0, err
+	}
+	return n, os.WriteFile(file, r.Bytes(), 0644)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/hknutzen/testtxt"
+)
+
+// sortTests reorders tests of a file by title or by value of another
+// attribute. Sorting is stable. Each template is placed in front of
+// the first test, that uses it; unused templates are placed in front
+// of all tests. A file, that redefines some template, isn't sorted.
+func sortTests(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("sort", "[-by attr] [-w] file.t", stderr)
+	by := flags.String("by", "", "sort by value of `attr` instead of title")
+	write := flags.Bool("w", false, "write result to file instead of stdout")
+	var templates stringList
+	flags.Var(&templates, "templates", "read templates from `file`")
+	if flags.Parse(args) != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	file := flags.Arg(0)
+	out, err := sortFile(file, *by, templates)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if *write {
+		err = os.WriteFile(file, out, 0644)
+	} else {
+		_, err = stdout.Write(out)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// sortFile returns the source of file with tests sorted by attribute
+// by or by title if by is empty.
+func sortFile(file, by string, templateFiles []string) ([]byte, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	l, err := testtxt.SplitBlocks(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	if l == nil {
+		return data, nil
+	}
+	parsed, err := testtxt.ParseTests(file, templateOpts(templateFiles)...)
+	if err != nil {
+		return nil, err
+	}
+	var tests, templList []*testtxt.Block
+	templates := make(map[string]*testtxt.Block)
+	key := make(map[*testtxt.Block]string)
+	for _, b := range l {
+		if b.Templ != "" {
+			// Tests using different definitions of a template can't
+			// be reordered freely.
+			if templates[b.Templ] != nil {
+				return nil, fmt.Errorf(
+					"%s: can't sort tests, template %s is defined multiple times",
+					file, b.Templ)
+			}
+			templList = append(templList, b)
+			templates[b.Templ] = b
+			continue
+		}
+		t := parsed[len(tests)]
+		key[b] = t.Title
+		if by != "" {
+			key[b], _ = t.Get(by)
+		}
+		tests = append(tests, b)
+	}
+	slices.SortStableFunc(tests, func(a, b *testtxt.Block) int {
+		return strings.Compare(key[a], key[b])
+	})
+	var w strings.Builder
+	w.Write(data[:l[0].Start])
+	var calls []string
+	for _, t := range tests {
+		calls = append(calls, t.Calls...)
+	}
+	done := make(map[string]bool)
+	addTemplates := func(used map[string]bool) {
+		for _, b := range templList {
+			if used[b.Templ] && !done[b.Templ] {
+				done[b.Templ] = true
+				addBlock(&w, b)
+			}
+		}
+	}
+	unused := make(map[string]bool)
+	usedAll := usedTemplates(templates, calls)
+	for _, b := range templList {
+		unused[b.Templ] = !usedAll[b.Templ]
+	}
+	addTemplates(unused)
+	for _, t := range tests {
+		addTemplates(usedTemplates(templates, t.Calls))
+		addBlock(&w, t)
+	}
+	return []byte(w.String()), nil
+}
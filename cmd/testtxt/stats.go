@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/hknutzen/testtxt"
+)
+
+// Calls of templates like [[name]] or [[name data]].
+var templCall = regexp.MustCompile(`(?s)\[\[([^\s\]]*).*?\]?\]\]`)
+
+// counter counts occurrences of names.
+type counter map[string]int
+
+// sorted returns names of c, ordered by decreasing count and name.
+func (c counter) sorted() []string {
+	var l []string
+	for name := range c {
+		l = append(l, name)
+	}
+	slices.SortFunc(l, func(a, b string) int {
+		if c[a] != c[b] {
+			return c[b] - c[a]
+		}
+		return strings.Compare(a, b)
+	})
+	return l
+}
+
+// block is an expanded attribute value for output of stats.
+type block struct {
+	file  string
+	line  int
+	title string
+	attr  string
+	size  int
+}
+
+// stats prints statistics about files of test descriptions.
+func stats(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("stats", "[-top n] file.t...", stderr)
+	top := fs.Int("top", 10, "print `n` largest expanded attributes")
+	var templates stringList
+	fs.Var(&templates, "templates", "read templates from `file`")
+	if fs.Parse(args) != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	perFile := make(counter)
+	attrs := make(counter)
+	calls := make(counter)
+	var blocks []block
+	total := 0
+	for _, file := range fs.Args() {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		for _, m := range templCall.FindAllStringSubmatch(string(data), -1) {
+			calls[m[1]]++
+		}
+		l, err := testtxt.ParseTests(file, templateOpts(templates)...)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		perFile[file] = len(l)
+		total += len(l)
+		for _, t := range l {
+			for _, a := range t.Attrs {
+				attrs[a.Name]++
+				blocks = append(blocks, block{
+					file, a.Line, t.Title, a.Name, strings.Count(a.Text, "\n"),
+				})
+			}
+		}
+	}
+	fmt.Fprintf(stdout, "Files: %d\nTests: %d\n", len(perFile), total)
+	printCounts(stdout, "Tests per file", perFile)
+	printCounts(stdout, "Attributes", attrs)
+	printCounts(stdout, "Template calls", calls)
+	slices.SortStableFunc(blocks, func(a, b block) int { return b.size - a.size })
+	if len(blocks) > *top {
+		blocks = blocks[:*top]
+	}
+	if len(blocks) > 0 {
+		fmt.Fprintln(stdout, "\nLargest expanded attributes:")
+		for _, b := range blocks {
+			fmt.Fprintf(stdout, "  %6d lines  %s:%d: =%s= of %s\n",
+				b.size, b.file, b.line, b.attr, b.title)
+		}
+	}
+	return 0
+}
+
+// printCounts prints counts of c with given heading.
+func printCounts(w io.Writer, heading string, c counter) {
+	if len(c) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%s:\n", heading)
+	for _, name := range c.sorted() {
+		fmt.Fprintf(w, "  %6d  %s\n", c[name], name)
+	}
+}
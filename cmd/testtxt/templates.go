@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/hknutzen/testtxt"
+)
+
+// templDef is the definition of a template together with its calls.
+type templDef struct {
+	name  string
+	file  string
+	line  int
+	calls int
+	// Files with calls, in order of first call.
+	files []string
+}
+
+// templatesReport lists templates defined in files of test
+// descriptions and in template files together with number of calls
+// and the files, where they are called. Unused templates and templates
+// called only once are marked.
+func templatesReport(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("templates", "[-ext .t] dir|file...", stderr)
+	ext := flags.String("ext", ".t", "read files with `extension` in directories")
+	var templates stringList
+	flags.Var(&templates, "templates", "read templates from `file`")
+	if flags.Parse(args) != nil {
+		return 2
+	}
+	if flags.NArg() == 0 && templates == nil {
+		flags.Usage()
+		return 2
+	}
+	files, err := findFiles(flags.Args(), *ext)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	var defs []*templDef
+	// Templates of template files, visible in all files.
+	global := make(map[string]*templDef)
+	exit := 0
+	scan := func(file string, visible map[string]*templDef) {
+		data, err := os.ReadFile(file)
+		if err == nil {
+			err = scanTemplates(file, data, visible, &defs)
+		}
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			exit = 1
+		}
+	}
+	for _, file := range templates {
+		scan(file, global)
+	}
+	for _, file := range files {
+		visible := make(map[string]*templDef)
+		for k, v := range global {
+			visible[k] = v
+		}
+		scan(file, visible)
+	}
+	for _, d := range defs {
+		fmt.Fprintf(stdout, "%s:%d: %s: %d %s", d.file, d.line, d.name,
+			d.calls, plural(d.calls, "call"))
+		if d.files != nil {
+			fmt.Fprintf(stdout, " in %s", strings.Join(d.files, ", "))
+		}
+		switch d.calls {
+		case 0:
+			fmt.Fprint(stdout, " (unused)")
+		case 1:
+			fmt.Fprint(stdout, " (single use)")
+		}
+		fmt.Fprintln(stdout)
+	}
+	return exit
+}
+
+// scanTemplates adds definitions of templates in data of file to
+// visible and defs and counts calls of visible templates.
+func scanTemplates(file string, data []byte, visible map[string]*templDef,
+	defs *[]*templDef,
+) error {
+	l, err := testtxt.SplitBlocks(data)
+	if err != nil {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+	for _, b := range l {
+		text := b.Text
+		if b.Templ != "" {
+			idx := strings.Index(text, "=TEMPL=")
+			line := bytes.Count(data[:b.Start+idx], []byte("\n")) + 1
+			// Calls in template refer to previous definitions.
+			countCalls(file, text[idx:], visible)
+			d := &templDef{name: b.Templ, file: file, line: line}
+			visible[b.Templ] = d
+			*defs = append(*defs, d)
+			continue
+		}
+		countCalls(file, text, visible)
+	}
+	return nil
+}
+
+// countCalls counts calls of visible templates in text of file.
+func countCalls(file, text string, visible map[string]*templDef) {
+	for _, m := range templCall.FindAllStringSubmatch(text, -1) {
+		if d := visible[m[1]]; d != nil {
+			d.calls++
+			if !slices.Contains(d.files, file) {
+				d.files = append(d.files, file)
+			}
+		}
+	}
+}
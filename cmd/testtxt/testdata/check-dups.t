@@ -0,0 +1,94 @@
+=TITLE=Duplicate titles in directory
+=INPUT=
+-- d/a.t
+%TITLE=x
+%TITLE=y
+-- d/sub/b.t
+%TITLE=z
+
+%TITLE=x
+-- d/c.txt
+%TITLE=y
+=SUBST=/%/=/
+=ARGS=check-dups d
+=STDOUT=
+duplicate title "x":
+  d/a.t:1
+  d/sub/b.t:3
+=END=
+=EXIT=1
+
+=TITLE=Other extension and explicit file
+=INPUT=
+-- d/a.t
+%TITLE=x
+%TITLE=y
+-- d/c.txt
+%TITLE=y
+=SUBST=/%/=/
+=ARGS=check-dups -ext .txt d d/a.t
+=STDOUT=
+duplicate title "y":
+  d/a.t:2
+  d/c.txt:1
+=END=
+=EXIT=1
+
+=TITLE=Duplicate attribute
+=INPUT=
+%TITLE=a
+%IN=1
+%TITLE=b
+%TITLE=c
+%OUT=x
+%IN=1
+=SUBST=/%/=/
+=ARGS=check-dups -by IN input.t
+=STDOUT=
+duplicate =IN= "1":
+  input.t:2
+  input.t:6
+=END=
+=EXIT=1
+
+=TITLE=No duplicates
+=INPUT=
+%TITLE=a
+%IN=1
+%TITLE=b
+%IN=2
+=SUBST=/%/=/
+=ARGS=check-dups -by IN input.t
+
+=TITLE=Parse error
+=INPUT=
+%TITLE=a
+%IN=<<t>>
+=SUBST=/%/=/
+=SUBST=/<</[[/
+=SUBST=/>>/]]/
+=ARGS=check-dups input.t
+=STDERR=
+input.t:2:5: calling unknown template t in test with =TITLE=a
+=END=
+=EXIT=1
+
+=TITLE=Missing directory
+=ARGS=check-dups missing
+=STDERR=
+lstat missing: no such file or directory
+=END=
+=EXIT=2
+
+=TITLE=Missing argument
+=ARGS=check-dups
+=STDERR=
+Usage: testtxt check-dups [-by attr] [-ext .t] dir|file...
+  -by attr
+    	compare value of attr instead of title
+  -ext extension
+    	read files with extension in directories (default ".t")
+  -templates file
+    	read templates from file
+=END=
+=EXIT=2
@@ -0,0 +1,215 @@
+=TITLE=To txtar
+=INPUT=
+-- in.t (base64)
+LS0tLSBhLnQKeAotLS0tIGRpci9iCnkK
+=ARGS=convert -to txtar in.t
+=STDOUT=
+-- a.t --
+x
+-- dir/b --
+y
+=END=
+
+=TITLE=To txtar, single file
+=INPUT=
+x
+=ARGS=convert -to txtar input.t
+=STDOUT=
+-- input --
+x
+=END=
+
+=TITLE=Symbolic link can't be converted to txtar
+=INPUT=
+-- in.t (base64)
+LS0tLSBsIC0+IGEK
+=ARGS=convert -to txtar in.t
+=STDERR=
+can't store symbolic link l in txtar
+=END=
+=EXIT=1
+
+=TITLE=From txtar
+=INPUT=
+-- in.txtar (base64)
+IyBjb21tZW50Ci0tIGEudCAtLQp4Ci0tIGRpci9iIC0tCnkK
+=ARGS=convert -from txtar in.txtar
+=STDOUT=
+---- a.t
+x
+---- dir/b
+y
+=END=
+
+=TITLE=Duplicate file in txtar
+=INPUT=
+-- in.txtar (base64)
+LS0gYSAtLQotLSBhIC0tCg==
+=ARGS=convert -from txtar in.txtar
+=STDERR=
+duplicate file a at lines 1 and 2
+=END=
+=EXIT=1
+
+=TITLE=From TOML
+=INPUT=
+[templates]
+greet = "Hello {{.}}"
+
+<<test>>
+TITLE = "a"
+IN = "x\ny\n"
+"OUT:RE" = { text = "<<greet you>>", subst = ["/you/World/"] }
+ON = true
+
+<<test>>
+TITLE = "b"
+COUNT = 2
+=SUBST=/<</[[/
+=SUBST=/>>/]]/
+=ARGS=convert -from toml input.t
+=STDOUT=
+%TEMPL=greet
+Hello {{.}}
+%END=
+
+%TITLE=a
+%IN=
+x
+y
+%END=
+%OUT:RE=<<greet you>>
+%SUBST=/you/World/
+%ON=
+%END=
+
+%TITLE=b
+%COUNT=2
+=SUBST=/%/=/
+=SUBST=/<</[[/
+=SUBST=/>>/]]/
+
+=TITLE=Invalid TOML
+=INPUT=
+[[test]
+=ARGS=convert -from toml input.t
+=STDERR=
+input.t: toml: line 2: expected end of table array name delimiter ']', but got '\n' instead
+=END=
+=EXIT=1
+
+=TITLE=Unsupported format
+=INPUT=
+x
+=ARGS=convert -to xml input.t
+=STDERR=
+unsupported format: xml
+=END=
+=EXIT=1
+
+=TITLE=Missing format
+=ARGS=convert
+=STDERR=
+Usage: testtxt convert -to format | -from format [file]
+  -from format
+    	convert from format: txtar, json, yaml, toml
+  -templates file
+    	read templates from file
+  -to format
+    	convert to format: txtar, json, yaml
+=END=
+=EXIT=2
+
+=TITLE=To JSON with expanded template
+=INPUT=
+%TEMPL=t
+x
+%TITLE=a
+%IN=
+<<t>>
+y
+%OUT:RE=z.*
+%TITLE=b
+=SUBST=/%/=/
+=SUBST=/<</[[/
+=SUBST=/>>/]]/
+=ARGS=convert -to json input.t
+=STDOUT=
+[
+  {
+    "TITLE": "a",
+    "IN": "x\ny\n",
+    "OUT:RE": "z.*"
+  },
+  {
+    "TITLE": "b"
+  }
+]
+=END=
+
+=TITLE=To YAML
+=INPUT=
+%TITLE=a
+%IN=
+x
+y
+%OUT:RE=z.*
+%TITLE=b
+=SUBST=/%/=/
+=ARGS=convert -to yaml input.t
+=STDOUT=
+- TITLE: a
+  IN: |
+    x
+    y
+  OUT:RE: z.*
+- TITLE: b
+=END=
+
+=TITLE=From JSON
+=INPUT=
+[
+  {"TITLE": "a", "IN": "x\ny\n", "OUT:RE": "z.*"},
+  {"TITLE": "b", "COUNT": 2}
+]
+=ARGS=convert -from json input.t
+=STDOUT=
+%TITLE=a
+%IN=
+x
+y
+%END=
+%OUT:RE=z.*
+
+%TITLE=b
+%COUNT=2
+=SUBST=/%/=/
+
+=TITLE=From YAML
+=INPUT=
+- TITLE: a
+  IN: |
+    x
+    y
+  OUT:RE: z.*
+- TITLE: b
+=ARGS=convert -from yaml input.t
+=STDOUT=
+%TITLE=a
+%IN=
+x
+y
+%END=
+%OUT:RE=z.*
+
+%TITLE=b
+=SUBST=/%/=/
+
+=TITLE=Invalid JSON
+=INPUT=
+{"TITLE": "a"}
+=ARGS=convert -from json input.t
+=STDERR=
+input.t: expected [ in JSON, got {
+=END=
+=EXIT=1
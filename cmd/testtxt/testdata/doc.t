@@ -0,0 +1,88 @@
+=TITLE=Description from comment and attribute
+=INPUT=
+# First test
+# with two lines.
+%TITLE=a
+%IN=x
+%OUT:RE=
+```y```
+%END=
+
+%TITLE=b
+%DESCRIPTION=Second test.
+%IN=
+%TITLE=c
+=SUBST=/%/=/
+=ARGS=doc input.t
+=STDOUT=
+# input.t
+
+## a
+
+First test
+with two lines.
+
+<details><summary>IN</summary>
+
+```
+x
+```
+
+</details>
+
+<details><summary>OUT:RE</summary>
+
+````
+```y```
+````
+
+</details>
+
+## b
+
+Second test.
+
+<details><summary>IN</summary>
+
+```
+```
+
+</details>
+
+## c
+=END=
+
+=TITLE=Other attribute as description, write to directory
+=INPUT=
+-- a.t
+%TITLE=a
+%INFO=About a.
+-- b.t
+%TITLE=b
+-- out/ (dir)
+=SUBST=/%/=/
+=ARGS=doc -o out -desc INFO a.t b.t
+=OUTPUT=
+-- a.t
+%TITLE=a
+%INFO=About a.
+-- b.t
+%TITLE=b
+-- out/a.md
+# a.t
+
+## a
+
+About a.
+-- out/b.md
+# b.t
+
+## b
+=SUBST=/%/=/
+
+=TITLE=Missing file
+=ARGS=doc missing.t
+=STDERR=
+open missing.t: no such file or directory
+=END=
+=EXIT=1
@@ -0,0 +1,81 @@
+=TITLE=All tests
+=INPUT=
+%TEMPL=t
+x{{.}}
+%TITLE=a
+%IN=
+<<t 1>>
+<<t 2>>
+%SUBST=/x/y/
+%OUT:RE=\d
+
+%TITLE=b
+%IN=z
+=SUBST=/%/=/
+=SUBST=/<</[[/
+=SUBST=/>>/]]/
+=ARGS=expand input.t
+=STDOUT=
+%TITLE=a
+%IN=
+y1
+y2
+%END=
+%OUT:RE=\d
+
+%TITLE=b
+%IN=z
+=SUBST=/%/=/
+
+=TITLE=Single test
+=INPUT=
+%TITLE=a
+%IN=x
+%TITLE=b
+%IN=y
+=SUBST=/%/=/
+=ARGS=expand input.t b
+=STDOUT=
+%TITLE=b
+%IN=y
+=SUBST=/%/=/
+
+=TITLE=Templates from file
+=INPUT=
+-- t.t
+%TEMPL=t
+x
+-- a.t
+%TITLE=a
+%IN=<<t>>
+=SUBST=/%/=/
+=SUBST=/<</[[/
+=SUBST=/>>/]]/
+=ARGS=expand -templates t.t a.t
+=STDOUT=
+%TITLE=a
+%IN=x
+=SUBST=/%/=/
+
+=TITLE=Missing test
+=INPUT=
+%TITLE=a
+=SUBST=/%/=/
+=ARGS=expand input.t b
+=STDERR=
+input.t: missing test "b"
+=END=
+=EXIT=1
+
+=TITLE=Text can't be represented
+=INPUT=
+%TITLE=a
+%IN=
+x
+%SUBST=/x/%B=/
+=SUBST=/%/=/
+=ARGS=expand input.t
+=STDERR=
+input.t: test "a": =IN=: line would be taken as =B=: =B=
+=END=
+=EXIT=1
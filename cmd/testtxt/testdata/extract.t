@@ -0,0 +1,118 @@
+=TITLE=Move test with used templates
+=INPUT=
+-- a.t
+%TEMPL=t
+x
+%TEMPL=u
+y
+%TITLE=a
+%IN=<<t>>
+%TITLE=b
+%IN=<<u>>
+=SUBST=/%/=/
+=SUBST=/<</[[/
+=SUBST=/>>/]]/
+=ARGS=extract a a.t b.t
+=OUTPUT=
+-- a.t
+%TEMPL=u
+y
+%END=
+
+%TITLE=b
+%IN=<<u>>
+-- b.t
+%TEMPL=t
+x
+%END=
+
+%TITLE=a
+%IN=<<t>>
+=SUBST=/%/=/
+=SUBST=/<</[[/
+=SUBST=/>>/]]/
+
+=TITLE=Keep template used indirectly by later template
+=INPUT=
+-- a.t
+%TEMPL=t
+x
+%TITLE=a
+%IN=<<t>>
+%TEMPL=u
+<<t>>
+%TITLE=b
+%IN=<<u>>
+=SUBST=/%/=/
+=SUBST=/<</[[/
+=SUBST=/>>/]]/
+=ARGS=extract a a.t b.t
+=OUTPUT=
+-- a.t
+%TEMPL=t
+x
+%END=
+
+%TEMPL=u
+<<t>>
+%END=
+
+%TITLE=b
+%IN=<<u>>
+-- b.t
+%TEMPL=t
+x
+%END=
+
+%TITLE=a
+%IN=<<t>>
+=SUBST=/%/=/
+=SUBST=/<</[[/
+=SUBST=/>>/]]/
+
+=TITLE=Invalid result
+=INPUT=
+-- a.t
+%TITLE=a
+%IN=<<m>>
+%TITLE=b
+=SUBST=/%/=/
+=SUBST=/<</[[/
+=SUBST=/>>/]]/
+=ARGS=extract b a.t b.t
+=STDERR=
+a.t would become invalid, no file changed:
+a.t:2:5: calling unknown template m in test with =TITLE=a
+=END=
+=EXIT=1
+=OUTPUT=
+-- a.t
+%TITLE=a
+%IN=<<m>>
+%TITLE=b
+=SUBST=/%/=/
+=SUBST=/<</[[/
+=SUBST=/>>/]]/
+
+=TITLE=Missing test
+=INPUT=
+-- a.t
+%TITLE=a
+=SUBST=/%/=/
+=ARGS=extract b a.t b.t
+=STDERR=
+a.t: missing test "b"
+=END=
+=EXIT=1
+
+=TITLE=Existing destination
+=INPUT=
+-- a.t
+%TITLE=a
+-- b.t
+=SUBST=/%/=/
+=ARGS=extract a a.t b.t
+=STDERR=
+b.t: file already exists
+=END=
+=EXIT=1
@@ -0,0 +1,113 @@
+=TITLE=Report repeated lines
+=INPUT=
+%TITLE=a
+%IN=
+1
+2
+3
+x
+%END=
+%TITLE=b
+%IN=
+y
+1
+2
+3
+%OUT=
+1
+2
+%END=
+=SUBST=/%/=/
+=ARGS=factor -min 2 input.t
+=STDOUT=
+common1: 2 lines repeated 3 times at lines 3 11 15
+=END=
+
+=TITLE=Replace repeated lines
+=INPUT=
+# Comment
+%TITLE=a
+%IN=
+1
+2
+3
+x
+%END=
+
+%TITLE=b
+%IN=
+y
+1
+2
+3
+%END=
+=SUBST=/%/=/
+=ARGS=factor -min 2 -prefix t -w input.t
+=OUTPUT=
+-- input.t
+%TEMPL=t1
+1
+2
+3
+%END=
+
+# Comment
+%TITLE=a
+%IN=
+<<t1>>
+x
+%END=
+
+%TITLE=b
+%IN=
+y
+<<t1>>
+%END=
+=SUBST=/%/=/
+=SUBST=/<</[[/
+=SUBST=/>>/]]/
+
+=TITLE=Skip used name
+=INPUT=
+%TEMPL=common1
+z
+%TITLE=a
+%IN=
+1
+2
+%END=
+%TITLE=b
+%IN=
+1
+2
+%OUT=<<common1>>
+=SUBST=/%/=/
+=SUBST=/<</[[/
+=SUBST=/>>/]]/
+=ARGS=factor -min 2 input.t
+=STDOUT=
+common2: 2 lines repeated 2 times at lines 5 10
+=END=
+
+=TITLE=Nothing repeated
+=INPUT=
+%TITLE=a
+%IN=
+1
+2
+%END=
+=SUBST=/%/=/
+=ARGS=factor -min 2 -w input.t
+=OUTPUT=
+-- input.t
+%TITLE=a
+%IN=
+1
+2
+%END=
+=SUBST=/%/=/
+
+=TITLE=Invalid minimum
+=ARGS=factor -min 0 input.t
+=STDERR:CONTAINS=Usage: testtxt factor
+=EXIT=2
@@ -0,0 +1,73 @@
+=TITLE=Print formatted file
+=INPUT=
+%TITLE=a
+%IN=x
+
+%OUT=y
+%TITLE=b
+=SUBST=/%/=/
+=ARGS=fmt input.t
+=STDOUT=
+%TITLE=a
+%IN=x
+%OUT=y
+
+%TITLE=b
+=SUBST=/%/=/
+
+=TITLE=List and diff
+=INPUT=
+-- a.t
+%TITLE=a
+%IN=x
+-- b.t
+%TITLE=a
+%IN=x
+%TITLE=b
+=SUBST=/%/=/
+=ARGS=fmt -l -d a.t b.t
+=STDOUT=
+b.t
+diff b.t
+--- expected
++++ got
+@@ -1,3 +1,4 @@
+ %TITLE=a
+ %IN=x
++
+ %TITLE=b
+=SUBST=/%/=/
+
+=TITLE=Write
+=INPUT=
+%TITLE=a  
+%IN=
+x
+=SUBST=/%/=/
+=ARGS=fmt -w input.t
+=OUTPUT=
+-- input.t
+%TITLE=a
+%IN=
+x
+%END=
+=SUBST=/%/=/
+
+=TITLE=Syntax error
+=INPUT=
+-- a.t
+x
+-- b.t
+%TITLE=b
+=SUBST=/%/=/
+=ARGS=fmt -w a.t b.t
+=STDERR=
+a.t: line 1: unexpected x
+=END=
+=EXIT=1
+=OUTPUT=
+-- a.t
+x
+-- b.t
+%TITLE=b
+=SUBST=/%/=/
@@ -0,0 +1,81 @@
+=TITLE=Guess types
+=INPUT=
+%TITLE=a
+%INPUT=x
+%OUTPUT:RE=y.*
+%COUNT=1
+%TIMEOUT=2s
+%SKIP=
+%ENV_HOME=/tmp
+%TITLE=b
+%COUNT=2
+%TIMEOUT=5
+%ENV_USER=u
+=SUBST=/%/=/
+=ARGS=gen-struct input.t
+=STDOUT=
+// Scaffold created by "testtxt gen-struct input.t".
+
+package tests
+
+import "github.com/hknutzen/testtxt"
+
+// Test is a test described in input.t.
+type Test struct {
+	Title   string
+	Input   string
+	Output  testtxt.Expected
+	Count   int
+	Timeout string
+	Skip    bool
+	Env     map[string]string
+}
+
+// parseTests parses file into a slice of Test.
+func parseTests(file string) ([]Test, error) {
+	var l []Test
+	err := testtxt.ParseFile(file, &l)
+	return l, err
+}
+=END=
+
+=TITLE=Package, type and map prefix
+=INPUT=
+%TITLE=a
+%TIMEOUT=2s
+%ARG_1=x
+%ARG_2=y
+=SUBST=/%/=/
+=ARGS=gen-struct -package p -type Case -map ARG input.t
+=STDOUT=
+// Scaffold created by "testtxt gen-struct input.t".
+
+package p
+
+import (
+	"time"
+
+	"github.com/hknutzen/testtxt"
+)
+
+// Case is a test described in input.t.
+type Case struct {
+	Title   string
+	Timeout time.Duration
+	Arg     map[string]string
+}
+
+// parseCases parses file into a slice of Case.
+func parseCases(file string) ([]Case, error) {
+	var l []Case
+	err := testtxt.ParseFile(file, &l)
+	return l, err
+}
+=END=
+
+=TITLE=Missing file
+=ARGS=gen-struct missing.t
+=STDERR=
+open missing.t: no such file or directory
+=END=
+=EXIT=1
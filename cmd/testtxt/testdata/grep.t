@@ -0,0 +1,49 @@
+=TITLE=Raw and expanded text, each line once
+=INPUT=
+%TEMPL=t
+Hello {{.}}
+%TITLE=a
+%IN=
+<<t World>>
+x
+%TITLE=b
+%IN=hello
+=SUBST=/%/=/
+=SUBST=/<</[[/
+=SUBST=/>>/]]/
+=ARGS=grep Hello|x input.t
+=STDOUT=
+input.t:5: a: Hello World
+input.t:6: a: x
+=END=
+
+=TITLE=Ignore case, print titles
+=INPUT=
+%TITLE=a
+%IN=Hello
+%TITLE=b
+%IN=hello
+%TITLE=c
+=SUBST=/%/=/
+=ARGS=grep -i -l hello input.t
+=STDOUT=
+input.t:1: a
+input.t:3: b
+=END=
+
+=TITLE=No match
+=INPUT=
+%TITLE=a
+=SUBST=/%/=/
+=ARGS=grep x input.t
+=EXIT=1
+
+=TITLE=Invalid pattern
+=INPUT=
+%TITLE=a
+=SUBST=/%/=/
+=ARGS=grep ( input.t
+=STDERR=
+error parsing regexp: missing closing ): `(`
+=END=
+=EXIT=2
@@ -0,0 +1,124 @@
+=TITLE=Convert table with struct type literal
+=INPUT=
+-- x_test.go
+package x
+
+func TestX(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		count int
+		fail  bool
+	}{
+		// First test.
+		{"a", "x\n" + "y\n", 2, false},
+		/* Second test
+		   with block comment. */
+		{name: "b", fail: true, count: -1},
+		{name: ("c"), in: ""},
+	}
+	_ = tests
+}
+=ARGS=import-go x_test.go
+=STDOUT=
+# First test.
+%NAME=a
+%IN=
+x
+y
+%END=
+%COUNT=2
+
+# Second test
+# with block comment.
+%NAME=b
+%COUNT=-1
+%FAIL=
+%END=
+
+%NAME=c
+=SUBST=/%/=/
+
+=TITLE=Select table of named type
+=INPUT=
+-- x_test.go
+package x
+
+type test struct {
+	title string
+	out   string
+}
+
+var a = []test{{"a", "1"}}
+
+var b = []test{{title: "b", out: "1\n2"}, {title: "c", out: " 3 "}}
+=ARGS=import-go -var b x_test.go
+=STDOUT=
+%TITLE=b
+%OUT=
+1
+2
+%END=
+
+%TITLE=c
+%OUT=3
+=SUBST=/%/=/
+=STDERR=
+x_test.go:10:16: warning: =OUT= can't keep value "1\n2" exactly
+x_test.go:10:43: warning: =OUT= can't keep value " 3 " exactly
+=END=
+
+=TITLE=Multiple tables
+=INPUT=
+-- x_test.go
+package x
+
+var a = []struct{ s string }{{"a"}}
+var b = []struct{ s string }{{"b"}}
+=ARGS=import-go x_test.go
+=STDERR=
+x_test.go: multiple tables, select one with -var: a, b
+=END=
+=EXIT=1
+
+=TITLE=Missing table
+=INPUT=
+-- x_test.go
+package x
+
+var a = []struct{ s string }{{"a"}}
+=ARGS=import-go -var b x_test.go
+=STDERR=
+x_test.go: missing slice literal of struct type in b
+=END=
+=EXIT=1
+
+=TITLE=Unsupported values
+=INPUT=
+-- x_test.go
+package x
+
+var a = []struct {
+	s string
+	n int
+}{{"a", 1 + 2}}
+=ARGS=import-go x_test.go
+=STDERR=
+x_test.go:6:3: field n: unsupported value
+=END=
+=EXIT=1
+
+=TITLE=Missing title
+=INPUT=
+-- x_test.go
+package x
+
+var a = []struct {
+	s string
+	n int
+}{{n: 1}}
+=ARGS=import-go x_test.go
+=STDERR=
+x_test.go:6:3: missing title in field s
+=END=
+=EXIT=1
@@ -0,0 +1,97 @@
+=TITLE=Convert here-documents and strings
+=INPUT=
+#!/usr/bin/perl
+use strict;
+use Test::More;
+
+my ($in, $out, $title);
+
+############################################################
+# Simple test
+$title = 'Simple';
+$in = <<'END';
+a
+b
+END
+$out = <<"END";
+x\ty
+END
+test_run($title, $in, $out);
+
+############################################################
+$title = "With option";
+$in = <<~END;
+    c
+      d
+    END
+test_err($title, $in, 'error', '-q');
+
+check($title, 1, 2);
+done_testing;
+# Trailing comment
+=ARGS=import-perl input.t
+=STDOUT=
+# Simple test
+%TITLE=Simple
+%INPUT=
+a
+b
+%END=
+%OUTPUT=
+x	y
+%END=
+
+%TITLE=With option
+%INPUT=
+c
+  d
+%END=
+%ERROR=error
+%OPTIONS=-q
+
+# Trailing comment
+=SUBST=/%/=/
+=STDERR=
+input.t:27: unsupported statement: check($title, 1, 2);
+=END=
+=EXIT=1
+
+=TITLE=Own function
+=INPUT=
+test_it('a', "1\n2\n", 3);
+=ARGS=import-perl -call test_it=TITLE,IN,COUNT input.t
+=STDOUT=
+%TITLE=a
+%IN=
+1
+2
+%END=
+%COUNT=3
+=SUBST=/%/=/
+
+=TITLE=Problems
+=INPUT=
+test_run('a', $missing, "$x");
+test_run('b', 'x', 'y', 'z', 'w');
+test_run('c', <<END, 'y');
+%IN=z
+END
+=SUBST=/%/=/
+=ARGS=import-perl input.t
+=STDOUT=
+%TITLE=a
+=SUBST=/%/=/
+=STDERR=
+input.t:1: undefined variable $missing
+input.t:1: interpolation isn't supported: $x
+input.t:2: test_run has 5 arguments, only 4 are mapped by -call
+input.t:3: =INPUT= has line, that would be taken as definition
+=END=
+=EXIT=1
+
+=TITLE=Invalid -call
+=ARGS=import-perl -call test_it input.t
+=STDERR:CONTAINS=
+invalid value "test_it" for flag -call: expected name=ATTR,..., got "test_it"
+=END=
+=EXIT=2
@@ -0,0 +1,71 @@
+=TITLE=Default severities
+=INPUT=
+%TEMPL=x
+1
+%TITLE=a
+%TITLE=a
+=SUBST=/%/=/
+=ARGS=lint input.t
+=STDOUT=
+input.t:1:8: warning: unused template x
+input.t:4:1: warning: duplicate =TITLE=a, already used at line 3
+=END=
+
+=TITLE=Change severity and switch off rule
+=INPUT=
+%TEMPL=x
+1
+%TITLE=a
+%TITLE=a
+=SUBST=/%/=/
+=ARGS=lint -rule unused-template=error -rule duplicate-title=off input.t
+=STDOUT=
+input.t:1:8: error: unused template x
+=END=
+=EXIT=1
+
+=TITLE=Long lines
+=INPUT=
+%TITLE=a
+%IN=1234567890
+=SUBST=/%/=/
+=ARGS=lint -max-line 8 -rule long-line=error input.t
+=STDOUT=
+input.t:2:9: error: line has 14 characters, more than 8
+=END=
+=EXIT=1
+
+=TITLE=Unknown rule
+=ARGS=lint -rule foo=off input.t
+=STDERR:CONTAINS=
+invalid value "foo=off" for flag -rule: unknown rule "foo", expected one of: duplicate-title, indented-definition, long-line, missing-end, mixed-line-endings, noop-subst, redefined-template, tab, unused-template
+Usage: testtxt lint
+=END=
+=EXIT=2
+
+=TITLE=Unknown severity
+=ARGS=lint -rule tab=fatal input.t
+=STDERR:CONTAINS=
+invalid value "tab=fatal" for flag -rule: unknown severity "fatal", expected off, warning or error
+  -rule code=severity
+=END=
+=EXIT=2
+
+=TITLE=Schema
+=INPUT=
+-- s.go
+package p
+
+type T struct {
+	Title string
+	Input string
+}
+-- a.t
+%TITLE=a
+%OUTPUT=x
+=SUBST=/%/=/
+=ARGS=lint -schema s.go a.t
+=STDOUT=
+a.t:2:1: error: unexpected =OUTPUT= in test with =TITLE=a
+=END=
+=EXIT=1
@@ -0,0 +1,61 @@
+=TITLE=Titles
+=INPUT=
+-- a.t
+%TITLE=a
+%TAGS=x y
+%IN=1
+
+%TITLE=b
+-- b.t
+%TITLE=c
+%OUT=2
+=SUBST=/%/=/
+=ARGS=list a.t b.t
+=STDOUT=
+a
+b
+c
+=END=
+
+=TITLE=Line numbers, tags and attributes
+=INPUT=
+%TITLE=a
+%TAGS=x y
+%IN=1
+
+%TITLE=b
+=SUBST=/%/=/
+=ARGS=list -n -tags -attrs input.t
+=STDOUT=
+input.t:1: a	[x y]	TITLE TAGS IN
+input.t:5: b	[]	TITLE
+=END=
+
+=TITLE=JSON
+=INPUT=
+%TITLE=a
+%TAGS=x y
+%IN=1
+
+%TITLE=b
+=SUBST=/%/=/
+=ARGS=list -json input.t
+=STDOUT=
+{"file":"input.t","line":1,"title":"a","tags":["x","y"],"attrs":["TITLE","TAGS","IN"]}
+{"file":"input.t","line":5,"title":"b","attrs":["TITLE"]}
+=END=
+
+=TITLE=Parse error
+=INPUT=
+%TITLE=a
+%IN=<<t>>
+=SUBST=/%/=/
+=SUBST=/<</[[/
+=SUBST=/>>/]]/
+=ARGS=list input.t
+=STDOUT=
+a
+=STDERR=
+input.t:2:5: calling unknown template t in test with =TITLE=a
+=END=
+=EXIT=1
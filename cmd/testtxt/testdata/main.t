@@ -0,0 +1,20 @@
+=TITLE=No command
+=ARGS=
+=STDERR:CONTAINS=
+Usage: testtxt <command> [flags] [arguments]
+Commands:
+  validate   check files of test descriptions for errors
+=EXIT=2
+
+=TITLE=Unknown command
+=ARGS=foo
+=STDERR:CONTAINS=
+testtxt: unknown command "foo"
+Usage: testtxt <command> [flags] [arguments]
+=EXIT=2
+
+=TITLE=Help
+=ARGS=help
+=STDOUT:CONTAINS=
+Usage: testtxt <command> [flags] [arguments]
+  validate   check files of test descriptions for errors
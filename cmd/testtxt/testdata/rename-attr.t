@@ -0,0 +1,62 @@
+=TITLE=Rename in multiple files
+=INPUT=
+-- a.t
+%TITLE=a
+%IN=x
+%OUT=y
+%TITLE=b
+%IN:RE=z
+-- b.t
+%TITLE=c
+%OUT=y
+=SUBST=/%/=/
+=ARGS=rename-attr IN INPUT a.t b.t
+=STDOUT=
+a.t: renamed 2 times
+=END=
+=OUTPUT=
+-- a.t
+%TITLE=a
+%INPUT=x
+%OUT=y
+%TITLE=b
+%INPUT:RE=z
+-- b.t
+%TITLE=c
+%OUT=y
+=SUBST=/%/=/
+
+=TITLE=Invalid new name
+=INPUT=
+%TITLE=a
+%IN=x
+=SUBST=/%/=/
+=ARGS=rename-attr IN I-N input.t
+=STDERR=
+input.t: invalid name of attribute: "I-N"
+=END=
+=EXIT=1
+=OUTPUT=
+-- input.t
+%TITLE=a
+%IN=x
+=SUBST=/%/=/
+
+=TITLE=Attribute exists
+=INPUT=
+%TITLE=a
+%IN=x
+%INPUT=y
+=SUBST=/%/=/
+=ARGS=rename-attr IN INPUT input.t
+=STDERR=
+input.t: test "a" already has =INPUT=
+=END=
+=EXIT=1
+
+=TITLE=Missing arguments
+=ARGS=rename-attr IN INPUT
+=STDERR=
+Usage: testtxt rename-attr old new file.t...
+=END=
+=EXIT=2
@@ -0,0 +1,92 @@
+=TITLE=Sort by title
+=INPUT=
+# Header
+
+%TEMPL=unused
+u
+%TEMPL=t
+x
+%TITLE=c
+%IN=<<t>>
+%TITLE=a
+%IN=1
+%TITLE=b
+%IN=<<t>>
+=SUBST=/%/=/
+=SUBST=/<</[[/
+=SUBST=/>>/]]/
+=ARGS=sort input.t
+=STDOUT=
+# Header
+
+%TEMPL=unused
+u
+%END=
+
+%TITLE=a
+%IN=1
+
+%TEMPL=t
+x
+%END=
+
+%TITLE=b
+%IN=<<t>>
+
+%TITLE=c
+%IN=<<t>>
+=SUBST=/%/=/
+=SUBST=/<</[[/
+=SUBST=/>>/]]/
+
+=TITLE=Sort by attribute, write file
+=INPUT=
+%TITLE=a
+%IN=2
+%TITLE=b
+%TITLE=c
+%IN=1
+=SUBST=/%/=/
+=ARGS=sort -by IN -w input.t
+=OUTPUT=
+-- input.t
+%TITLE=b
+
+%TITLE=c
+%IN=1
+
+%TITLE=a
+%IN=2
+=SUBST=/%/=/
+
+=TITLE=Redefined template
+=INPUT=
+%TEMPL=t
+x
+%TITLE=b
+%IN=<<t>>
+%TEMPL=t
+y
+%TITLE=a
+%IN=<<t>>
+=SUBST=/%/=/
+=SUBST=/<</[[/
+=SUBST=/>>/]]/
+=ARGS=sort -w input.t
+=STDERR=
+input.t: can't sort tests, template t is defined multiple times
+=END=
+=EXIT=1
+=OUTPUT=
+-- input.t
+%TEMPL=t
+x
+%TITLE=b
+%IN=<<t>>
+%TEMPL=t
+y
+%TITLE=a
+%IN=<<t>>
+=SUBST=/%/=/
+=SUBST=/<</[[/
+=SUBST=/>>/]]/
@@ -0,0 +1,46 @@
+=TITLE=Statistics
+=INPUT=
+-- a.t
+%TEMPL=t
+xxxxxxxxxx
+%TITLE=a
+%IN=
+<<t>>
+<<t>>
+%OUT=y
+%TITLE=b
+%IN=<<t>>
+-- b.t
+%TITLE=c
+%IN=z
+=SUBST=/%/=/
+=SUBST=/<</[[/
+=SUBST=/>>/]]/
+=ARGS=stats -top 2 a.t b.t
+=STDOUT=
+Files: 2
+Tests: 3
+
+Tests per file:
+       2  a.t
+       1  b.t
+
+Attributes:
+       3  IN
+       3  TITLE
+       1  OUT
+
+Template calls:
+       3  t
+
+Largest expanded attributes:
+       2 lines  a.t:4: =IN= of a
+       0 lines  a.t:3: =TITLE= of a
+=END=
+
+=TITLE=Missing file
+=ARGS=stats a.t
+=STDERR=
+open a.t: no such file or directory
+=END=
+=EXIT=1
@@ -0,0 +1,48 @@
+=TITLE=Report templates of files and template files
+=INPUT=
+-- common.tmpl
+%TEMPL=g
+x
+%TEMPL=unused
+y
+-- d/a.t
+%TEMPL=l
+<<g>>
+%TITLE=a
+%IN=<<l>>
+%OUT=<<g>>
+-- d/b.t
+%TITLE=b
+%IN=<<g>>
+%TEMPL=l
+z
+%TITLE=c
+%IN=<<l>>
+=SUBST=/%/=/
+=SUBST=/<</[[/
+=SUBST=/>>/]]/
+=ARGS=templates -templates common.tmpl d
+=STDOUT=
+common.tmpl:1: g: 3 calls in d/a.t, d/b.t
+common.tmpl:3: unused: 0 calls (unused)
+d/a.t:1: l: 1 call in d/a.t (single use)
+d/b.t:3: l: 1 call in d/b.t (single use)
+=END=
+
+=TITLE=Invalid file
+=INPUT=
+%TITLE=a
+%TEMPL=t
+x
+%IN=y
+=SUBST=/%/=/
+=ARGS=templates input.t
+=STDERR=
+input.t: =IN= at line 4 doesn't belong to a test
+=END=
+=EXIT=1
+
+=TITLE=Missing argument
+=ARGS=templates
+=STDERR:CONTAINS=Usage: testtxt templates
+=EXIT=2
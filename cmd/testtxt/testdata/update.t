@@ -0,0 +1,114 @@
+=TITLE=Update from results file
+=INPUT=
+-- a.t
+%TITLE=a
+%OUT=old
+%TITLE=b
+%OUT=
+x
+%END=
+-- b.t
+%TITLE=c
+%OUT=same
+-- r.json
+{"file": "a.t", "title": "a", "attr": "OUT", "text": "new"}
+{"file": "b.t", "title": "c", "attr": "OUT", "text": "same"}
+{"file": "a.t", "title": "b", "attr": "OUT", "text": "y\nz\n"}
+=SUBST=/%/=/
+=ARGS=update -results r.json
+=STDOUT=
+a.t: updated 2 attributes
+=END=
+=OUTPUT=
+#ignore r.json
+-- a.t
+%TITLE=a
+%OUT=new
+%TITLE=b
+%OUT=
+y
+z
+%END=
+-- b.t
+%TITLE=c
+%OUT=same
+=SUBST=/%/=/
+
+=TITLE=Dry run
+=INPUT=
+-- a.t
+%TITLE=a
+%OUT=old
+-- r.json
+{"file": "a.t", "title": "a", "attr": "OUT", "text": "new"}
+=SUBST=/%/=/
+=ARGS=update -n -results r.json
+=STDOUT=
+a.t: updated 1 attribute
+=END=
+=OUTPUT=
+#ignore r.json
+-- a.t
+%TITLE=a
+%OUT=old
+=SUBST=/%/=/
+
+=TITLE=Unknown test
+=INPUT=
+-- a.t
+%TITLE=a
+-- r.json
+{"file": "a.t", "title": "x", "attr": "OUT", "text": "new"}
+=SUBST=/%/=/
+=ARGS=update -results r.json
+=STDERR=
+a.t: missing test with =TITLE=x
+=END=
+=EXIT=1
+
+=TITLE=Invalid entry
+=INPUT=
+-- r.json
+{"title": "x", "attr": "OUT", "text": "new"}
+=ARGS=update -results r.json
+=STDERR=
+r.json: missing "file" or "attr" in entry
+=END=
+=EXIT=1
+
+=TITLE=Invalid JSON
+=INPUT=
+-- r.json
+{"file": "a.t"
+=ARGS=update -results r.json
+=STDERR=
+r.json: unexpected EOF
+=END=
+=EXIT=1
+
+=TITLE=Run command in update mode
+=INPUT=
+-- s.sh 0755
+#!/bin/sh
+echo "TESTTXT_UPDATE=$TESTTXT_UPDATE $*"
+=ARGS=update ./s.sh x y
+=STDOUT=
+TESTTXT_UPDATE=1 x y
+=END=
+
+=TITLE=Failing command
+=INPUT=
+-- s.sh 0755
+#!/bin/sh
+echo failed >&2
+exit 3
+=ARGS=update ./s.sh
+=STDERR=
+failed
+=END=
+=EXIT=1
+
+=TITLE=Dry run needs results file
+=ARGS=update -n ./s.sh
+=STDERR:CONTAINS=Usage: testtxt update
+=EXIT=2
@@ -0,0 +1,130 @@
+=TITLE=Valid file
+=INPUT=
+%TITLE=a
+%INPUT=x
+=SUBST=/%/=/
+=ARGS=validate input.t
+
+=TITLE=Errors in multiple files
+=INPUT=
+-- a.t
+%TITLE=a
+%INPUT=<<missing>>
+-- b.t
+%TITLE=b
+%TEMPL=x
+=SUBST=/%/=/
+=SUBST=/<</[[/
+=SUBST=/>>/]]/
+=ARGS=validate a.t b.t
+=STDOUT=
+a.t:2:8: error: calling unknown template missing in test with =TITLE=a
+b.t:2:8: warning: unused template x
+=END=
+=EXIT=1
+
+=TITLE=Strict
+=INPUT=
+%TITLE=a
+%TEMPL=x
+=SUBST=/%/=/
+=ARGS=validate -strict input.t
+=STDOUT=
+input.t:2:8: error: unused template x
+=END=
+=EXIT=1
+
+=TITLE=JSON
+=INPUT=
+%TITLE=a
+%TEMPL=x
+=SUBST=/%/=/
+=ARGS=validate -json input.t
+=STDOUT=
+{"file":"input.t","range":{"start":{"line":2,"column":8},"end":{"line":2,"column":8}},"severity":"warning","code":"unused-template","message":"unused template x"}
+=END=
+
+=TITLE=Schema
+=INPUT=
+-- s.go
+package p
+
+type T struct {
+	Title string
+	Input string
+}
+-- a.t
+%TITLE=a
+%OUTPUT=x
+=SUBST=/%/=/
+=ARGS=validate -schema s.go a.t
+=STDOUT=
+a.t:2:1: error: unexpected =OUTPUT= in test with =TITLE=a
+=END=
+=EXIT=1
+
+=TITLE=TAP
+=INPUT=
+-- a.t
+%TITLE=a
+-- b.t
+%TITLE=b
+%TEMPL=x
+-- c.t
+%TITLE=c
+%IN=<<missing>>
+=SUBST=/%/=/
+=SUBST=/<</[[/
+=SUBST=/>>/]]/
+=ARGS=validate -tap a.t b.t c.t
+=STDOUT=
+TAP version 13
+ok 1 - a.t
+ok 2 - b.t
+  ---
+  message: "b.t:2:8: warning: unused template x"
+  ...
+not ok 3 - c.t
+  ---
+  message: "c.t:2:5: error: calling unknown template missing in test with =TITLE=c"
+  ...
+1..3
+=END=
+=EXIT=1
+
+=TITLE=JUnit
+=INPUT=
+-- a.t
+%TITLE=a
+-- b.t
+%TITLE=b
+%IN=<<missing>>
+=SUBST=/%/=/
+=SUBST=/<</[[/
+=SUBST=/>>/]]/
+=ARGS=validate -junit a.t b.t
+=STDOUT=
+<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="a.t" tests="1" failures="0" skipped="0" time="0.000">
+    <testcase name="a.t" classname="a.t"></testcase>
+  </testsuite>
+  <testsuite name="b.t" tests="1" failures="1" skipped="0" time="0.000">
+    <testcase name="b.t:2: template" classname="b.t">
+      <failure message="calling unknown template missing" type="template"><![CDATA[b.t:2:5: error: calling unknown template missing in test with =TITLE=b]]></failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+=END=
+=EXIT=1
+
+=TITLE=Missing file argument
+=ARGS=validate
+=STDERR:CONTAINS=
+Usage: testtxt validate [-schema file.go [-type name]] [-strict] [-json|-tap|-junit] file.t...
+=EXIT=2
+
+=TITLE=Multiple output formats
+=ARGS=validate -json -tap input.t
+=STDERR:CONTAINS=Usage: testtxt validate
+=EXIT=2
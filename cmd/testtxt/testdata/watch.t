@@ -0,0 +1,16 @@
+=TITLE=Invalid interval
+=ARGS=watch -interval 0s d
+=STDERR:CONTAINS=Usage: testtxt watch
+=EXIT=2
+
+=TITLE=Missing argument
+=ARGS=watch
+=STDERR:CONTAINS=Usage: testtxt watch
+=EXIT=2
+
+=TITLE=Invalid schema
+=ARGS=watch -schema missing.go d
+=STDERR=
+open missing.go: no such file or directory
+=END=
+=EXIT=2
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/hknutzen/testtxt"
+)
+
+// updateEntry is a line of a results file of subcommand update.
+type updateEntry struct {
+	File  string `json:"file"`
+	Title string `json:"title"`
+	Attr  string `json:"attr"`
+	Text  string `json:"text"`
+}
+
+// update rewrites expected text in files of test descriptions. Either
+// a test command is run in update mode, i.e. with TESTTXT_UPDATE=1, or
+// new text of attributes is read from a results file with JSON lines
+// like {"file": "x.t", "title": "t1", "attr": "OUT", "text": "..."}.
+func update(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("update",
+		"[-results file | -results -] [-n] | command [arg...]", stderr)
+	results := fs.String("results", "",
+		"read new text of attributes from JSON lines in `file`")
+	dryRun := fs.Bool("n", false,
+		"only print changed files of -results, don't write them")
+	if fs.Parse(args) != nil {
+		return 2
+	}
+	switch {
+	case *results != "" && fs.NArg() == 0:
+		return updateFromResults(*results, *dryRun, stdout, stderr)
+	case *results == "" && fs.NArg() > 0 && !*dryRun:
+		return updateByCommand(fs.Args(), stdout, stderr)
+	}
+	fs.Usage()
+	return 2
+}
+
+// updateByCommand runs command args in update mode.
+func updateByCommand(args []string, stdout, stderr io.Writer) int {
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Env = append(os.Environ(), "TESTTXT_UPDATE=1")
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		if errors.As(err, new(*exec.ExitError)) {
+			return 1
+		}
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// updateFromResults applies entries of results file to files of test
+// descriptions. Each file is read and written only once.
+func updateFromResults(results string, dryRun bool, stdout, stderr io.Writer,
+) int {
+	var r io.Reader = os.Stdin
+	if results != "-" {
+		f, err := os.Open(results)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		defer f.Close()
+		r = f
+	}
+	var files []string
+	byFile := make(map[string][]updateEntry)
+	dec := json.NewDecoder(r)
+	for {
+		var e updateEntry
+		if err := dec.Decode(&e); err == io.EOF {
+			break
+		} else if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", results, err)
+			return 1
+		}
+		if e.File == "" || e.Attr == "" {
+			fmt.Fprintf(stderr, "%s: missing \"file\" or \"attr\" in entry\n",
+				results)
+			return 1
+		}
+		if _, found := byFile[e.File]; !found {
+			files = append(files, e.File)
+		}
+		byFile[e.File] = append(byFile[e.File], e)
+	}
+	exit := 0
+	for _, file := range files {
+		n, err := updateFile(file, byFile[file], dryRun)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", file, err)
+			exit = 1
+			continue
+		}
+		if n > 0 {
+			fmt.Fprintf(stdout, "%s: updated %d %s\n",
+				file, n, plural(n, "attribute"))
+		}
+	}
+	return exit
+}
+
+// updateFile sets attributes of entries l in file and returns the
+// number of changed attributes. File is only written if it changes.
+func updateFile(file string, l []updateEntry, dryRun bool) (int, error) {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+	r := testtxt.NewRewriter(src)
+	n := 0
+	for _, e := range l {
+		prev := string(r.Bytes())
+		if err := r.SetAttr(e.Title, e.Attr, e.Text); err != nil {
+			return 0, err
+		}
+		if string(r.Bytes()) != prev {
+			n++
+		}
+	}
+	if n == 0 || dryRun {
+		return n, nil
+	}
+	return n, os.WriteFile(file, r.Bytes(), 0644)
+}
+
+// plural returns word with suffix "s" if n isn't 1.
+func plural(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
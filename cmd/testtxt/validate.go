@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/hknutzen/testtxt"
+	"github.com/hknutzen/testtxt/internal/gostruct"
+)
+
+// validate checks files of test descriptions and prints diagnostics.
+// Without a schema, any attribute is accepted.
+func validate(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("validate",
+		"[-schema file.go [-type name]] [-strict] [-json|-tap|-junit] file.t...", stderr)
+	schema := fs.String("schema", "",
+		"Go source `file` with struct type of test descriptions")
+	typeName := fs.String("type", "", "`name` of struct type in schema file")
+	strict := fs.Bool("strict", false, "treat warnings as errors")
+	out := newDiagOutput(fs, stdout)
+	var templates stringList
+	fs.Var(&templates, "templates", "read templates from `file`")
+	if fs.Parse(args) != nil {
+		return 2
+	}
+	if fs.NArg() == 0 || !out.check() {
+		fs.Usage()
+		return 2
+	}
+	var typ reflect.Type
+	if *schema != "" {
+		t, err := gostruct.Load(*schema, *typeName)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		typ = t
+	}
+	var opts []testtxt.Option
+	if *strict {
+		opts = append(opts, testtxt.Strict())
+	}
+	opts = append(opts, templateOpts(templates)...)
+	failed := false
+	for _, file := range fs.Args() {
+		var target any
+		if typ != nil {
+			target = reflect.New(reflect.SliceOf(typ)).Interface()
+		}
+		l := testtxt.Lint(file, target, opts...)
+		for _, d := range l {
+			if d.Severity == testtxt.SeverityError {
+				failed = true
+			}
+		}
+		out.file(file, l)
+	}
+	if err := out.close(); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
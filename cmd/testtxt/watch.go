@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/hknutzen/testtxt"
+	"github.com/hknutzen/testtxt/internal/gostruct"
+)
+
+// watch checks files of test descriptions below given directories,
+// whenever they change. Changes are detected by polling modification
+// times. Optionally a test command is run after changed files have
+// been checked without errors. It runs until interrupted.
+func watch(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("watch",
+		"[-interval d] [-run command] [-schema file.go [-type name]] dir|file...",
+		stderr)
+	interval := flags.Duration("interval", 500*time.Millisecond,
+		"poll files every `duration`")
+	run := flags.String("run", "",
+		"run `command`, e.g. \"go test ./...\", after changes without errors")
+	ext := flags.String("ext", ".t", "read files with `extension` in directories")
+	schema := flags.String("schema", "",
+		"Go source `file` with struct type of test descriptions")
+	typeName := flags.String("type", "", "`name` of struct type in schema file")
+	var templates stringList
+	flags.Var(&templates, "templates", "read templates from `file`")
+	if flags.Parse(args) != nil {
+		return 2
+	}
+	if flags.NArg() == 0 || *interval <= 0 {
+		flags.Usage()
+		return 2
+	}
+	var typ reflect.Type
+	if *schema != "" {
+		t, err := gostruct.Load(*schema, *typeName)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		typ = t
+	}
+	opts := templateOpts(templates)
+	seen := make(map[string]time.Time)
+	for ; ; time.Sleep(*interval) {
+		files, err := findFiles(flags.Args(), *ext)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			continue
+		}
+		changed := watchChanged(append(files, templates...), seen)
+		if changed == nil {
+			continue
+		}
+		// All files depend on template files.
+		for _, t := range templates {
+			if changed[t] {
+				for _, f := range files {
+					changed[f] = true
+				}
+				break
+			}
+		}
+		failed := false
+		for _, file := range files {
+			if !changed[file] {
+				continue
+			}
+			var target any
+			if typ != nil {
+				target = reflect.New(reflect.SliceOf(typ)).Interface()
+			}
+			l := testtxt.Lint(file, target, opts...)
+			for _, d := range l {
+				if d.Severity == testtxt.SeverityError {
+					failed = true
+				}
+				fmt.Fprintln(stdout, d)
+			}
+			if l == nil {
+				fmt.Fprintf(stdout, "%s: ok\n", file)
+			}
+		}
+		if *run != "" && !failed {
+			runWatchCmd(*run, stdout, stderr)
+		}
+	}
+}
+
+// watchChanged returns the set of files, that are new or have been
+// modified since last call, and records their modification times in
+// seen. Removed files are removed from seen. It returns nil, if
+// nothing has changed.
+func watchChanged(files []string, seen map[string]time.Time,
+) map[string]bool {
+	var changed map[string]bool
+	add := func(file string) {
+		if changed == nil {
+			changed = make(map[string]bool)
+		}
+		changed[file] = true
+	}
+	present := make(map[string]bool)
+	for _, file := range files {
+		fi, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		present[file] = true
+		if t, found := seen[file]; !found || !t.Equal(fi.ModTime()) {
+			seen[file] = fi.ModTime()
+			add(file)
+		}
+	}
+	for file := range seen {
+		if !present[file] {
+			delete(seen, file)
+			add(file)
+		}
+	}
+	return changed
+}
+
+// runWatchCmd runs whitespace separated command line and reports its
+// result.
+func runWatchCmd(line string, stdout, stderr io.Writer) {
+	args := strings.Fields(line)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", line, err)
+		return
+	}
+	fmt.Fprintf(stdout, "%s: ok\n", line)
+}
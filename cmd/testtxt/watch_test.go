@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWatchChanged(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.t")
+	b := filepath.Join(dir, "b.t")
+	write := func(file string, mtime time.Time) {
+		t.Helper()
+		if err := os.WriteFile(file, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(file, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	write(a, t0)
+	write(b, t0)
+	seen := make(map[string]time.Time)
+	files := []string{a, b, filepath.Join(dir, "missing.t")}
+	check := func(want map[string]bool) {
+		t.Helper()
+		if d := cmp.Diff(want, watchChanged(files, seen)); d != "" {
+			t.Error(d)
+		}
+	}
+	check(map[string]bool{a: true, b: true})
+	check(nil)
+	write(b, t0.Add(time.Second))
+	check(map[string]bool{b: true})
+	if err := os.Remove(a); err != nil {
+		t.Fatal(err)
+	}
+	check(map[string]bool{a: true})
+	check(nil)
+}
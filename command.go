@@ -0,0 +1,115 @@
+package testtxt
+
+import (
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Cmd is the description of a test of a command line program, that is
+// run by RunCmd or RunExec.
+type Cmd struct {
+	Title string
+	// Args are whitespace separated command line arguments.
+	Args string
+	// Stdin is the standard input of the program.
+	Stdin string
+	// Input gives files in the format of input of PrepareInDir, that
+	// are created in a temporary directory before the program is run.
+	Input string
+	// Stdout and Stderr are the expected standard output and standard
+	// error. Missing attributes expect empty output.
+	Stdout Expected
+	Stderr Expected
+	// Exit is the expected exit code.
+	Exit int
+	// Env gives environment variables from attributes =ENV_*=.
+	Env map[string]string
+	// Output gives the expected files in the directory of Input after
+	// the program has run, in the format of expected of CompareDir.
+	// It isn't compared, if empty.
+	Output  string
+	Skip    string
+	Only    bool
+	Xfail   string
+	Flaky   int
+	Tags    string
+	Serial  bool
+	Timeout time.Duration
+}
+
+// CmdFunc is called by RunCmd like the main function of a program
+// with arguments, standard input and writers for standard output and
+// standard error. It returns the exit code.
+type CmdFunc func(args []string, stdin io.Reader, stdout, stderr io.Writer,
+) int
+
+// CompareOptions passes options to the comparison of output of RunCmd
+// and RunExec.
+func CompareOptions(opts ...CompareOption) RunOption {
+	return func(c *runConfig) {
+		c.compareOpts = append(c.compareOpts, opts...)
+	}
+}
+
+// RunCmd parses file into a slice of Cmd and runs fn for each test as
+// subtest of t like RunTests. Output and exit code of fn are compared
+// with attributes =STDOUT=, =STDERR=, =EXIT= and files of the input
+// directory with =OUTPUT=. Each difference is reported as error.
+// Fn finds the files of =INPUT= in the current directory, if option
+// Chdir is given.
+func RunCmd(t *testing.T, file string, fn CmdFunc, opts ...RunOption) {
+	t.Helper()
+	runCmds(t, file, opts, func(t *testing.T, d *Cmd, dir string) RunResult {
+		return CaptureFunc(func(stdout, stderr io.Writer) int {
+			return fn(strings.Fields(d.Args), strings.NewReader(d.Stdin),
+				stdout, stderr)
+		})
+	})
+}
+
+// RunExec parses file into a slice of Cmd and runs external program
+// for each test as subtest of t like RunCmd. The program is started
+// with the directory of =INPUT= as working directory. A relative path
+// of program is interpreted relative to the current directory.
+func RunExec(t *testing.T, file, program string, opts ...RunOption) {
+	t.Helper()
+	if strings.ContainsRune(program, filepath.Separator) {
+		abs, err := filepath.Abs(program)
+		if err != nil {
+			t.Fatal(err)
+		}
+		program = abs
+	}
+	runCmds(t, file, opts, func(t *testing.T, d *Cmd, dir string) RunResult {
+		cmd := exec.Command(program, strings.Fields(d.Args)...)
+		cmd.Dir = dir
+		cmd.Stdin = strings.NewReader(d.Stdin)
+		r, err := CaptureCmd(cmd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	})
+}
+
+// runCmds runs tests of file, where run executes the program of a
+// single test in directory dir.
+func runCmds(t *testing.T, file string, opts []RunOption,
+	run func(t *testing.T, d *Cmd, dir string) RunResult,
+) {
+	t.Helper()
+	c := newRunConfig(opts)
+	opts = append([]RunOption{PrepareInput()}, opts...)
+	RunTests(t, file, func(t *testing.T, d Cmd) {
+		dir := InputDir(t)
+		got := run(t, &d, dir)
+		CheckStreams(t, d, got, c.compareOpts...)
+		if d.Output != "" {
+			CompareDir(t, dir, d.Output, c.compareOpts...)
+		}
+	}, opts...)
+}
@@ -0,0 +1,192 @@
+package testtxt
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Equal compares expected and got after applying normalizers given by
+// option Normalize. A difference is reported as error of t with a
+// unified diff. It reports whether both texts are equal.
+func Equal(t testing.TB, expected, got string, opts ...CompareOption,
+) bool {
+	t.Helper()
+	c := newCompareConfig(opts)
+	d := c.diff("expected", "got", c.normalize(expected), c.normalize(got))
+	if d != "" {
+		reportDiff(t, d)
+		return false
+	}
+	return true
+}
+
+// CompareDir compares directory tree gotDir with files described by
+// expected in the format of input of PrepareInDir.
+// Each difference is reported as error of t: a unified diff for each
+// file with different content and each missing or unexpected file.
+// It reports whether both trees are equal.
+//
+// Content of files is normalized as given by option Normalize.
+// Leading lines of expected starting with "#ignore" give
+// whitespace separated patterns like option IgnoreFiles.
+func CompareDir(t testing.TB, gotDir, expected string,
+	opts ...CompareOption,
+) bool {
+	t.Helper()
+	diffs, err := compareDir(gotDir, expected, newCompareConfig(opts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range diffs {
+		reportDiff(t, d)
+	}
+	return diffs == nil
+}
+
+// compareDir implements CompareDir and returns a description of each
+// difference. Patterns of "#ignore" lines are added to c.
+func compareDir(gotDir, expected string, c *compareConfig,
+) ([]string, error) {
+	if expected == "NONE" {
+		expected = ""
+	}
+	header, expected := splitIgnoreHeader(expected)
+	for _, line := range strings.Split(header, "\n") {
+		c.ignore = append(c.ignore,
+			strings.Fields(strings.TrimPrefix(line, "#ignore"))...)
+	}
+	want, err := splitInput(expected, newPrepareConfig(nil))
+	if err != nil {
+		return nil, err
+	}
+	if want == nil && expected != "" {
+		return nil, fmt.Errorf(
+			"missing file marker in first line of expected files")
+	}
+	want = slices.DeleteFunc(want, func(e *fileEntry) bool {
+		return c.ignored(path.Clean(e.name), e.dir)
+	})
+	got, err := c.readEntries(gotDir)
+	if err != nil {
+		return nil, err
+	}
+	gotMap := make(map[string]*fileEntry)
+	for _, e := range got {
+		gotMap[e.name] = e
+	}
+	var diffs []string
+	errorf := func(format string, args ...any) {
+		diffs = append(diffs, fmt.Sprintf(format, args...))
+	}
+	for _, w := range want {
+		name := path.Clean(w.name)
+		g := gotMap[name]
+		delete(gotMap, name)
+		if w.dir {
+			fi, err := os.Stat(filepath.Join(gotDir, name))
+			if err != nil || !fi.IsDir() {
+				errorf("missing directory %s", name)
+			}
+			continue
+		}
+		if g == nil {
+			errorf("missing file %s", name)
+			continue
+		}
+		if w.link != "" {
+			if g.link != w.link {
+				errorf("%s: expected symbolic link to %s", name, w.link)
+			}
+			continue
+		}
+		if g.link != "" || g.dir {
+			errorf("%s: expected regular file", name)
+			continue
+		}
+		data := w.data
+		if w.from != "" {
+			b, err := os.ReadFile(w.from)
+			if err != nil {
+				return nil, err
+			}
+			data = string(b)
+		}
+		d := c.diff("expected/"+name, "got/"+name,
+			c.normalize(data), c.normalize(g.data))
+		if d != "" {
+			errorf("%s", d)
+		}
+		if w.modeSet && w.mode != g.mode {
+			errorf("%s: expected mode %04o, got %04o", name, w.mode, g.mode)
+		}
+	}
+	for _, g := range got {
+		if gotMap[g.name] == nil {
+			continue
+		}
+		if g.dir {
+			errorf("unexpected directory %s", g.name)
+		} else {
+			errorf("unexpected file %s", g.name)
+		}
+	}
+	return diffs, nil
+}
+
+// splitIgnoreHeader splits leading "#ignore" lines from text.
+func splitIgnoreHeader(text string) (header, rest string) {
+	rest = text
+	for {
+		after, found := strings.CutPrefix(rest, "#ignore")
+		if !found || after != "" && after[0] != ' ' && after[0] != '\n' {
+			break
+		}
+		_, after, _ = strings.Cut(after, "\n")
+		rest = after
+	}
+	return text[:len(text)-len(rest)], rest
+}
+
+// readEntries reads directory tree dir like function readEntries,
+// but leaves out ignored files.
+func (c *compareConfig) readEntries(dir string) ([]*fileEntry, error) {
+	l, err := readEntries(dir)
+	l = slices.DeleteFunc(l, func(e *fileEntry) bool {
+		return c.ignored(e.name, e.dir)
+	})
+	return l, err
+}
+
+// ignored reports whether file or directory name, given as cleaned
+// slash separated path, matches an ignore pattern of c.
+func (c *compareConfig) ignored(name string, dir bool) bool {
+	if len(c.ignore) == 0 {
+		return false
+	}
+	parts := strings.Split(name, "/")
+	for i, part := range parts {
+		isDir := dir || i+1 < len(parts)
+		sub := strings.Join(parts[:i+1], "/")
+		for _, p := range c.ignore {
+			p, dirOnly := strings.CutSuffix(p, "/")
+			if dirOnly && !isDir {
+				continue
+			}
+			var ok bool
+			if strings.Contains(p, "/") {
+				ok, _ = path.Match(strings.TrimPrefix(p, "/"), sub)
+			} else {
+				ok, _ = path.Match(p, part)
+			}
+			if ok {
+				return true
+			}
+		}
+	}
+	return false
+}
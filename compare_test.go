@@ -0,0 +1,161 @@
+package testtxt
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCompareDir(t *testing.T) {
+	got := "---- a\nx\n---- b 0755\ny\n---- d/ (dir)\n---- l -> a\n"
+	tests := []struct {
+		name     string
+		expected string
+		want     []string
+	}{
+		{
+			name:     "equal",
+			expected: got,
+		},
+		{
+			name:     "different content",
+			expected: "---- a\nX\n---- b 0755\ny\n---- d/ (dir)\n---- l -> a\n",
+			want: []string{
+				"--- expected/a\n+++ got/a\n@@ -1 +1 @@\n-X\n+x\n",
+			},
+		},
+		{
+			name:     "different mode",
+			expected: "---- a 0600\nx\n---- b\ny\n---- d/ (dir)\n---- l -> a\n",
+			want: []string{
+				"a: expected mode 0600, got 0644",
+			},
+		},
+		{
+			name:     "missing and unexpected",
+			expected: "---- a\nx\n---- c\n---- e (dir)\n---- l -> b\n",
+			want: []string{
+				"missing file c",
+				"missing directory e",
+				"l: expected symbolic link to b",
+				"unexpected file b",
+				"unexpected directory d",
+			},
+		},
+		{
+			name:     "wrong type",
+			expected: "---- a -> b\n---- b 0755\ny\n---- d/ (dir)\n---- l\n",
+			want: []string{
+				"a: expected symbolic link to b",
+				"l: expected regular file",
+			},
+		},
+		{
+			name:     "no files",
+			expected: "NONE",
+			want: []string{
+				"unexpected file a",
+				"unexpected file b",
+				"unexpected directory d",
+				"unexpected file l",
+			},
+		},
+	}
+	dir := t.TempDir()
+	if _, err := PrepareInDirE(dir, "input", got); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			diffs, err := compareDir(dir, tc.expected, newCompareConfig(nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := cmp.Diff(tc.want, diffs); d != "" {
+				t.Error(d)
+			}
+		})
+	}
+}
+
+func TestCompareDirError(t *testing.T) {
+	_, err := compareDir(t.TempDir(), "x\n", newCompareConfig(nil))
+	want := "missing file marker in first line of expected files"
+	if err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %q", err, want)
+	}
+}
+
+func TestIgnoreFiles(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		dir     bool
+		want    bool
+	}{
+		{"*.log", "a.log", false, true},
+		{"*.log", "sub/a.log", false, true},
+		{"*.log", "a.txt", false, false},
+		{"tmp", "tmp/x", false, true},
+		{"tmp", "a/tmp", true, true},
+		{"tmp/", "tmp", false, false},
+		{"tmp/", "tmp", true, true},
+		{"tmp/", "tmp/x", false, true},
+		{"a/*.o", "a/x.o", false, true},
+		{"a/*.o", "b/a/x.o", false, false},
+		{"/a/*.o", "a/x.o", false, true},
+		{"a/b", "a/b/c", false, true},
+	}
+	for _, tc := range tests {
+		c := newCompareConfig([]CompareOption{IgnoreFiles(tc.pattern)})
+		if got := c.ignored(tc.name, tc.dir); got != tc.want {
+			t.Errorf("pattern %q, name %q, dir %v: got %v",
+				tc.pattern, tc.name, tc.dir, got)
+		}
+	}
+}
+
+func TestCompareDirIgnore(t *testing.T) {
+	dir := t.TempDir()
+	input := "---- a\nx\n---- run.log\n1\n---- cache/x\n"
+	if _, err := PrepareInDirE(dir, "input", input); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		expected string
+		opts     []CompareOption
+		want     []string
+	}{
+		{
+			name:     "option",
+			expected: "---- a\nx\n---- b.log\n",
+			opts:     []CompareOption{IgnoreFiles("*.log", "cache/")},
+		},
+		{
+			name:     "header",
+			expected: "#ignore *.log\n#ignore cache/\n---- a\nx\n",
+		},
+		{
+			name:     "header and option",
+			expected: "#ignore *.log\n---- a\nx\n",
+			opts:     []CompareOption{IgnoreFiles("cache")},
+		},
+		{
+			name:     "not ignored",
+			expected: "#ignore *.txt\n---- a\nx\n---- cache/x\n",
+			want:     []string{"unexpected file run.log"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			diffs, err := compareDir(dir, tc.expected, newCompareConfig(tc.opts))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := cmp.Diff(tc.want, diffs); d != "" {
+				t.Error(d)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package testtxt
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// AttrUsage describes which attributes of a single test are read
+// by the code executing the test.
+type AttrUsage struct {
+	Title   string
+	Line    int
+	Defined []string // Attributes defined in test.
+	Unread  []string // Attributes whose struct field is never read.
+}
+
+// AttrCoverage parses file of test descriptions like ParseFile and
+// reports for each test, which of its attributes correspond to struct
+// fields, that are never read in given Go source files.
+// A field counts as read, if its name occurs as selector x.Field.
+// A directory in goFiles stands for all .go files in that directory.
+func AttrCoverage(file string, target any, goFiles ...string,
+) ([]AttrUsage, error) {
+	read, err := readFields(goFiles)
+	if err != nil {
+		return nil, err
+	}
+	var result []AttrUsage
+	fields := fieldsOfTarget(target)
+	hook := func(s *state, name string, pos int) {
+		if name == s.titleAttr {
+			line, _ := s.position(pos)
+			result = append(result, AttrUsage{Title: s.title, Line: line})
+		}
+		u := &result[len(result)-1]
+		u.Defined = append(u.Defined, name)
+		if f := fields.field(name); f != "" && !read[f] {
+			u.Unread = append(u.Unread, name)
+		}
+	}
+	err = ParseFile(file, target, func(c *config) { c.attrHook = hook })
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// targetFields maps attribute names to names of struct fields.
+type targetFields struct {
+	exact map[string]string
+	// Map fields in order of struct, given as prefix of attributes
+	// and name of field.
+	maps [][2]string
+}
+
+// fieldsOfTarget returns names of struct fields of pointer to slice of
+// struct.
+func fieldsOfTarget(target any) *targetFields {
+	tf := &targetFields{exact: make(map[string]string)}
+	t := reflect.TypeOf(target).Elem().Elem()
+	mapType := reflect.TypeOf(map[string]string(nil))
+	for _, f := range reflect.VisibleFields(t) {
+		tf.exact[toSnakeCase(f.Name)] = f.Name
+		if f.Type == mapType {
+			tf.maps = append(tf.maps, [2]string{toSnakeCase(f.Name) + "_", f.Name})
+		}
+	}
+	return tf
+}
+
+// field returns name of struct field, that stores attribute name,
+// or "" if there is none. Like in ParseFile, a field of the same
+// name has precedence over a map field collecting attributes with
+// its name as prefix.
+func (tf *targetFields) field(name string) string {
+	if f, found := tf.exact[name]; found {
+		return f
+	}
+	for _, m := range tf.maps {
+		if key, found := strings.CutPrefix(name, m[0]); found && key != "" {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// readFields returns names of all selectors used in given Go files.
+func readFields(files []string) (map[string]bool, error) {
+	var expanded []string
+	for _, f := range files {
+		if fi, err := os.Stat(f); err == nil && fi.IsDir() {
+			l, err := filepath.Glob(filepath.Join(f, "*.go"))
+			if err != nil {
+				return nil, err
+			}
+			expanded = append(expanded, l...)
+		} else {
+			expanded = append(expanded, f)
+		}
+	}
+	read := make(map[string]bool)
+	fset := token.NewFileSet()
+	for _, f := range expanded {
+		af, err := parser.ParseFile(fset, f, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		ast.Inspect(af, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				read[sel.Sel.Name] = true
+			}
+			return true
+		})
+	}
+	return read, nil
+}
@@ -0,0 +1,67 @@
+package testtxt
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAttrCoverage(t *testing.T) {
+	src := "=TITLE=a\n=INPUT=x\n=OUTPUT=y\n=ENV_HOME=/tmp\n\n" +
+		"=TITLE=b\n=COUNT=1\n"
+	tests := []struct {
+		name string
+		code string
+		want []AttrUsage
+	}{
+		{
+			name: "nothing read",
+			code: "package x\n",
+			want: []AttrUsage{
+				{
+					Title:   "a",
+					Line:    1,
+					Defined: []string{"TITLE", "INPUT", "OUTPUT", "ENV_HOME"},
+					Unread:  []string{"TITLE", "INPUT", "OUTPUT", "ENV_HOME"},
+				},
+				{
+					Title:   "b",
+					Line:    6,
+					Defined: []string{"TITLE", "COUNT"},
+					Unread:  []string{"TITLE", "COUNT"},
+				},
+			},
+		},
+		{
+			name: "fields read",
+			code: "package x\n\nfunc f(d D) { g(d.Title, d.Input, d.Env) }\n",
+			want: []AttrUsage{
+				{
+					Title:   "a",
+					Line:    1,
+					Defined: []string{"TITLE", "INPUT", "OUTPUT", "ENV_HOME"},
+					Unread:  []string{"OUTPUT"},
+				},
+				{
+					Title:   "b",
+					Line:    6,
+					Defined: []string{"TITLE", "COUNT"},
+					Unread:  []string{"COUNT"},
+				},
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			file := writeTemp(t, "x.t", src)
+			code := writeTemp(t, "x.go", tc.code)
+			got, err := AttrCoverage(file, &[]parseDescr{}, code)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := cmp.Diff(tc.want, got); d != "" {
+				t.Error(d)
+			}
+		})
+	}
+}
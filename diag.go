@@ -0,0 +1,166 @@
+package testtxt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Severity of a Diagnostic.
+type Severity int
+
+const (
+	SeverityError Severity = iota + 1
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// Diagnostic describes a problem found in a file of test descriptions.
+type Diagnostic struct {
+	File   string
+	Line   int // 1-based, 0 if unknown
+	Column int // 1-based byte offset in line, 0 if unknown
+	// End of range, given as position after last byte.
+	// Is 0 if range is unknown.
+	EndLine   int
+	EndColumn int
+	TestTitle string
+	Severity  Severity
+	// Short identifier for the kind of problem, e.g. "unused-template".
+	Code    string
+	Message string
+	// Name of title attribute, empty if not inside test.
+	titleAttr string
+}
+
+func (d Diagnostic) String() string {
+	var b strings.Builder
+	if d.File != "" {
+		b.WriteString(d.File)
+		if d.Line > 0 {
+			fmt.Fprintf(&b, ":%d", d.Line)
+			if d.Column > 0 {
+				fmt.Fprintf(&b, ":%d", d.Column)
+			}
+		}
+		b.WriteString(": ")
+	}
+	fmt.Fprintf(&b, "%s: %s", d.Severity, d.Message)
+	if d.titleAttr != "" {
+		fmt.Fprintf(&b, " in test with =%s=%s", d.titleAttr, d.TestTitle)
+	}
+	return b.String()
+}
+
+type jsonPosition struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type jsonRange struct {
+	Start jsonPosition `json:"start"`
+	End   jsonPosition `json:"end"`
+}
+
+type jsonDiagnostic struct {
+	File     string     `json:"file"`
+	Range    *jsonRange `json:"range,omitempty"`
+	Severity string     `json:"severity"`
+	Code     string     `json:"code,omitempty"`
+	Message  string     `json:"message"`
+	Test     string     `json:"test,omitempty"`
+}
+
+// MarshalJSON encodes d as JSON object with attributes
+// "file", "range", "severity", "code", "message" and "test".
+// Attribute "range" has "start" and "end", each with "line" and
+// "column". If the end is unknown, it is set to start.
+func (d Diagnostic) MarshalJSON() ([]byte, error) {
+	j := jsonDiagnostic{
+		File:     d.File,
+		Severity: d.Severity.String(),
+		Code:     d.Code,
+		Message:  d.Message,
+		Test:     d.TestTitle,
+	}
+	if d.Line > 0 {
+		start := jsonPosition{d.Line, d.Column}
+		end := start
+		if d.EndLine > 0 {
+			end = jsonPosition{d.EndLine, d.EndColumn}
+		}
+		j.Range = &jsonRange{start, end}
+	}
+	return json.Marshal(j)
+}
+
+// SortDiagnostics sorts l by file, line and column.
+// Sorting is stable.
+func SortDiagnostics(l []Diagnostic) {
+	slices.SortStableFunc(l, func(a, b Diagnostic) int {
+		if a.File != b.File {
+			return strings.Compare(a.File, b.File)
+		}
+		if a.Line != b.Line {
+			return a.Line - b.Line
+		}
+		return a.Column - b.Column
+	})
+}
+
+// Diagnostics converts an error returned by ParseFile to a list of
+// diagnostics with severity error.
+// Errors combined by option CollectErrors are returned individually.
+func Diagnostics(err error) []Diagnostic {
+	var result []Diagnostic
+	for _, err := range unjoin(err) {
+		var pe *ParseError
+		if errors.As(err, &pe) {
+			result = append(result, pe.Diagnostic())
+		} else {
+			result = append(result, Diagnostic{
+				Severity: SeverityError,
+				Message:  err.Error(),
+			})
+		}
+	}
+	return result
+}
+
+// warnf sends a warning for given byte offset of current file
+// to the warning handler.
+// In strict mode, the warning is recorded as error instead.
+func (s *state) warnf(code string, off int, format string, args ...any) {
+	if s.strict {
+		s.errs = append(s.errs, s.errorf(KindWarning, off, format, args...))
+		return
+	}
+	if s.warnHandler == nil {
+		return
+	}
+	line, col := s.position(off)
+	d := Diagnostic{
+		File:     s.filename,
+		Line:     line,
+		Column:   col,
+		Severity: SeverityWarning,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+	}
+	if s.inTest {
+		d.TestTitle = s.title
+		d.titleAttr = s.titleAttr
+	}
+	s.warnHandler(d)
+}
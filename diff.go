@@ -0,0 +1,316 @@
+package testtxt
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// diffOp is a single line of a diff.
+type diffOp struct {
+	kind byte // ' ': unchanged, '-': only in a, '+': only in b
+	line string
+}
+
+// splitLines splits text into lines, each with its trailing newline.
+// Last line has no trailing newline, if text doesn't end with newline.
+func splitLines(text string) []string {
+	l := strings.SplitAfter(text, "\n")
+	if l[len(l)-1] == "" {
+		l = l[:len(l)-1]
+	}
+	return l
+}
+
+// diffLines computes a shortest edit script from a to b
+// using the linear space variant of the algorithm of Myers.
+// In each group of changed lines, lines only in a come first.
+func diffLines(a, b []string) []diffOp {
+	var ops []diffOp
+	diffRec(a, b, &ops)
+	// Move lines of a in front of lines of b in each group of changes.
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		j := i
+		for j < len(ops) && ops[j].kind != ' ' {
+			j++
+		}
+		grp := ops[i:j]
+		var del, ins []diffOp
+		for _, op := range grp {
+			if op.kind == '-' {
+				del = append(del, op)
+			} else {
+				ins = append(ins, op)
+			}
+		}
+		copy(grp, del)
+		copy(grp[len(del):], ins)
+		i = j
+	}
+	return ops
+}
+
+// diffRec appends a shortest edit script from a to b to ops.
+// It splits the problem at the middle snake of an optimal path.
+func diffRec(a, b []string, ops *[]diffOp) {
+	// Strip common prefix and suffix.
+	p := 0
+	for p < len(a) && p < len(b) && a[p] == b[p] {
+		*ops = append(*ops, diffOp{' ', a[p]})
+		p++
+	}
+	a, b = a[p:], b[p:]
+	s := 0
+	for s < len(a) && s < len(b) && a[len(a)-1-s] == b[len(b)-1-s] {
+		s++
+	}
+	suffix := a[len(a)-s:]
+	a, b = a[:len(a)-s], b[:len(b)-s]
+	switch {
+	case len(a) == 0:
+		for _, l := range b {
+			*ops = append(*ops, diffOp{'+', l})
+		}
+	case len(b) == 0:
+		for _, l := range a {
+			*ops = append(*ops, diffOp{'-', l})
+		}
+	default:
+		// Both parts have at least one difference less than a and b,
+		// because a and b differ in first and in last line.
+		x, y, u, v := middleSnake(a, b)
+		diffRec(a[:x], b[:y], ops)
+		for _, l := range a[x:u] {
+			*ops = append(*ops, diffOp{' ', l})
+		}
+		diffRec(a[u:], b[v:], ops)
+	}
+	for _, l := range suffix {
+		*ops = append(*ops, diffOp{' ', l})
+	}
+}
+
+// middleSnake returns start x, y and end u, v of the middle snake of
+// a shortest edit path from a to b. Paths are searched simultaneously
+// from start and from end, until they overlap.
+func middleSnake(a, b []string) (x, y, u, v int) {
+	n, m := len(a), len(b)
+	delta := n - m
+	odd := delta%2 != 0
+	maxD := (n + m + 1) / 2
+	off := maxD + 1
+	// Furthest reaching x on diagonal k, counted from start in vf and
+	// from end in vb.
+	vf := make([]int, 2*off+1)
+	vb := make([]int, 2*off+1)
+	for d := 0; d <= maxD; d++ {
+		for k := -d; k <= d; k += 2 {
+			x := vf[off+k-1] + 1
+			if k == -d || k != d && vf[off+k-1] < vf[off+k+1] {
+				x = vf[off+k+1]
+			}
+			y := x - k
+			x0, y0 := x, y
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			vf[off+k] = x
+			if odd && k >= delta-(d-1) && k <= delta+(d-1) &&
+				x+vb[off+delta-k] >= n {
+				return x0, y0, x, y
+			}
+		}
+		for k := -d; k <= d; k += 2 {
+			x := vb[off+k-1] + 1
+			if k == -d || k != d && vb[off+k-1] < vb[off+k+1] {
+				x = vb[off+k+1]
+			}
+			y := x - k
+			x0, y0 := x, y
+			for x < n && y < m && a[n-1-x] == b[m-1-y] {
+				x++
+				y++
+			}
+			vb[off+k] = x
+			if !odd && delta-k >= -d && delta-k <= d &&
+				x+vf[off+delta-k] >= n {
+				return n - x, m - y, n - x0, m - y0
+			}
+		}
+	}
+	panic("no middle snake found")
+}
+
+// unifiedDiff returns differences between a and b in unified format
+// with given number of context lines.
+// It returns the empty string, if a and b are equal.
+func unifiedDiff(nameA, nameB, a, b string, context int) string {
+	if a == b {
+		return ""
+	}
+	ops := diffLines(splitLines(a), splitLines(b))
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", nameA, nameB)
+	// Line numbers in a and b at start of each op.
+	lineA := make([]int, len(ops)+1)
+	lineB := make([]int, len(ops)+1)
+	for i, op := range ops {
+		lineA[i+1], lineB[i+1] = lineA[i], lineB[i]
+		if op.kind != '+' {
+			lineA[i+1]++
+		}
+		if op.kind != '-' {
+			lineB[i+1]++
+		}
+	}
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		// Find end of hunk, merging changes separated by
+		// at most 2*context unchanged lines.
+		start := max(0, i-context)
+		end := i
+		for j := i; j < len(ops); j++ {
+			if ops[j].kind != ' ' {
+				end = j + 1
+			} else if j-end >= 2*context {
+				break
+			}
+		}
+		end = min(len(ops), end+context)
+		countA := lineA[end] - lineA[start]
+		countB := lineB[end] - lineB[start]
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n",
+			hunkRange(lineA[start], countA), hunkRange(lineB[start], countB))
+		for _, op := range ops[start:end] {
+			out.WriteByte(op.kind)
+			out.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = end
+	}
+	return out.String()
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// diff returns differences between a and b in the format selected by
+// options of c. It returns the empty string, if a and b are equal.
+func (c *compareConfig) diff(nameA, nameB, a, b string) string {
+	var d string
+	if c.unordered {
+		d = unorderedDiff(nameA, nameB, a, b)
+	} else {
+		d = unifiedDiff(nameA, nameB, a, b, c.context)
+	}
+	if d == "" {
+		return ""
+	}
+	return c.render(d)
+}
+
+// render truncates and colors diff d as given by options of c.
+func (c *compareConfig) render(d string) string {
+	lines := splitLines(d)
+	if c.maxLines > 0 && len(lines) > c.maxLines {
+		n := len(lines) - c.maxLines
+		lines = append(lines[:c.maxLines], fmt.Sprintf(
+			"... %d more lines not shown, see option MaxDiffLines\n", n))
+	}
+	if c.color && useColor() {
+		for i, l := range lines {
+			lines[i] = colorLine(l)
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+// useColor reports whether standard output is a terminal, that should
+// show colors.
+func useColor() bool {
+	if _, found := os.LookupEnv("NO_COLOR"); found {
+		return false
+	}
+	fi, err := os.Stdout.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// colorLine marks line of unified diff with ANSI colors.
+func colorLine(l string) string {
+	var code string
+	switch {
+	case strings.HasPrefix(l, "---"), strings.HasPrefix(l, "+++"):
+		code = "1"
+	case strings.HasPrefix(l, "@@"):
+		code = "36"
+	case strings.HasPrefix(l, "-"):
+		code = "31"
+	case strings.HasPrefix(l, "+"):
+		code = "32"
+	default:
+		return l
+	}
+	text, found := strings.CutSuffix(l, "\n")
+	l = "\x1b[" + code + "m" + text + "\x1b[0m"
+	if found {
+		l += "\n"
+	}
+	return l
+}
+
+// unorderedDiff compares a and b as multisets of lines.
+// It returns lines missing in b, marked by "-", followed by
+// unexpected lines of b, marked by "+", each in original order.
+func unorderedDiff(nameA, nameB, a, b string) string {
+	la, lb := splitLines(a), splitLines(b)
+	count := make(map[string]int)
+	for _, l := range la {
+		count[strings.TrimSuffix(l, "\n")]++
+	}
+	var plus []string
+	for _, l := range lb {
+		l = strings.TrimSuffix(l, "\n")
+		if count[l] > 0 {
+			count[l]--
+		} else {
+			plus = append(plus, l)
+		}
+	}
+	var minus []string
+	for _, l := range la {
+		l = strings.TrimSuffix(l, "\n")
+		if count[l] > 0 {
+			count[l]--
+			minus = append(minus, l)
+		}
+	}
+	if minus == nil && plus == nil {
+		return ""
+	}
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", nameA, nameB)
+	for _, l := range minus {
+		out.WriteString("-" + l + "\n")
+	}
+	for _, l := range plus {
+		out.WriteString("+" + l + "\n")
+	}
+	return out.String()
+}
@@ -0,0 +1,234 @@
+package testtxt
+
+import (
+	"math/rand"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		context int
+		want    string
+	}{
+		{
+			name: "equal",
+			a:    "x\ny\n",
+			b:    "x\ny\n",
+			want: "",
+		},
+		{
+			name:    "changed line",
+			a:       "a\nb\nc\n",
+			b:       "a\nB\nc\n",
+			context: 3,
+			want: "--- a\n+++ b\n@@ -1,3 +1,3 @@\n" +
+				" a\n-b\n+B\n c\n",
+		},
+		{
+			name:    "deleted lines come first",
+			a:       "1\n2\n3\n",
+			b:       "4\n5\n",
+			context: 3,
+			want: "--- a\n+++ b\n@@ -1,3 +1,2 @@\n" +
+				"-1\n-2\n-3\n+4\n+5\n",
+		},
+		{
+			name:    "empty a",
+			a:       "",
+			b:       "x\n",
+			context: 3,
+			want:    "--- a\n+++ b\n@@ -0,0 +1 @@\n+x\n",
+		},
+		{
+			name:    "missing newline at end",
+			a:       "x\ny",
+			b:       "x\ny\n",
+			context: 3,
+			want: "--- a\n+++ b\n@@ -1,2 +1,2 @@\n" +
+				" x\n-y\n\\ No newline at end of file\n+y\n",
+		},
+		{
+			name:    "separate hunks",
+			a:       "1\n2\n3\n4\n5\n6\n7\n8\n",
+			b:       "X\n2\n3\n4\n5\n6\n7\nY\n",
+			context: 1,
+			want: "--- a\n+++ b\n" +
+				"@@ -1,2 +1,2 @@\n-1\n+X\n 2\n" +
+				"@@ -7,2 +7,2 @@\n 7\n-8\n+Y\n",
+		},
+		{
+			name:    "merged hunks",
+			a:       "1\n2\n3\n4\n5\n",
+			b:       "X\n2\n3\n4\nY\n",
+			context: 2,
+			want: "--- a\n+++ b\n@@ -1,5 +1,5 @@\n" +
+				"-1\n+X\n 2\n 3\n 4\n-5\n+Y\n",
+		},
+		{
+			name:    "no context",
+			a:       "1\n2\n3\n",
+			b:       "1\nX\n3\n",
+			context: 0,
+			want:    "--- a\n+++ b\n@@ -2 +2 @@\n-2\n+X\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := unifiedDiff("a", "b", tc.a, tc.b, tc.context)
+			if d := cmp.Diff(tc.want, got); d != "" {
+				t.Error(d)
+			}
+		})
+	}
+}
+
+// TestDiffLinesRandom checks, that the edit script of diffLines
+// transforms a into b.
+func TestDiffLinesRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	gen := func() []string {
+		l := make([]string, r.Intn(40))
+		for i := range l {
+			l[i] = string(rune('a'+r.Intn(4))) + "\n"
+		}
+		return l
+	}
+	for i := 0; i < 1000; i++ {
+		a, b := gen(), gen()
+		var gotA, gotB []string
+		for _, op := range diffLines(a, b) {
+			if op.kind != '+' {
+				gotA = append(gotA, op.line)
+			}
+			if op.kind != '-' {
+				gotB = append(gotB, op.line)
+			}
+		}
+		if strings.Join(gotA, "") != strings.Join(a, "") ||
+			strings.Join(gotB, "") != strings.Join(b, "") {
+			t.Fatalf("invalid edit script for\n%q\n%q", a, b)
+		}
+	}
+}
+
+// TestDiffLinesLarge checks, that memory isn't quadratic in the size
+// of the edit script.
+func TestDiffLinesLarge(t *testing.T) {
+	var a, b []string
+	for i := 0; i < 4000; i++ {
+		a = append(a, "a\n")
+		b = append(b, "b\n")
+	}
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	diffLines(a, b)
+	runtime.ReadMemStats(&after)
+	if n := after.TotalAlloc - before.TotalAlloc; n > 16<<20 {
+		t.Errorf("allocated %d MB", n>>20)
+	}
+}
+
+func TestUnorderedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want string
+	}{
+		{"equal", "1\n2\n3\n", "3\n1\n2\n", ""},
+		{"missing final newline", "1\n2", "2\n1\n", ""},
+		{
+			name: "duplicates",
+			a:    "x\nx\ny\n",
+			b:    "y\nx\n",
+			want: "--- a\n+++ b\n-x\n",
+		},
+		{
+			name: "in original order",
+			a:    "1\n2\n3\n4\n",
+			b:    "4\nB\n2\nA\n",
+			want: "--- a\n+++ b\n-1\n-3\n+B\n+A\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := newCompareConfig([]CompareOption{UnorderedLines()}).
+				diff("a", "b", tc.a, tc.b)
+			if d := cmp.Diff(tc.want, got); d != "" {
+				t.Error(d)
+			}
+		})
+	}
+}
+
+func TestRenderDiff(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n"
+	b := "1\n2\n3\n4\nX\n6\n7\n8\n9\n"
+	tests := []struct {
+		name string
+		opts []CompareOption
+		want string
+	}{
+		{
+			name: "default context",
+			want: "--- a\n+++ b\n@@ -2,7 +2,7 @@\n" +
+				" 2\n 3\n 4\n-5\n+X\n 6\n 7\n 8\n",
+		},
+		{
+			name: "context",
+			opts: []CompareOption{DiffContext(1)},
+			want: "--- a\n+++ b\n@@ -4,3 +4,3 @@\n 4\n-5\n+X\n 6\n",
+		},
+		{
+			name: "truncated",
+			opts: []CompareOption{DiffContext(1), MaxDiffLines(4)},
+			want: "--- a\n+++ b\n@@ -4,3 +4,3 @@\n 4\n" +
+				"... 3 more lines not shown, see option MaxDiffLines\n",
+		},
+		{
+			name: "not truncated at limit",
+			opts: []CompareOption{DiffContext(1), MaxDiffLines(7)},
+			want: "--- a\n+++ b\n@@ -4,3 +4,3 @@\n 4\n-5\n+X\n 6\n",
+		},
+		{
+			name: "unordered",
+			opts: []CompareOption{UnorderedLines()},
+			want: "--- a\n+++ b\n-5\n+X\n",
+		},
+		{
+			name: "no color if output isn't terminal",
+			opts: []CompareOption{DiffContext(0), ColorDiff()},
+			want: "--- a\n+++ b\n@@ -5 +5 @@\n-5\n+X\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := newCompareConfig(tc.opts).diff("a", "b", a, b)
+			if d := cmp.Diff(tc.want, got); d != "" {
+				t.Error(d)
+			}
+		})
+	}
+}
+
+func TestColorLine(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"--- a\n", "\x1b[1m--- a\x1b[0m\n"},
+		{"+++ b\n", "\x1b[1m+++ b\x1b[0m\n"},
+		{"@@ -1 +1 @@\n", "\x1b[36m@@ -1 +1 @@\x1b[0m\n"},
+		{"-x\n", "\x1b[31m-x\x1b[0m\n"},
+		{"+x", "\x1b[32m+x\x1b[0m"},
+		{" x\n", " x\n"},
+	}
+	for _, tc := range tests {
+		if got := colorLine(tc.in); got != tc.want {
+			t.Errorf("colorLine(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
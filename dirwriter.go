@@ -0,0 +1,252 @@
+package testtxt
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DirWriter is an io.WriteCloser, that writes files to a directory
+// while receiving input in the format of input of PrepareInDir.
+// Other than PrepareInDirE, it never holds the complete input or a
+// complete file in memory. Use it for very large generated fixtures.
+// Input must start with a file marker.
+// Files are written as soon as their content arrives, hence the
+// directory may be filled partially if an error is found later.
+// Symbolic links are created by Close, after their targets have been
+// checked.
+// Close must be called after all input has been written.
+type DirWriter struct {
+	dir       string
+	c         *prepareConfig
+	seen      map[string]*fileEntry
+	line      int  // number of current line
+	lineStart bool // next byte starts a new line
+	buffered  bool // current line may be a marker and is buffered
+	partial   []byte
+	entry     *fileEntry
+	written   []*fileEntry
+	f         *os.File
+	out       *bufio.Writer
+	dec       *base64Writer
+	err       error
+}
+
+// NewDirWriter returns a DirWriter, that writes files into dir.
+func NewDirWriter(dir string, opts ...PrepareOption) *DirWriter {
+	return &DirWriter{
+		dir:       dir,
+		c:         newPrepareConfig(opts),
+		seen:      make(map[string]*fileEntry),
+		lineStart: true,
+	}
+}
+
+// Write processes p as next part of input.
+// Lines starting with a dash are buffered until they are complete,
+// all other content is written immediately.
+func (d *DirWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 && d.err == nil {
+		if d.lineStart {
+			d.line++
+			d.buffered = p[0] == '-'
+		}
+		chunk := p
+		i := bytes.IndexByte(p, '\n')
+		if i != -1 {
+			chunk = p[:i+1]
+		}
+		p = p[len(chunk):]
+		d.lineStart = i != -1
+		if !d.buffered {
+			d.err = d.content(chunk)
+			continue
+		}
+		d.partial = append(d.partial, chunk...)
+		if d.lineStart {
+			d.err = d.fullLine(d.partial)
+			d.partial = d.partial[:0]
+		}
+	}
+	if d.err != nil {
+		return 0, d.err
+	}
+	return n, nil
+}
+
+// Close processes a remaining incomplete last line, closes the last
+// written file, creates symbolic links and sets modification times of
+// all written files and modes of directories.
+// Write permissions of read-only files and directories aren't
+// restored later, the caller must take care of removing them.
+func (d *DirWriter) Close() error {
+	if d.err == nil && len(d.partial) != 0 {
+		d.err = d.content(d.partial)
+		d.partial = nil
+	}
+	if err := d.finish(); d.err == nil {
+		d.err = err
+	}
+	if d.err == nil {
+		d.err = d.writeLinks()
+	}
+	if d.err == nil {
+		d.err = finishEntries(d.dir, d.written, d.c)
+	}
+	return d.err
+}
+
+// writeLinks creates all symbolic links of input. They are created
+// last, because their targets can only be checked with all other
+// links known.
+func (d *DirWriter) writeLinks() error {
+	if err := checkLinks(d.written, d.c); err != nil {
+		return err
+	}
+	for _, e := range d.written {
+		if e.link != "" {
+			if err := writeEntry(d.dir, e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// fullLine handles a complete line, that may be a marker.
+func (d *DirWriter) fullLine(line []byte) error {
+	m := markerRe.FindSubmatchIndex(line)
+	if m != nil && m[0] == 0 && m[1] == len(line) {
+		if e := parseMarker(string(line[m[2]:m[3]])); e != nil {
+			e.line = d.line
+			return d.start(e)
+		}
+	}
+	return d.content(line)
+}
+
+// start closes the previous file and creates the file described by e.
+func (d *DirWriter) start(e *fileEntry) error {
+	if err := d.finish(); err != nil {
+		return err
+	}
+	if err := checkEntry(e, d.c, d.seen); err != nil {
+		return err
+	}
+	if e.archive != "" {
+		return fmt.Errorf("archive %s at line %d isn't supported by DirWriter",
+			e.name, e.line)
+	}
+	d.entry = e
+	d.written = append(d.written, e)
+	if e.link != "" {
+		return nil
+	}
+	if e.dir || e.from != "" {
+		return writeEntry(d.dir, e)
+	}
+	file := filepath.Join(d.dir, filepath.FromSlash(e.name))
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return fmt.Errorf("can't create directory for '%s': %v", file, err)
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	d.f = f
+	d.out = bufio.NewWriter(f)
+	if e.base64 {
+		d.dec = &base64Writer{w: d.out}
+	}
+	return nil
+}
+
+// content handles part of a line, that isn't a marker.
+func (d *DirWriter) content(b []byte) error {
+	e := d.entry
+	switch {
+	case e == nil:
+		return fmt.Errorf("missing file marker in first line")
+	case d.dec != nil:
+		if _, err := d.dec.Write(b); err != nil {
+			return fmt.Errorf("invalid base64 content for %s at line %d: %v",
+				e.name, e.line, err)
+		}
+		return nil
+	case d.out != nil:
+		_, err := d.out.Write(b)
+		return err
+	case strings.TrimSpace(string(b)) != "":
+		return fmt.Errorf("unexpected content for %s at line %d",
+			e.name, e.line)
+	}
+	return nil
+}
+
+// finish flushes and closes the current file.
+func (d *DirWriter) finish() error {
+	if d.f == nil {
+		return nil
+	}
+	f, e, out, dec := d.f, d.entry, d.out, d.dec
+	d.f, d.out, d.dec = nil, nil, nil
+	if dec != nil {
+		if err := dec.Close(); err != nil {
+			f.Close()
+			return fmt.Errorf("invalid base64 content for %s at line %d: %v",
+				e.name, e.line, err)
+		}
+	}
+	if err := out.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return closeEntry(f, e)
+}
+
+// base64Writer decodes base64 text written to it, ignoring white
+// space, and writes the decoded bytes to w.
+type base64Writer struct {
+	w    *bufio.Writer
+	buf  []byte
+	done bool // padding was seen
+}
+
+func (b *base64Writer) Write(p []byte) (int, error) {
+	for _, c := range p {
+		switch c {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		if b.done {
+			return 0, fmt.Errorf("data after padding")
+		}
+		b.buf = append(b.buf, c)
+	}
+	n := len(b.buf) / 4 * 4
+	if n == 0 {
+		return len(p), nil
+	}
+	dst := make([]byte, base64.StdEncoding.DecodedLen(n))
+	k, err := base64.StdEncoding.Decode(dst, b.buf[:n])
+	if err != nil {
+		return 0, err
+	}
+	b.done = b.buf[n-1] == '='
+	b.buf = b.buf[:copy(b.buf, b.buf[n:])]
+	_, err = b.w.Write(dst[:k])
+	return len(p), err
+}
+
+// Close reports incomplete input.
+func (b *base64Writer) Close() error {
+	if len(b.buf) != 0 {
+		return fmt.Errorf("incomplete input")
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package testtxt
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// snapshotDir describes each file, directory and symbolic link below
+// dir by type and mode, and additionally by modification time and
+// content for regular files.
+func snapshotDir(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	m := make(map[string]string)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || p == dir {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, p)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		desc := info.Mode().String()
+		switch {
+		case info.Mode()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			desc += " -> " + target
+		case info.Mode().IsRegular():
+			data, err := os.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			desc += fmt.Sprintf(" %s %q",
+				info.ModTime().UTC().Format(time.RFC3339), data)
+		}
+		m[filepath.ToSlash(rel)] = desc
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestDirWriterLikePrepareInDirE(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		err   bool
+	}{
+		{
+			name:  "files",
+			input: "--- a\nx\n--- sub/b\ny\nz\n",
+		},
+		{
+			name:  "mode, directory and link",
+			input: "--- run.sh 0755\n#!/bin/sh\n---- empty (dir)\n---- l -> a\n",
+		},
+		{
+			name:  "base64 and time",
+			input: "--- bin (base64) @2020-01-02\nAAEC\n/w==\n--- t @2021-03-04T05:06:07Z\nt\n",
+		},
+		{
+			name:  "dashes in content",
+			input: "--- a\n-\n--\n- x\n",
+		},
+		{
+			name:  "last file without newline",
+			input: "--- a\nx\n--- b\ny",
+		},
+		{
+			name:  "empty file",
+			input: "--- a\n--- b\n",
+		},
+		{
+			name:  "duplicate file",
+			input: "--- a\n--- ./a\n",
+			err:   true,
+		},
+		{
+			name:  "unsafe path",
+			input: "--- ../a\n",
+			err:   true,
+		},
+		{
+			name:  "invalid base64",
+			input: "--- a (base64)\n!!\n",
+			err:   true,
+		},
+	}
+	mtime := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir1 := t.TempDir()
+			_, err := PrepareInDirE(dir1, "input", tc.input, WithModTime(mtime))
+			if (err != nil) != tc.err {
+				t.Fatalf("PrepareInDirE: got error %v", err)
+			}
+			want := snapshotDir(t, dir1)
+			if !tc.err && len(want) == 0 {
+				t.Fatal("no files written")
+			}
+			// Write input in chunks of different sizes, such that
+			// markers are split.
+			for _, size := range []int{1, 3, len(tc.input)} {
+				dir2 := t.TempDir()
+				w := NewDirWriter(dir2, WithModTime(mtime))
+				var err error
+				for s := tc.input; s != "" && err == nil; {
+					n := min(size, len(s))
+					_, err = w.Write([]byte(s[:n]))
+					s = s[n:]
+				}
+				if cerr := w.Close(); err == nil {
+					err = cerr
+				}
+				if (err != nil) != tc.err {
+					t.Fatalf("DirWriter with size %d: got error %v", size, err)
+				}
+				if tc.err {
+					continue
+				}
+				if d := cmp.Diff(want, snapshotDir(t, dir2)); d != "" {
+					t.Errorf("size %d:\n%s", size, d)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package testtxt
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// StdinFile writes input to a temporary file and returns it opened
+// for reading, e.g. to be used as os.Stdin or as Stdin of exec.Cmd.
+// The file is closed when the test ends.
+// Use strings.NewReader if an io.Reader is sufficient.
+func StdinFile(t testing.TB, input string) *os.File {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "stdin")
+	if err := os.WriteFile(file, []byte(input), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+// Setenv sets environment variables from env by t.Setenv in order of
+// their names. Previous values are restored when the test ends.
+// Map env typically is a struct field, that collects attributes
+// =ENV_*= of a test description.
+// Like t.Setenv it can't be used in parallel tests.
+func Setenv(t testing.TB, env map[string]string) {
+	t.Helper()
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		t.Setenv(name, env[name])
+	}
+}
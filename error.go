@@ -0,0 +1,95 @@
+package testtxt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ErrorKind classifies a ParseError.
+type ErrorKind int
+
+const (
+	// KindTarget reports an argument of ParseFile,
+	// that isn't a pointer to an empty slice of struct.
+	KindTarget ErrorKind = iota + 1
+	// KindSyntax reports text, that doesn't follow the file format.
+	KindSyntax
+	// KindAttribute reports a missing, unexpected or duplicate attribute.
+	KindAttribute
+	// KindValue reports a value, that can't be stored in a struct field.
+	KindValue
+	// KindTemplate reports an invalid definition or call of a template.
+	KindTemplate
+	// KindSubst reports an invalid =SUBST=.
+	KindSubst
+	// KindEncoding reports invalid UTF-8.
+	KindEncoding
+	// KindWarning reports a warning, that was turned into an error
+	// by option Strict.
+	KindWarning
+)
+
+var kindNames = map[ErrorKind]string{
+	KindTarget:    "target",
+	KindSyntax:    "syntax",
+	KindAttribute: "attribute",
+	KindValue:     "value",
+	KindTemplate:  "template",
+	KindSubst:     "subst",
+	KindEncoding:  "encoding",
+	KindWarning:   "warning",
+}
+
+func (k ErrorKind) String() string {
+	if n, found := kindNames[k]; found {
+		return n
+	}
+	return fmt.Sprintf("ErrorKind(%d)", int(k))
+}
+
+// ParseError describes an error found while parsing a file
+// of test descriptions.
+// Use errors.As to access its fields.
+type ParseError struct {
+	File      string
+	Line      int // 1-based, 0 if unknown
+	Column    int // 1-based byte offset in line, 0 if unknown
+	TestTitle string
+	Kind      ErrorKind
+	Msg       string
+	// Name of title attribute, empty if error doesn't occur inside test.
+	titleAttr string
+}
+
+func (e *ParseError) Error() string {
+	var b strings.Builder
+	if e.File != "" {
+		b.WriteString(e.File)
+		if e.Line > 0 {
+			fmt.Fprintf(&b, ":%d", e.Line)
+			if e.Column > 0 {
+				fmt.Fprintf(&b, ":%d", e.Column)
+			}
+		}
+		b.WriteString(": ")
+	}
+	b.WriteString(e.Msg)
+	if e.titleAttr != "" {
+		fmt.Fprintf(&b, " in test with =%s=%s", e.titleAttr, e.TestTitle)
+	}
+	return b.String()
+}
+
+// Diagnostic converts e to a Diagnostic with severity error.
+func (e *ParseError) Diagnostic() Diagnostic {
+	return Diagnostic{
+		File:      e.File,
+		Line:      e.Line,
+		Column:    e.Column,
+		TestTitle: e.TestTitle,
+		Severity:  SeverityError,
+		Code:      e.Kind.String(),
+		Message:   e.Msg,
+		titleAttr: e.titleAttr,
+	}
+}
@@ -0,0 +1,159 @@
+package testtxt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// fmtBlock is a definition together with its text and preceding
+// comments, as found by Format.
+type fmtBlock struct {
+	// Comments and empty lines in front of definition, trimmed.
+	lead  []string
+	name  string
+	value string
+	// Lines of multi line text including line endings.
+	text  []string
+	multi bool
+	end   bool
+}
+
+// Format returns src, a file of test descriptions, in canonical
+// format:
+//   - Text of single line attributes directly follows =NAME=.
+//   - Each test and each template is preceded by a single empty line,
+//     other empty lines outside of text are removed.
+//   - Multi line text of the last attribute of a test ends with =END=,
+//     unless the text has no final newline at end of file.
+//     =END= is removed, where the next attribute follows directly.
+//   - Trailing white space is removed outside of multi line text.
+//   - Added and changed lines get the line ending of the first line.
+//
+// The parsed value of each attribute remains unchanged, in particular
+// multi line text is never changed.
+func Format(src []byte) ([]byte, error) {
+	s := &state{src: src, rest: src}
+	title := s.firstAttr()
+	blocks, trailer, err := s.fmtBlocks()
+	if err != nil {
+		return nil, err
+	}
+	// Added lines get line ending of first line.
+	nl := "\n"
+	if line, _, _ := strings.Cut(string(src), "\n"); strings.HasSuffix(line, "\r") {
+		nl = "\r\n"
+	}
+	var b strings.Builder
+	for i, bl := range blocks {
+		start := bl.name == title || bl.name == "TEMPL"
+		if i > 0 && start {
+			b.WriteString(nl)
+		}
+		for _, line := range compactLead(bl.lead, start) {
+			b.WriteString(line + nl)
+		}
+		b.WriteString("=" + bl.name + "=")
+		if !bl.multi || bl.name == "TEMPL" {
+			b.WriteString(bl.value)
+		}
+		b.WriteString(nl)
+		if !bl.multi {
+			continue
+		}
+		for _, line := range bl.text {
+			b.WriteString(line)
+		}
+		end := bl.end
+		var next *fmtBlock
+		if i+1 < len(blocks) {
+			next = blocks[i+1]
+		}
+		switch {
+		case next == nil || next.name == title || next.name == "TEMPL":
+			end = true
+		case next.name == "SUBST":
+		case len(next.lead) == 0:
+			end = false
+		}
+		// Text without final newline at end of file can't be terminated
+		// by =END= without changing its value.
+		if n := len(bl.text); n > 0 && !strings.HasSuffix(bl.text[n-1], "\n") {
+			end = false
+		}
+		if end {
+			b.WriteString("=END=" + nl)
+		}
+	}
+	if trailer = compactLead(trailer, true); trailer != nil {
+		if blocks != nil {
+			b.WriteString(nl)
+		}
+		for _, line := range trailer {
+			b.WriteString(line + nl)
+		}
+	}
+	return []byte(b.String()), nil
+}
+
+// fmtBlocks splits s.src into blocks. Comments after the last
+// definition are returned separately.
+func (s *state) fmtBlocks() ([]*fmtBlock, []string, error) {
+	lines := strings.SplitAfter(string(s.src), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	var blocks []*fmtBlock
+	var lead []string
+	for i := 0; i < len(lines); {
+		trimmed := strings.TrimSpace(lines[i])
+		i++
+		if trimmed == "" || trimmed[0] == '#' {
+			lead = append(lead, trimmed)
+			continue
+		}
+		name := s.checkDef(trimmed)
+		if name == "" || name == "END" {
+			return nil, nil, fmt.Errorf("line %d: unexpected %s", i, trimmed)
+		}
+		bl := &fmtBlock{lead: lead, name: name}
+		lead = nil
+		bl.value = strings.TrimSpace(trimmed[len(name)+2:])
+		blocks = append(blocks, bl)
+		if bl.value != "" && name != "TEMPL" {
+			continue
+		}
+		bl.multi = true
+		for i < len(lines) {
+			line := lines[i]
+			if n := s.checkDef(line); n != "" {
+				if n == "END" {
+					if rest := strings.TrimSpace(line[len("=END="):]); rest != "" {
+						return nil, nil, fmt.Errorf(
+							"line %d: unexpected text after =END=: %s", i+1, rest)
+					}
+					bl.end = true
+					i++
+				}
+				break
+			}
+			bl.text = append(bl.text, line)
+			i++
+		}
+	}
+	return blocks, lead, nil
+}
+
+// compactLead removes empty lines from lead. If keepEmpty is set,
+// only leading and trailing empty lines are removed and sequences of
+// empty lines are reduced to a single one.
+func compactLead(lead []string, keepEmpty bool) []string {
+	var result []string
+	for i, line := range lead {
+		if line == "" && (!keepEmpty || result == nil ||
+			i+1 == len(lead) || lead[i+1] == "") {
+			continue
+		}
+		result = append(result, line)
+	}
+	return result
+}
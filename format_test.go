@@ -0,0 +1,92 @@
+package testtxt
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "canonical",
+			src:  "=TITLE=a\n=IN=x\n\n=TITLE=b\n=IN=\ny\n=END=\n",
+			want: "=TITLE=a\n=IN=x\n\n=TITLE=b\n=IN=\ny\n=END=\n",
+		},
+		{
+			name: "empty lines and trailing blanks",
+			src:  "\n\n=TITLE=a  \n\n=IN=x \n=TITLE=b\n\n\n",
+			want: "=TITLE=a\n=IN=x\n\n=TITLE=b\n",
+		},
+		{
+			name: "END added and removed",
+			src:  "=TITLE=a\n=IN=\nx\n=END=\n=OUT=\ny\n=TITLE=b\n",
+			want: "=TITLE=a\n=IN=\nx\n=OUT=\ny\n=END=\n\n=TITLE=b\n",
+		},
+		{
+			name: "END kept before comment",
+			src:  "=TITLE=a\n=IN=\nx\n=END=\n# c\n=OUT=y\n",
+			want: "=TITLE=a\n=IN=\nx\n=END=\n# c\n=OUT=y\n",
+		},
+		{
+			name: "templates and substitutions",
+			src: "=TEMPL=t\nx {{.}}\n=END=\n=TITLE=a\n=IN=\n[[t 1]]\n" +
+				"=SUBST=/x/y/\n=OUT=z\n",
+			want: "=TEMPL=t\nx {{.}}\n=END=\n\n=TITLE=a\n=IN=\n[[t 1]]\n" +
+				"=SUBST=/x/y/\n=OUT=z\n",
+		},
+		{
+			name: "multi line text at end of file",
+			src:  "=TITLE=a\n=IN=\nx\ny",
+			want: "=TITLE=a\n=IN=\nx\ny",
+		},
+		{
+			name: "CRLF",
+			src:  "=TITLE=a \r\n\r\n=IN=\r\nx\r\n=TITLE=b\r\n=IN=\r\ny\r\n",
+			want: "=TITLE=a\r\n=IN=\r\nx\r\n=END=\r\n\r\n" +
+				"=TITLE=b\r\n=IN=\r\ny\r\n=END=\r\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Format([]byte(tc.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := cmp.Diff(tc.want, string(got)); d != "" {
+				t.Error(d)
+			}
+			again, err := Format(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := cmp.Diff(string(got), string(again)); d != "" {
+				t.Errorf("not idempotent:\n%s", d)
+			}
+			// Values must be unchanged.
+			values := func(src string) [][]Attr {
+				l, err := ParseTests(writeTemp(t, "x.t", src))
+				if err != nil {
+					t.Fatal(err)
+				}
+				var result [][]Attr
+				for _, test := range l {
+					var attrs []Attr
+					for _, a := range test.Attrs {
+						a.Line = 0
+						attrs = append(attrs, a)
+					}
+					result = append(result, attrs)
+				}
+				return result
+			}
+			if d := cmp.Diff(values(tc.src), values(string(got))); d != "" {
+				t.Errorf("values changed:\n%s", d)
+			}
+		})
+	}
+}
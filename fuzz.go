@@ -0,0 +1,59 @@
+package testtxt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// AddSeeds adds the text of attributes attrs of each test in file as
+// seed to f. Each seed consists of one string per attribute in given
+// order; a missing attribute gives the empty string. Hence the fuzz
+// target must take len(attrs) string parameters.
+func AddSeeds(f *testing.F, file string, attrs ...string) {
+	f.Helper()
+	l, err := ParseTests(file)
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, test := range l {
+		args := make([]any, len(attrs))
+		for i, a := range attrs {
+			args[i], _ = test.Get(a)
+		}
+		f.Add(args...)
+	}
+}
+
+// WriteCorpus writes the text of attributes attrs of each test in file
+// as seed corpus file to directory dir, typically
+// "testdata/fuzz/FuzzName", in the format read by "go test".
+// Seeds are built like in AddSeeds. Files are named by number and
+// title of test.
+func WriteCorpus(file, dir string, attrs ...string) error {
+	l, err := ParseTests(file)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for i, test := range l {
+		var b strings.Builder
+		b.WriteString("go test fuzz v1\n")
+		for _, a := range attrs {
+			text, _ := test.Get(a)
+			fmt.Fprintf(&b, "string(%s)\n", strconv.Quote(text))
+		}
+		name := fmt.Sprintf("%03d-%s", i+1,
+			unsafeChars.ReplaceAllString(test.Title, "_"))
+		err := os.WriteFile(filepath.Join(dir, name), []byte(b.String()), 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package testtxt
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const fuzzSrc = "=TEMPL=t\nx\n=END=\n" +
+	"=TITLE=a b\n=INPUT=[[t]]\n=OUTPUT=1\n\n" +
+	"=TITLE=c\n=INPUT=\n=OUTPUT:RE=\\d\n\n" +
+	"=TITLE=d\n"
+
+func FuzzAddSeeds(f *testing.F) {
+	file := filepath.Join(f.TempDir(), "x.t")
+	if err := os.WriteFile(file, []byte(fuzzSrc), 0644); err != nil {
+		f.Fatal(err)
+	}
+	AddSeeds(f, file, "OUTPUT", "INPUT")
+	var got []string
+	f.Cleanup(func() {
+		sort.Strings(got)
+		want := []string{"1|x", "\\d|", "|"}
+		if d := cmp.Diff(want, got); d != "" {
+			f.Error(d)
+		}
+	})
+	f.Fuzz(func(t *testing.T, out, in string) {
+		got = append(got, out+"|"+in)
+	})
+}
+
+func TestWriteCorpus(t *testing.T) {
+	file := writeTemp(t, "x.t", fuzzSrc)
+	dir := filepath.Join(t.TempDir(), "testdata", "fuzz", "FuzzX")
+	if err := WriteCorpus(file, dir, "INPUT", "OUTPUT"); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"001-a_b": "go test fuzz v1\nstring(\"x\")\nstring(\"1\")\n",
+		"002-c":   "go test fuzz v1\nstring(\"\")\nstring(\"\\\\d\")\n",
+		"003-d":   "go test fuzz v1\nstring(\"\")\nstring(\"\")\n",
+	}
+	got := make(map[string]string)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[e.Name()] = string(data)
+	}
+	if d := cmp.Diff(want, got); d != "" {
+		t.Error(d)
+	}
+	if err := WriteCorpus(writeTemp(t, "y.t", "=A=[[missing]]\n"), dir); err == nil {
+		t.Error("expected error for invalid file")
+	}
+}
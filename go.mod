@@ -2,4 +2,9 @@ module github.com/hknutzen/testtxt
 
 go 1.21.4
 
-require gopkg.in/yaml.v3 v3.0.1
+require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/google/go-cmp v0.7.0
+	golang.org/x/tools v0.24.0
+	gopkg.in/yaml.v3 v3.0.1
+)
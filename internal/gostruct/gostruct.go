@@ -0,0 +1,132 @@
+// Package gostruct reads struct types of test descriptions from Go
+// source files.
+package gostruct
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/hknutzen/testtxt"
+)
+
+// Load reads the struct type with given name from Go source file and
+// returns an equivalent type, that is used as target of ParseFile.
+// If name is empty, file must define a single struct type.
+// Only exported fields are taken; their types must be string, int,
+// bool, time.Duration, map[string]string or testtxt.Expected.
+// Struct tags are kept.
+func Load(file, name string) (reflect.Type, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, file, nil, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	var found *ast.StructType
+	var count int
+	ast.Inspect(f, func(n ast.Node) bool {
+		ts, ok := n.(*ast.TypeSpec)
+		if !ok {
+			return true
+		}
+		st, ok := ts.Type.(*ast.StructType)
+		if !ok {
+			return false
+		}
+		if name == "" || ts.Name.Name == name {
+			found = st
+			count++
+		}
+		return false
+	})
+	switch {
+	case count == 0 && name == "":
+		return nil, fmt.Errorf("%s: missing struct type", file)
+	case count == 0:
+		return nil, fmt.Errorf("%s: missing struct type %s", file, name)
+	case count > 1:
+		return nil, fmt.Errorf(
+			"%s: multiple struct types, select one with -type", file)
+	}
+	var fields []reflect.StructField
+	for _, fd := range found.Fields.List {
+		if len(fd.Names) == 0 {
+			return nil, fmt.Errorf("%s: embedded field isn't supported",
+				fset.Position(fd.Pos()))
+		}
+		typ, err := fieldType(fd.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", fset.Position(fd.Type.Pos()), err)
+		}
+		var tag reflect.StructTag
+		if fd.Tag != nil {
+			t, err := strconv.Unquote(fd.Tag.Value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", fset.Position(fd.Tag.Pos()), err)
+			}
+			tag = reflect.StructTag(t)
+		}
+		for _, n := range fd.Names {
+			if n.IsExported() {
+				fields = append(fields,
+					reflect.StructField{Name: n.Name, Type: typ, Tag: tag})
+			}
+		}
+	}
+	if fields == nil {
+		return nil, fmt.Errorf("%s: struct type without exported fields", file)
+	}
+	return reflect.StructOf(fields), nil
+}
+
+// fieldType returns the type given by expression e.
+func fieldType(e ast.Expr) (reflect.Type, error) {
+	switch x := e.(type) {
+	case *ast.Ident:
+		switch x.Name {
+		case "string":
+			return reflect.TypeOf(""), nil
+		case "int":
+			return reflect.TypeOf(0), nil
+		case "bool":
+			return reflect.TypeOf(false), nil
+		case "Expected":
+			return reflect.TypeOf(testtxt.Expected{}), nil
+		}
+	case *ast.SelectorExpr:
+		switch x.Sel.Name {
+		case "Duration":
+			return reflect.TypeOf(time.Duration(0)), nil
+		case "Expected":
+			return reflect.TypeOf(testtxt.Expected{}), nil
+		}
+	case *ast.MapType:
+		k, ok1 := x.Key.(*ast.Ident)
+		v, ok2 := x.Value.(*ast.Ident)
+		if ok1 && ok2 && k.Name == "string" && v.Name == "string" {
+			return reflect.TypeOf(map[string]string{}), nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported type %s", typeString(e))
+}
+
+// typeString returns the source text of simple type expression e.
+func typeString(e ast.Expr) string {
+	switch x := e.(type) {
+	case *ast.Ident:
+		return x.Name
+	case *ast.SelectorExpr:
+		return typeString(x.X) + "." + x.Sel.Name
+	case *ast.StarExpr:
+		return "*" + typeString(x.X)
+	case *ast.ArrayType:
+		return "[]" + typeString(x.Elt)
+	case *ast.MapType:
+		return "map[" + typeString(x.Key) + "]" + typeString(x.Value)
+	}
+	return fmt.Sprintf("%T", e)
+}
@@ -0,0 +1,155 @@
+package testtxt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// CompareJSON compares JSON document got with expected JSON text.
+// Both are compared structurally: order of keys and formatting are
+// ignored. Integers are compared exactly, other numbers as float64,
+// such that e.g. 1, 1.0 and 1e0 are equal.
+// Values at paths given by option IgnorePaths are left out.
+// A difference is reported as error of t with a unified diff of both
+// documents in canonical format.
+// It reports whether both documents are equal.
+func CompareJSON(t testing.TB, expected string, got []byte,
+	opts ...CompareOption,
+) bool {
+	t.Helper()
+	c := newCompareConfig(opts)
+	want, err := decodeJSON([]byte(expected))
+	if err != nil {
+		t.Fatalf("invalid expected JSON: %v", err)
+	}
+	have, err := decodeJSON(got)
+	if err != nil {
+		t.Errorf("invalid JSON: %v\n%s", err, got)
+		return false
+	}
+	return c.compareDocs(t, []any{want}, []any{have}, canonicalJSON)
+}
+
+// decodeJSON decodes a single JSON document. Numbers are decoded as
+// json.Number in canonical format, without losing precision of large
+// integers.
+func decodeJSON(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after JSON value")
+	}
+	return canonicalNumbers(v), nil
+}
+
+// canonicalNumbers replaces each json.Number in v by its canonical
+// format. Integers are kept exactly, other numbers are formatted as
+// float64. A float with integral value is given as integer, if it is
+// represented exactly.
+func canonicalNumbers(v any) any {
+	switch x := v.(type) {
+	case json.Number:
+		if i, ok := new(big.Int).SetString(string(x), 10); ok {
+			return json.Number(i.String())
+		}
+		f, err := x.Float64()
+		if err != nil {
+			return x
+		}
+		if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+			return json.Number(strconv.FormatInt(int64(f), 10))
+		}
+		return json.Number(strconv.FormatFloat(f, 'g', -1, 64))
+	case map[string]any:
+		for k, elem := range x {
+			x[k] = canonicalNumbers(elem)
+		}
+	case []any:
+		for i, elem := range x {
+			x[i] = canonicalNumbers(elem)
+		}
+	}
+	return v
+}
+
+// compareDocs compares lists of decoded documents structurally.
+// Differences are shown in format of render.
+func (c *compareConfig) compareDocs(t testing.TB, want, have []any,
+	render func(any) string,
+) bool {
+	t.Helper()
+	text := func(docs []any) string {
+		var b strings.Builder
+		for i, v := range docs {
+			for _, p := range c.ignorePaths {
+				parts := strings.Split(strings.TrimPrefix(p, "/"), "/")
+				v = removePath(v, parts)
+			}
+			if i > 0 {
+				b.WriteString("---\n")
+			}
+			b.WriteString(render(v))
+		}
+		return b.String()
+	}
+	d := c.diff("expected", "got", text(want), text(have))
+	if d != "" {
+		reportDiff(t, d)
+		return false
+	}
+	return true
+}
+
+// removePath removes values at path from decoded document v.
+// Array elements are replaced by nil, to keep indexes stable.
+func removePath(v any, path []string) any {
+	if len(path) == 0 {
+		return v
+	}
+	key, rest := path[0], path[1:]
+	switch x := v.(type) {
+	case map[string]any:
+		for k, elem := range x {
+			if key != "*" && key != k {
+				continue
+			}
+			if len(rest) == 0 {
+				delete(x, k)
+			} else {
+				x[k] = removePath(elem, rest)
+			}
+		}
+	case []any:
+		for i, elem := range x {
+			if key != "*" && key != strconv.Itoa(i) {
+				continue
+			}
+			if len(rest) == 0 {
+				x[i] = nil
+			} else {
+				x[i] = removePath(elem, rest)
+			}
+		}
+	}
+	return v
+}
+
+// canonicalJSON returns v as indented JSON with sorted keys.
+func canonicalJSON(v any) string {
+	b, err := json.MarshalIndent(v, "", " ")
+	if err != nil {
+		return err.Error()
+	}
+	return string(b) + "\n"
+}
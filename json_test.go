@@ -0,0 +1,74 @@
+package testtxt
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCompareJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []CompareOption
+		expected string
+		got      string
+		msgs     []string
+	}{
+		{
+			name:     "order of keys and formatting",
+			expected: `{"a": 1, "b": [true, null]}`,
+			got:      `{"b":[true,null],"a":1}`,
+		},
+		{
+			name:     "equal numbers",
+			expected: `[1, 1.0, 1e0, 0.5, -2]`,
+			got:      `[1e0, 1, 1.00, 5e-1, -2.0]`,
+		},
+		{
+			name:     "large integers are equal",
+			expected: `{"n": 9007199254740993}`,
+			got:      `{"n": 9007199254740993}`,
+		},
+		{
+			name:     "large integers differ above 2^53",
+			expected: `{"n": 9007199254740993}`,
+			got:      `{"n": 9007199254740992}`,
+			msgs: []string{"error: --- expected\n+++ got\n" +
+				"@@ -1,3 +1,3 @@\n {\n- \"n\": 9007199254740993\n" +
+				"+ \"n\": 9007199254740992\n }\n"},
+		},
+		{
+			name:     "ignore paths",
+			opts:     []CompareOption{IgnorePaths("/id", "/l/*/t", "/l/0")},
+			expected: `{"id": 1, "l": [{"t": 1, "v": 2}, {"t": 1, "v": 3}]}`,
+			got:      `{"id": 2, "l": [{"t": 2}, {"t": 2, "v": 3}]}`,
+		},
+		{
+			name:     "invalid JSON",
+			expected: `1`,
+			got:      `1 2`,
+			msgs: []string{
+				"error: invalid JSON: unexpected data after JSON value\n1 2"},
+		},
+		{
+			name:     "invalid expected JSON",
+			expected: `{`,
+			got:      `{}`,
+			msgs:     []string{"fatal: invalid expected JSON: unexpected EOF"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var ok bool
+			r := record(t, func(r *recorder) {
+				ok = CompareJSON(r, tc.expected, []byte(tc.got), tc.opts...)
+			})
+			if ok != (tc.msgs == nil) {
+				t.Errorf("got result %v", ok)
+			}
+			if d := cmp.Diff(tc.msgs, r.msgs); d != "" {
+				t.Error(d)
+			}
+		})
+	}
+}
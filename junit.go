@@ -0,0 +1,162 @@
+package testtxt
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JUnitWriter collects results of tests and diagnostics of files and
+// writes them in JUnit XML format, as read by many CI systems.
+// Output is written by Close.
+//
+// Results are grouped into a test suite for each top level test, with
+// a test case for each subtest. Method Result can be used as hook of
+// option ResultHook, like TAPWriter.Result.
+//
+// Diagnostics are grouped into a test suite for each file, with a
+// test case for each diagnostic. Errors are reported as failures.
+// Warnings are reported as passed test cases with the message as
+// output. A file without diagnostics gives a single passed test case.
+type JUnitWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	suites []*junitSuite
+}
+
+type junitSuites struct {
+	XMLName xml.Name      `xml:"testsuites"`
+	Suites  []*junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Time     string       `xml:"time,attr"`
+	Cases    []*junitCase `xml:"testcase"`
+	duration time.Duration
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr,omitempty"`
+	Failure   *junitFailure `xml:"failure"`
+	Skipped   *struct{}     `xml:"skipped"`
+	SystemOut *junitText    `xml:"system-out"`
+}
+
+type junitText struct {
+	Text string `xml:",cdata"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",cdata"`
+}
+
+// NewJUnitWriter returns a JUnitWriter, that writes to w.
+func NewJUnitWriter(w io.Writer) *JUnitWriter {
+	return &JUnitWriter{w: w}
+}
+
+// suite returns the test suite with given name, which is added if
+// needed.
+func (w *JUnitWriter) suite(name string) *junitSuite {
+	for _, s := range w.suites {
+		if s.Name == name {
+			return s
+		}
+	}
+	s := &junitSuite{Name: name}
+	w.suites = append(w.suites, s)
+	return s
+}
+
+// add adds test case c to suite s.
+func (s *junitSuite) add(c *junitCase) {
+	s.Cases = append(s.Cases, c)
+	s.Tests++
+	if c.Failure != nil {
+		s.Failures++
+	}
+	if c.Skipped != nil {
+		s.Skipped++
+	}
+}
+
+// Result adds result r of a test as test case.
+func (w *JUnitWriter) Result(r Result) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	top, name, _ := strings.Cut(r.Name, "/")
+	if name == "" {
+		name = r.Title
+	}
+	c := &junitCase{Name: name, Classname: top, Time: junitTime(r.Duration)}
+	switch r.Status {
+	case "fail":
+		msg, _, _ := strings.Cut(r.Diff, "\n")
+		if msg == "" {
+			msg = "failed"
+		}
+		c.Failure = &junitFailure{Message: msg, Text: r.Diff}
+	case "skip":
+		c.Skipped = new(struct{})
+	}
+	s := w.suite(top)
+	s.add(c)
+	s.duration += r.Duration
+}
+
+// Diagnostics adds diagnostics l of file, as returned by Lint, as test
+// cases.
+func (w *JUnitWriter) Diagnostics(file string, l []Diagnostic) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	s := w.suite(file)
+	if l == nil {
+		s.add(&junitCase{Name: file, Classname: file})
+		return
+	}
+	for _, d := range l {
+		name := d.Code
+		if d.Line > 0 {
+			name = fmt.Sprintf("%s:%d: %s", file, d.Line, d.Code)
+		}
+		c := &junitCase{Name: name, Classname: file}
+		if d.Severity == SeverityError {
+			c.Failure = &junitFailure{Message: d.Message, Type: d.Code,
+				Text: d.String()}
+		} else {
+			c.SystemOut = &junitText{d.String()}
+		}
+		s.add(c)
+	}
+}
+
+// Close writes collected test suites.
+func (w *JUnitWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, s := range w.suites {
+		s.Time = junitTime(s.duration)
+	}
+	data, err := xml.MarshalIndent(junitSuites{Suites: w.suites}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w.w, "%s%s\n", xml.Header, data)
+	return err
+}
+
+// junitTime returns d in seconds.
+func junitTime(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
@@ -0,0 +1,75 @@
+package testtxt
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestJUnitWriter(t *testing.T) {
+	var b strings.Builder
+	w := NewJUnitWriter(&b)
+	w.Result(Result{Name: "TestX/a", Title: "a", Status: "pass",
+		Duration: 1500 * time.Millisecond})
+	w.Result(Result{Name: "TestX/b", Title: "b", Status: "fail",
+		Diff: "--- expected\n+++ got\n", Duration: time.Second})
+	w.Result(Result{Name: "TestY", Title: "c", Status: "skip"})
+	w.Diagnostics("x.t", []Diagnostic{
+		{File: "x.t", Line: 1, Column: 8, Severity: SeverityError,
+			Code: "parse", Message: "bad <x>"},
+		{File: "x.t", Line: 3, Column: 1, Severity: SeverityWarning,
+			Code: "tab", Message: "tab"},
+	})
+	w.Diagnostics("y.t", nil)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="TestX" tests="2" failures="1" skipped="0" time="2.500">
+    <testcase name="a" classname="TestX" time="1.500"></testcase>
+    <testcase name="b" classname="TestX" time="1.000">
+      <failure message="--- expected"><![CDATA[--- expected
++++ got
+]]></failure>
+    </testcase>
+  </testsuite>
+  <testsuite name="TestY" tests="1" failures="0" skipped="1" time="0.000">
+    <testcase name="c" classname="TestY" time="0.000">
+      <skipped></skipped>
+    </testcase>
+  </testsuite>
+  <testsuite name="x.t" tests="2" failures="1" skipped="0" time="0.000">
+    <testcase name="x.t:1: parse" classname="x.t">
+      <failure message="bad &lt;x&gt;" type="parse"><![CDATA[x.t:1:8: error: bad <x>]]></failure>
+    </testcase>
+    <testcase name="x.t:3: tab" classname="x.t">
+      <system-out><![CDATA[x.t:3:1: warning: tab]]></system-out>
+    </testcase>
+  </testsuite>
+  <testsuite name="y.t" tests="1" failures="0" skipped="0" time="0.000">
+    <testcase name="y.t" classname="y.t"></testcase>
+  </testsuite>
+</testsuites>
+`
+	if d := cmp.Diff(want, b.String()); d != "" {
+		t.Error(d)
+	}
+
+	// Read back.
+	var got junitSuites
+	if err := xml.Unmarshal([]byte(b.String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(got.Suites); n != 4 {
+		t.Fatalf("got %d test suites, want 4", n)
+	}
+	if d := cmp.Diff(w.suites, got.Suites,
+		cmpopts.IgnoreUnexported(junitSuite{})); d != "" {
+		t.Error(d)
+	}
+}
@@ -0,0 +1,58 @@
+package testtxt
+
+// Lint parses file like ParseFile, but doesn't stop at the first error
+// and additionally checks for questionable constructs:
+// unused templates, duplicate titles, substitutions that don't match
+// and tab characters in multi line text.
+// All errors and warnings are returned, ordered by file and position.
+// If target is nil, any attribute is accepted and the first attribute
+// of file is taken as title attribute.
+func Lint(file string, target any, opts ...Option) []Diagnostic {
+	var result []Diagnostic
+	if target == nil {
+		target = &[]struct{ Title string }{}
+		opts = append(opts, func(c *config) {
+			c.valueHook =
+				func(*state, string, string, string, int) error { return nil }
+		})
+	}
+	opts = append(opts,
+		CollectErrors(),
+		WithWarningHandler(func(d Diagnostic) { result = append(result, d) }),
+		func(c *config) { c.lint = true },
+	)
+	for _, d := range Diagnostics(ParseFile(file, target, opts...)) {
+		if d.File == "" {
+			d.File = file
+		}
+		result = append(result, d)
+	}
+	SortDiagnostics(result)
+	return result
+}
+
+// unjoin returns list of errors combined by errors.Join.
+func unjoin(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if j, ok := err.(interface{ Unwrap() []error }); ok {
+		return j.Unwrap()
+	}
+	return []error{err}
+}
+
+// checkDupTitle warns if title at byte offset pos was already used
+// by some other test.
+func (s *state) checkDupTitle(title string, pos int) {
+	if s.titles == nil {
+		s.titles = make(map[string]int)
+	}
+	if prev, found := s.titles[title]; found {
+		line, _ := s.position(prev)
+		s.warnf("duplicate-title", pos,
+			"duplicate =%s=%s, already used at line %d", s.titleAttr, title, line)
+		return
+	}
+	s.titles[title] = pos
+}
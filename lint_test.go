@@ -0,0 +1,36 @@
+package testtxt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLintSortsByFile(t *testing.T) {
+	dir := t.TempDir()
+	templ := filepath.Join(dir, "a.t")
+	if err := os.WriteFile(templ, []byte("=TEMPL=u\n\n  =X=\n=END=\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "b.t")
+	src := "=TEMPL=v\nx\n=END=\n=TITLE=a\n\n=TITLE=a\n"
+	if err := os.WriteFile(file, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, d := range Lint(file, nil, WithTemplateFile(templ)) {
+		got = append(got, fmt.Sprintf("%s:%d %s",
+			filepath.Base(d.File), d.Line, d.Code))
+	}
+	want := []string{
+		"a.t:3 indented-definition",
+		"b.t:1 unused-template",
+		"b.t:6 duplicate-title",
+	}
+	if d := cmp.Diff(want, got); d != "" {
+		t.Error(d)
+	}
+}
@@ -0,0 +1,157 @@
+package testtxt
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// MatchMode tells how an expected text is compared.
+type MatchMode int
+
+const (
+	// MatchExact requires equal text.
+	MatchExact MatchMode = iota
+	// MatchRegexp takes expected text as regular expression,
+	// that must match the complete text. It is selected by suffix
+	// ":RE" of attribute name, e.g. =OUTPUT:RE=.
+	MatchRegexp
+	// MatchContains takes each non empty line of expected text as
+	// fragment, that must occur in text. A line starting with "!" gives
+	// a fragment, that must not occur. A leading backslash is removed,
+	// e.g. to start a fragment with "!". It is selected by suffix
+	// ":CONTAINS" of attribute name.
+	MatchContains
+)
+
+var matchModes = map[string]MatchMode{
+	"":         MatchExact,
+	"RE":       MatchRegexp,
+	"CONTAINS": MatchContains,
+}
+
+// Expected is the type of struct fields, that take an expected text
+// together with the mode of comparison given as suffix of the
+// attribute name.
+type Expected struct {
+	Text string
+	Mode MatchMode
+}
+
+var expectedType = reflect.TypeOf(Expected{})
+
+// MatchOutput compares got with expected in the mode of expected.
+// Normalizers given by option Normalize are applied to got and, if
+// expected isn't a regular expression, to expected text as well.
+// A difference is reported as error of t.
+// It reports whether got matches.
+func MatchOutput(t testing.TB, expected Expected, got string,
+	opts ...CompareOption,
+) bool {
+	t.Helper()
+	return newCompareConfig(opts).match(t, "", expected, got)
+}
+
+// match implements MatchOutput.
+// Non empty label names the compared text in messages.
+func (c *compareConfig) match(t testing.TB, label string, expected Expected,
+	got string,
+) bool {
+	t.Helper()
+	prefix, nameA, nameB := "", "expected", "got"
+	if label != "" {
+		prefix = label + ": "
+		nameA += "/" + label
+		nameB += "/" + label
+	}
+	got = c.normalize(got)
+	switch expected.Mode {
+	case MatchRegexp:
+		if msg := matchRegexp(expected.Text, got); msg != "" {
+			reportDiff(t, prefix+msg)
+			return false
+		}
+		return true
+	case MatchContains:
+		return c.containsAll(t, prefix, expected.Text, got)
+	default:
+		d := c.diff(nameA, nameB, c.normalize(expected.Text), got)
+		if d != "" {
+			reportDiff(t, d)
+			return false
+		}
+		return true
+	}
+}
+
+// matchRegexp reports why got doesn't match regular expression
+// pattern, or returns the empty string if it matches.
+func matchRegexp(pattern, got string) string {
+	re, err := regexp.Compile(`\A(?:` + pattern + `)\z`)
+	if err != nil {
+		return fmt.Sprintf("invalid regular expression: %v", err)
+	}
+	if re.MatchString(got) {
+		return ""
+	}
+	// Try to find first line, that doesn't match.
+	pl, gl := splitLines(pattern), splitLines(got)
+	if len(pl) == len(gl) {
+		for i, p := range pl {
+			re, err := regexp.Compile(`\A(?:` + p + `)\z`)
+			if err == nil && !re.MatchString(gl[i]) {
+				return fmt.Sprintf(
+					"line %d doesn't match regular expression\n"+
+						"pattern: %q\ngot:     %q", i+1, p, gl[i])
+			}
+		}
+	}
+	return fmt.Sprintf("text doesn't match regular expression\n"+
+		"pattern:\n%s\ngot:\n%s", pattern, got)
+}
+
+// ContainsAll checks that got contains each fragment given as line of
+// fragments and doesn't contain fragments given by lines starting
+// with "!", as described for MatchContains.
+// Each violation is reported as error of t.
+// It reports whether all fragments were found as expected.
+func ContainsAll(t testing.TB, fragments, got string) bool {
+	t.Helper()
+	return newCompareConfig(nil).containsAll(t, "", fragments, got)
+}
+
+// containsAll implements ContainsAll. Normalizers of c are applied to
+// each fragment. Messages are prefixed by prefix.
+func (c *compareConfig) containsAll(t testing.TB, prefix, fragments,
+	got string,
+) bool {
+	t.Helper()
+	ok := true
+	for _, line := range strings.Split(fragments, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" {
+			continue
+		}
+		negate := strings.HasPrefix(line, "!")
+		if negate {
+			line = line[1:]
+		}
+		line = c.normalize(strings.TrimPrefix(line, `\`))
+		if strings.Contains(got, line) == negate {
+			if negate {
+				reportDiff(t, fmt.Sprintf("%sunexpected fragment: %q",
+					prefix, line))
+			} else {
+				reportDiff(t, fmt.Sprintf("%smissing fragment: %q",
+					prefix, line))
+			}
+			ok = false
+		}
+	}
+	if !ok {
+		t.Logf("%sgot:\n%s", prefix, got)
+	}
+	return ok
+}
@@ -0,0 +1,102 @@
+package testtxt
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMatchOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []CompareOption
+		expected Expected
+		got      string
+		msgs     []string
+	}{
+		{
+			name:     "exact",
+			expected: Expected{Text: "a\n"},
+			got:      "a\n",
+		},
+		{
+			name:     "regexp matches",
+			expected: Expected{Text: "a+\nb\\d\n", Mode: MatchRegexp},
+			got:      "aaa\nb7\n",
+		},
+		{
+			name:     "regexp must match complete text",
+			expected: Expected{Text: "a", Mode: MatchRegexp},
+			got:      "ab",
+			msgs: []string{"error: line 1 doesn't match regular expression\n" +
+				"pattern: \"a\"\ngot:     \"ab\""},
+		},
+		{
+			name:     "regexp with different number of lines",
+			expected: Expected{Text: "x+", Mode: MatchRegexp},
+			got:      "x\ny",
+			msgs: []string{"error: text doesn't match regular expression\n" +
+				"pattern:\nx+\ngot:\nx\ny"},
+		},
+		{
+			name:     "invalid regexp",
+			expected: Expected{Text: "(", Mode: MatchRegexp},
+			got:      "(",
+			msgs: []string{"error: invalid regular expression: " +
+				"error parsing regexp: missing closing ): `\\A(?:()\\z`"},
+		},
+		{
+			name:     "regexp matches normalized text",
+			opts:     []CompareOption{Normalize(MaskDurations)},
+			expected: Expected{Text: `ok <DURATION>`, Mode: MatchRegexp},
+			got:      "ok 1.2s",
+		},
+		{
+			name:     "contains",
+			expected: Expected{Text: "b\n\n!x\n\\!a\n", Mode: MatchContains},
+			got:      "!abc",
+		},
+		{
+			name:     "contains normalized fragments",
+			opts:     []CompareOption{Normalize(CollapseSpace)},
+			expected: Expected{Text: "a  b\n!c   d\n", Mode: MatchContains},
+			got:      "a \t b c d",
+			msgs: []string{
+				"error: unexpected fragment: \"c d\"",
+				"log: got:\na b c d",
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var ok bool
+			r := record(t, func(r *recorder) {
+				ok = MatchOutput(r, tc.expected, tc.got, tc.opts...)
+			})
+			if ok != (tc.msgs == nil) {
+				t.Errorf("got result %v", ok)
+			}
+			if d := cmp.Diff(tc.msgs, r.msgs); d != "" {
+				t.Error(d)
+			}
+		})
+	}
+}
+
+func TestContainsAll(t *testing.T) {
+	var ok bool
+	r := record(t, func(r *recorder) {
+		ok = ContainsAll(r, "a\r\nb\r\n!c\r\n", "a c")
+	})
+	if ok {
+		t.Error("got result true")
+	}
+	want := []string{
+		"error: missing fragment: \"b\"",
+		"error: unexpected fragment: \"c\"",
+		"log: got:\na c",
+	}
+	if d := cmp.Diff(want, r.msgs); d != "" {
+		t.Error(d)
+	}
+}
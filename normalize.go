@@ -0,0 +1,104 @@
+package testtxt
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Normalizer transforms text before it is compared.
+type Normalizer func(string) string
+
+func (c *compareConfig) normalize(text string) string {
+	for _, n := range c.normalizers {
+		text = n(text)
+	}
+	return text
+}
+
+// StripPath removes directory dir, e.g. a temporary directory, from
+// paths in text: "dir/file" becomes "file" and "dir" alone becomes ".".
+// The directory is also recognized with resolved symbolic links and
+// with slashes as separator.
+func StripPath(dir string) Normalizer {
+	dirs := []string{filepath.Clean(dir)}
+	if real, err := filepath.EvalSymlinks(dir); err == nil {
+		dirs = append(dirs, real)
+	}
+	for _, d := range dirs {
+		if s := filepath.ToSlash(d); s != d {
+			dirs = append(dirs, s)
+		}
+	}
+	var pairs []string
+	for _, d := range dirs {
+		pairs = append(pairs, d+"/", "", d+string(filepath.Separator), "")
+	}
+	for _, d := range dirs {
+		pairs = append(pairs, d, ".")
+	}
+	r := strings.NewReplacer(pairs...)
+	return r.Replace
+}
+
+var timestampRe = regexp.MustCompile(
+	`\d{4}-\d\d-\d\d[T ]\d\d:\d\d:\d\d(\.\d+)?(Z|[+-]\d\d:?\d\d)?`)
+
+// MaskTimestamps replaces timestamps like "2024-01-02T15:04:05Z" or
+// "2024-01-02 15:04:05.123" by "<TIMESTAMP>".
+func MaskTimestamps(text string) string {
+	return timestampRe.ReplaceAllLiteralString(text, "<TIMESTAMP>")
+}
+
+var durationRe = regexp.MustCompile(
+	`\b(\d+(\.\d+)?(ns|us|µs|ms|h|m|s))+\b`)
+
+// MaskDurations replaces durations in the format of time.Duration like
+// "1.5s" or "2m3.004s" by "<DURATION>".
+func MaskDurations(text string) string {
+	return durationRe.ReplaceAllLiteralString(text, "<DURATION>")
+}
+
+var trailingSpaceRe = regexp.MustCompile(`(?m)[ \t]+$`)
+var spaceRe = regexp.MustCompile(`[ \t]+`)
+
+// CollapseSpace removes blanks and tabs at end of lines and replaces
+// each other sequence of blanks and tabs by a single blank.
+func CollapseSpace(text string) string {
+	text = trailingSpaceRe.ReplaceAllLiteralString(text, "")
+	return spaceRe.ReplaceAllLiteralString(text, " ")
+}
+
+var ansiRe = regexp.MustCompile(
+	"\x1b\\[[0-?]*[ -/]*[@-~]|\x1b\\][^\x07\x1b]*(\x07|\x1b\\\\)|\x1b[@-Z\\\\-_]")
+
+// StripANSI removes ANSI escape sequences for colors, cursor movement
+// and terminal titles.
+func StripANSI(text string) string {
+	return ansiRe.ReplaceAllLiteralString(text, "")
+}
+
+// NormalizeCR converts line endings "\r\n" to "\n" and keeps only the
+// last non empty text between carriage returns of each line, as shown
+// by a terminal for progress lines, that are overwritten repeatedly.
+func NormalizeCR(text string) string {
+	if !strings.Contains(text, "\r") {
+		return text
+	}
+	lines := strings.SplitAfter(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i, l := range lines {
+		body, nl := strings.CutSuffix(l, "\n")
+		parts := strings.Split(body, "\r")
+		body = ""
+		for _, p := range parts {
+			if p != "" {
+				body = p
+			}
+		}
+		if nl {
+			body += "\n"
+		}
+		lines[i] = body
+	}
+	return strings.Join(lines, "")
+}
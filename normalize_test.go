@@ -0,0 +1,105 @@
+package testtxt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNormalizers(t *testing.T) {
+	dir := filepath.Join(string(filepath.Separator)+"tmp", "x1")
+	tests := []struct {
+		name string
+		n    Normalizer
+		in   string
+		want string
+	}{
+		{"strip path", StripPath(dir),
+			"/tmp/x1/a/b: error\nin /tmp/x1\n/tmp/x10\n",
+			"a/b: error\nin .\n.0\n"},
+		{"timestamps", MaskTimestamps,
+			"at 2024-01-02T15:04:05Z and 2024-01-02 15:04:05.123+01:00\n",
+			"at <TIMESTAMP> and <TIMESTAMP>\n"},
+		{"date only", MaskTimestamps, "2024-01-02\n", "2024-01-02\n"},
+		{"durations", MaskDurations,
+			"ok 1.5s, 2m3.004s, 10ms, 5µs\nseconds: 5\n",
+			"ok <DURATION>, <DURATION>, <DURATION>, <DURATION>\nseconds: 5\n"},
+		{"collapse space", CollapseSpace,
+			"a  \t b \t\nc\t\n  d\n",
+			"a b\nc\n d\n"},
+		{"strip ANSI", StripANSI,
+			"\x1b[1;31mred\x1b[0m \x1b[2K\x1b]0;title\x07ok\n",
+			"red ok\n"},
+		{"CR line endings", NormalizeCR, "a\r\nb\r\n", "a\nb\n"},
+		{"progress lines", NormalizeCR,
+			"10%\r50%\r100%\r\ndone\rDONE\r\n",
+			"100%\nDONE\n"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.n(tc.in); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestStripPathSymlink(t *testing.T) {
+	real := t.TempDir()
+	link := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink(real, link); err != nil {
+		t.Skip(err)
+	}
+	n := StripPath(link)
+	in := filepath.Join(real, "a") + " " + filepath.Join(link, "b")
+	if got := n(in); got != "a b" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestEqualNormalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []CompareOption
+		expected string
+		got      string
+		msgs     []string
+	}{
+		{
+			name:     "equal after normalizing both",
+			opts:     []CompareOption{Normalize(CollapseSpace, MaskDurations)},
+			expected: "took  1s\n",
+			got:      "took 2.5s \n",
+		},
+		{
+			name:     "normalizers in order",
+			opts:     []CompareOption{Normalize(MaskDurations, CollapseSpace)},
+			expected: "a 1s\n",
+			got:      "a  <DURATION>\n",
+		},
+		{
+			name:     "differs",
+			opts:     []CompareOption{Normalize(CollapseSpace)},
+			expected: "a  b\n",
+			got:      "a c\n",
+			msgs: []string{"error: --- expected\n+++ got\n" +
+				"@@ -1 +1 @@\n-a b\n+a c\n"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var ok bool
+			r := record(t, func(r *recorder) {
+				ok = Equal(r, tc.expected, tc.got, tc.opts...)
+			})
+			if ok != (tc.msgs == nil) {
+				t.Errorf("got result %v", ok)
+			}
+			if d := cmp.Diff(tc.msgs, r.msgs); d != "" {
+				t.Error(d)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package testtxt
+
+import (
+	"text/template"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// Option changes the behaviour of ParseFile.
+type Option func(*config)
+
+type config struct {
+	collectErrors bool
+	warnHandler   func(Diagnostic)
+	templateFiles []string
+	strict        bool
+	rawBytes      bool
+	normalizeCRLF bool
+	sourceMap     *SourceMap
+	funcs         template.FuncMap
+	// Additional checks, only used by Lint.
+	lint bool
+	// Called for each attribute of each test with its name and byte offset.
+	attrHook func(s *state, name string, pos int)
+	// If set, takes expanded text of each attribute instead of struct
+	// fields. Title attribute is then the first attribute of file.
+	// Gets mode and byte offset of definition as well.
+	valueHook func(s *state, name, mode, text string, pos int) error
+}
+
+// CollectErrors lets ParseFile go on after recoverable errors.
+// All errors found are returned, combined by errors.Join.
+// Each error is of type *ParseError and has its own position.
+func CollectErrors() Option {
+	return func(c *config) { c.collectErrors = true }
+}
+
+// WithWarningHandler lets ParseFile call h for each problem,
+// that doesn't prevent parsing, e.g. an unused template.
+func WithWarningHandler(h func(Diagnostic)) Option {
+	return func(c *config) { c.warnHandler = h }
+}
+
+// WithTemplateFile lets ParseFile read definitions of templates
+// from file before the file of test descriptions is parsed.
+// It is an error, if a template is defined in multiple files.
+// This option may be given multiple times.
+func WithTemplateFile(file string) Option {
+	return func(c *config) { c.templateFiles = append(c.templateFiles, file) }
+}
+
+// Strict lets ParseFile treat warnings as errors.
+// See also WithWarningHandler.
+func Strict() Option {
+	return func(c *config) { c.strict = true }
+}
+
+// AllowRawBytes lets ParseFile accept files, that aren't valid UTF-8.
+// By default, invalid bytes are reported as errors.
+func AllowRawBytes() Option {
+	return func(c *config) { c.rawBytes = true }
+}
+
+// NormalizeLineEndings lets ParseFile convert line endings "\r\n"
+// to "\n" in text of attributes and templates.
+func NormalizeLineEndings() Option {
+	return func(c *config) { c.normalizeCRLF = true }
+}
+
+// WithSourceMap lets ParseFile fill m with the origin of each line
+// of each attribute value.
+func WithSourceMap(m *SourceMap) Option {
+	return func(c *config) { c.sourceMap = m }
+}
+
+// WithFuncs adds functions, that can be called in templates.
+// Functions with the same name as a predefined function, e.g. DATE,
+// replace that function.
+func WithFuncs(m template.FuncMap) Option {
+	return func(c *config) {
+		if c.funcs == nil {
+			c.funcs = make(template.FuncMap)
+		}
+		for k, f := range m {
+			c.funcs[k] = f
+		}
+	}
+}
+
+// PrepareOption changes the behaviour of PrepareInDir and related
+// functions.
+type PrepareOption func(*prepareConfig)
+
+type prepareConfig struct {
+	unsafePaths bool
+	modTime     time.Time
+	single      string
+}
+
+func newPrepareConfig(opts []PrepareOption) *prepareConfig {
+	c := new(prepareConfig)
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+// AllowUnsafePaths allows absolute file names and file names with
+// component ".." in file markers and targets of symbolic links
+// outside of the prepared directory.
+// Such files may be created outside of the prepared directory.
+func AllowUnsafePaths() PrepareOption {
+	return func(c *prepareConfig) { c.unsafePaths = true }
+}
+
+// WithModTime sets the modification time of all created files and
+// directories, that have no time given in their marker.
+func WithModTime(t time.Time) PrepareOption {
+	return func(c *prepareConfig) { c.modTime = t }
+}
+
+// SingleFileName sets the name of the file, that Prepare creates from
+// input without markers. Default is "input".
+func SingleFileName(name string) PrepareOption {
+	return func(c *prepareConfig) { c.single = name }
+}
+
+func (c *prepareConfig) singleName() string {
+	if c.single == "" {
+		return "input"
+	}
+	return c.single
+}
+
+// CompareOption changes the behaviour of CompareDir and related
+// functions.
+type CompareOption func(*compareConfig)
+
+type compareConfig struct {
+	ignore      []string
+	normalizers []Normalizer
+	ignorePaths []string
+	unordered   bool
+	context     int
+	maxLines    int
+	color       bool
+	cmpOptions  []cmp.Option
+}
+
+func newCompareConfig(opts []CompareOption) *compareConfig {
+	c := &compareConfig{context: 3}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+// IgnoreFiles excludes files matching one of patterns from comparison.
+// Patterns are matched like in .gitignore:
+// a pattern without slash is matched against the name of each file
+// and directory at any depth, a pattern containing a slash is matched
+// against the path relative to the compared directory and a trailing
+// slash only matches directories. Files below an excluded directory
+// are excluded as well. Patterns use the syntax of path.Match.
+func IgnoreFiles(patterns ...string) CompareOption {
+	return func(c *compareConfig) {
+		c.ignore = append(c.ignore, patterns...)
+	}
+}
+
+// Normalize applies normalizers in given order to expected and actual
+// text before comparing them.
+func Normalize(n ...Normalizer) CompareOption {
+	return func(c *compareConfig) {
+		c.normalizers = append(c.normalizers, n...)
+	}
+}
+
+// IgnorePaths excludes values at given paths from structural
+// comparison by CompareJSON and related functions.
+// A path consists of keys of objects and indexes of arrays, each
+// preceded by a slash like in a JSON pointer, e.g. "/items/0/id".
+// A component "*" matches any key or index.
+func IgnorePaths(paths ...string) CompareOption {
+	return func(c *compareConfig) {
+		c.ignorePaths = append(c.ignorePaths, paths...)
+	}
+}
+
+// UnorderedLines compares texts as multisets of lines, for output with
+// nondeterministic order. Differences are shown as missing and
+// unexpected lines.
+func UnorderedLines() CompareOption {
+	return func(c *compareConfig) { c.unordered = true }
+}
+
+// DiffContext sets the number of unchanged lines shown around each
+// difference. Default is 3.
+func DiffContext(n int) CompareOption {
+	return func(c *compareConfig) { c.context = n }
+}
+
+// MaxDiffLines truncates the shown differences to n lines.
+// Default is 0, which shows all lines.
+func MaxDiffLines(n int) CompareOption {
+	return func(c *compareConfig) { c.maxLines = n }
+}
+
+// ColorDiff marks differences with ANSI colors, if standard output is
+// a terminal and environment variable NO_COLOR isn't set.
+func ColorDiff() CompareOption {
+	return func(c *compareConfig) { c.color = true }
+}
+
+// CmpOptions passes options to cmp.Diff, used by Assert and Diff for
+// values other than strings.
+func CmpOptions(o ...cmp.Option) CompareOption {
+	return func(c *compareConfig) {
+		c.cmpOptions = append(c.cmpOptions, o...)
+	}
+}
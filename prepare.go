@@ -0,0 +1,785 @@
+package testtxt
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Create inDir and fill it with files from input.
+// Parts of input are marked by single lines of dashes
+// followed by a filename.
+// The filename may be followed by an octal file mode like 0755.
+// A marker "name -> target" creates a symbolic link without content.
+// Its target must stay inside of inDir and no other file may be given
+// below the link.
+// A marker "name (dir)" creates an empty directory.
+// A marker "name (base64)" creates a file from base64 encoded content.
+// A marker "name <= file" copies an existing file, given relative to the
+// current working directory.
+// A flag like "@2020-01-01" or "@2020-01-01T12:00:00Z" sets the
+// modification time of a file or directory.
+// A marker "name (tar)", "name (tgz)" or "name (zip)" creates an archive
+// without content in input. Entries of the archive are taken from all
+// other markers with names below "name/", e.g. "name/file".
+// A flag "(ro)" removes write permissions from the mode of a file or
+// directory. Modes of directories are set after all files have been
+// written. Write permissions are restored when the test ends, such
+// that the directory can be removed.
+// If no markers are given, a file named single is created.
+// If single was used it returns path of single, otherwise returns
+// path of inDir.
+// It may be called from tests, benchmarks and fuzz targets.
+func PrepareInDir(t testing.TB, inDir, single, input string,
+	opts ...PrepareOption,
+) string {
+	t.Helper()
+	result, l, err := prepareInDir(inDir, single, input,
+		newPrepareConfig(opts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { restoreWritable(inDir, l) })
+	return result
+}
+
+// PrepareTempDir creates a temporary directory, that is removed when
+// the test ends, and fills it with files from input like PrepareInDir.
+// If no markers are given, a file named "input" is created and its
+// path is returned. Otherwise the path of the directory is returned.
+//
+// If environment variable TESTTXT_KEEP is set to a true value like
+// "1", the directory is created below os.TempDir()/testtxt with a name
+// derived from the name of the test and a unique suffix. Its path is
+// logged and it isn't removed, if the test fails.
+func PrepareTempDir(t testing.TB, input string, opts ...PrepareOption,
+) string {
+	t.Helper()
+	return PrepareInDir(t, tempDir(t), "input", input, opts...)
+}
+
+// tempDir returns a new temporary directory for test t.
+func tempDir(t testing.TB) string {
+	t.Helper()
+	if keep, _ := strconv.ParseBool(os.Getenv("TESTTXT_KEEP")); !keep {
+		return t.TempDir()
+	}
+	base := filepath.Join(os.TempDir(), "testtxt")
+	if err := os.MkdirAll(base, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Each call gets its own directory, even for the same test or for
+	// names of tests, that are equal after replacing unsafe characters.
+	name := unsafeChars.ReplaceAllString(t.Name(), "_")
+	dir, err := os.MkdirTemp(base, name+"-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("Preparing files in %s", dir)
+	t.Cleanup(func() {
+		if t.Failed() {
+			t.Logf("Keeping files in %s", dir)
+		} else {
+			os.RemoveAll(dir)
+		}
+	})
+	return dir
+}
+
+var unsafeChars = regexp.MustCompile(`[^\w.-]+`)
+
+// PrepareInDirE is like PrepareInDir, but returns an error
+// instead of failing the test.
+// It can be used outside of tests.
+// For very large input, use a DirWriter instead.
+func PrepareInDirE(inDir, single, input string, opts ...PrepareOption,
+) (string, error) {
+	result, _, err := prepareInDir(inDir, single, input,
+		newPrepareConfig(opts))
+	return result, err
+}
+
+// prepareInDir implements PrepareInDirE and
+// additionally returns the written entries.
+func prepareInDir(inDir, single, input string, c *prepareConfig,
+) (string, []*fileEntry, error) {
+	if input == "NONE" {
+		input = ""
+	}
+	l, err := splitInput(input, c)
+	if err != nil {
+		return "", nil, err
+	}
+	result := inDir
+	// No filename
+	if l == nil {
+		result = filepath.Join(inDir, single)
+		l = []*fileEntry{{name: single, data: input}}
+	}
+	for _, e := range l {
+		if err := writeEntry(inDir, e); err != nil {
+			return "", l, err
+		}
+	}
+	return result, l, finishEntries(inDir, l, c)
+}
+
+// Prepared describes files created by Prepare.
+type Prepared struct {
+	// Dir is the directory, where files have been created.
+	Dir string
+	// SingleFile is the path of the only regular file given by input,
+	// empty if input gives more or other files.
+	SingleFile string
+	// Files are paths of all created files, directories and symbolic
+	// links in order of input.
+	Files []string
+}
+
+// Prepare fills directory inDir with files from input like
+// PrepareInDir, but describes the created files in its result.
+// If inDir is empty, a temporary directory is created like in
+// PrepareTempDir.
+// Input without markers is stored in a file named "input" or as given
+// by option SingleFileName. Alternatively input may choose the name of
+// its only file by a single marker.
+func Prepare(t testing.TB, inDir, input string, opts ...PrepareOption,
+) *Prepared {
+	t.Helper()
+	if inDir == "" {
+		inDir = tempDir(t)
+	}
+	p, l, err := prepare(inDir, input, newPrepareConfig(opts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { restoreWritable(inDir, l) })
+	return p
+}
+
+// PrepareE is like Prepare, but returns an error
+// instead of failing the test.
+// Parameter inDir must not be empty.
+func PrepareE(inDir, input string, opts ...PrepareOption,
+) (*Prepared, error) {
+	if inDir == "" {
+		return nil, fmt.Errorf("missing name of directory")
+	}
+	p, _, err := prepare(inDir, input, newPrepareConfig(opts))
+	return p, err
+}
+
+func prepare(inDir, input string, c *prepareConfig,
+) (*Prepared, []*fileEntry, error) {
+	_, l, err := prepareInDir(inDir, c.singleName(), input, c)
+	if err != nil {
+		return nil, l, err
+	}
+	p := &Prepared{Dir: inDir}
+	for _, e := range l {
+		p.Files = append(p.Files,
+			filepath.Join(inDir, filepath.FromSlash(e.name)))
+	}
+	if len(l) == 1 && !l[0].dir && l[0].link == "" {
+		p.SingleFile = p.Files[0]
+	}
+	return p, l, nil
+}
+
+// PrepareMapFS creates an in-memory file system from input, which
+// uses the same markers as PrepareInDir.
+// Input without any marker is an error.
+func PrepareMapFS(input string, opts ...PrepareOption,
+) (fstest.MapFS, error) {
+	if input == "NONE" {
+		input = ""
+	}
+	c := newPrepareConfig(opts)
+	l, err := splitInput(input, c)
+	if err != nil {
+		return nil, err
+	}
+	if l == nil && input != "" {
+		return nil, fmt.Errorf("missing file marker in first line")
+	}
+	m := make(fstest.MapFS)
+	for _, e := range l {
+		f := &fstest.MapFile{Mode: 0644}
+		switch {
+		case e.dir:
+			f.Mode = fs.ModeDir | 0755
+		case e.link != "":
+			f.Mode = fs.ModeSymlink | 0777
+			f.Data = []byte(e.link)
+		case e.from != "":
+			f.Data, err = os.ReadFile(e.from)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			f.Data = []byte(e.data)
+		}
+		if e.modeSet {
+			f.Mode = f.Mode&^fs.ModePerm | e.mode
+		}
+		if e.link == "" {
+			f.ModTime = e.modTime(c)
+		}
+		m[path.Clean(e.name)] = f
+	}
+	return m, nil
+}
+
+// SplitFiles splits input, which uses the same markers as
+// PrepareInDir, into a map from file name to content.
+// Directories and symbolic links are left out.
+func SplitFiles(input string, opts ...PrepareOption,
+) (map[string]string, error) {
+	m, err := PrepareMapFS(input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]string)
+	for name, f := range m {
+		if f.Mode.IsRegular() {
+			result[name] = string(f.Data)
+		}
+	}
+	return result, nil
+}
+
+// fileEntry describes a file given by a marker in input.
+type fileEntry struct {
+	name     string
+	mode     fs.FileMode
+	modeSet  bool
+	link     string // target of symbolic link
+	dir      bool
+	base64   bool
+	from     string // file to copy
+	mtime    time.Time
+	readOnly bool
+	archive  string // "tar", "tgz" or "zip"
+	data     string
+	line     int // line of marker in input
+}
+
+var markerRe = regexp.MustCompile(`(?m)^-+[ ]*(\S.*?)[ ]*\r?\n`)
+var modeRe = regexp.MustCompile(`^0[0-7]{3}$`)
+
+// splitInput splits input at file markers.
+// It returns nil if input has no markers.
+func splitInput(input string, c *prepareConfig) ([]*fileEntry, error) {
+	var l []*fileEntry
+	var starts, ends []int
+	for _, m := range markerRe.FindAllStringSubmatchIndex(input, -1) {
+		e := parseMarker(input[m[2]:m[3]])
+		if e == nil {
+			continue
+		}
+		l = append(l, e)
+		starts = append(starts, m[0])
+		ends = append(ends, m[1])
+	}
+	if l == nil {
+		return nil, nil
+	}
+	if starts[0] != 0 {
+		return nil, fmt.Errorf("missing file marker in first line")
+	}
+	seen := make(map[string]*fileEntry)
+	line := 1
+	for i, e := range l {
+		end := len(input)
+		if i+1 < len(l) {
+			end = starts[i+1]
+		}
+		e.data = input[ends[i]:end]
+		// Count lines incrementally, to keep large input linear.
+		if i > 0 {
+			line += strings.Count(input[starts[i-1]:starts[i]], "\n")
+		}
+		e.line = line
+		if err := checkEntry(e, c, seen); err != nil {
+			return nil, err
+		}
+		if (e.link != "" || e.dir || e.from != "" || e.archive != "") &&
+			strings.TrimSpace(e.data) != "" {
+			return nil, fmt.Errorf("unexpected content for %s at line %d",
+				e.name, e.line)
+		}
+		if e.base64 {
+			b, err := base64.StdEncoding.DecodeString(
+				strings.Join(strings.Fields(e.data), ""))
+			if err != nil {
+				return nil, fmt.Errorf(
+					"invalid base64 content for %s at line %d: %v", e.name, e.line, err)
+			}
+			e.data = string(b)
+		}
+	}
+	if err := checkLinks(l, c); err != nil {
+		return nil, err
+	}
+	return packArchives(l)
+}
+
+// checkEntry checks name of e and reports a name already recorded
+// in seen, which maps cleaned names to entries.
+// Files must not be placed below a symbolic link, otherwise they
+// could be written outside of the prepared directory.
+func checkEntry(e *fileEntry, c *prepareConfig, seen map[string]*fileEntry,
+) error {
+	if err := checkName(e.name, c); err != nil {
+		return fmt.Errorf("%v at line %d", err, e.line)
+	}
+	name := path.Clean(e.name)
+	if prev, found := seen[name]; found {
+		return fmt.Errorf("duplicate file %s at lines %d and %d",
+			name, prev.line, e.line)
+	}
+	for p := path.Dir(name); p != "." && p != "/"; p = path.Dir(p) {
+		if prev := seen[p]; prev != nil && prev.link != "" {
+			return fmt.Errorf("file %s at line %d is below symbolic link"+
+				" %s at line %d", name, e.line, p, prev.line)
+		}
+	}
+	if e.link != "" {
+		var below *fileEntry
+		for n, prev := range seen {
+			if strings.HasPrefix(n, name+"/") &&
+				(below == nil || prev.line < below.line) {
+				below = prev
+			}
+		}
+		if below != nil {
+			return fmt.Errorf("file %s at line %d is below symbolic link"+
+				" %s at line %d", path.Clean(below.name), below.line, name, e.line)
+		}
+	}
+	seen[name] = e
+	return nil
+}
+
+// checkLinks checks, that targets of symbolic links in l stay inside
+// the prepared directory. Targets are resolved lexically, following
+// other symbolic links of l.
+func checkLinks(l []*fileEntry, c *prepareConfig) error {
+	if c.unsafePaths {
+		return nil
+	}
+	links := make(map[string]string)
+	for _, e := range l {
+		if e.link != "" {
+			links[path.Clean(e.name)] = e.link
+		}
+	}
+	for _, e := range l {
+		if e.link != "" && linkEscapes(path.Clean(e.name), links) {
+			return fmt.Errorf("target %q of symbolic link %s at line %d"+
+				" is outside of directory", e.link, e.name, e.line)
+		}
+	}
+	return nil
+}
+
+// linkEscapes reports whether target of symbolic link name in links
+// is absolute or leads outside of directory.
+// A loop of links doesn't escape.
+func linkEscapes(name string, links map[string]string) bool {
+	var cur []string
+	if dir := path.Dir(name); dir != "." {
+		cur = strings.Split(dir, "/")
+	}
+	target := links[name]
+	if isAbsPath(target) {
+		return true
+	}
+	todo := strings.Split(target, "/")
+	for n := 0; len(todo) > 0; {
+		part := todo[0]
+		todo = todo[1:]
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			if len(cur) == 0 {
+				return true
+			}
+			cur = cur[:len(cur)-1]
+			continue
+		}
+		cur = append(cur, part)
+		target, found := links[strings.Join(cur, "/")]
+		if !found {
+			continue
+		}
+		if n++; n > 255 {
+			return false
+		}
+		if isAbsPath(target) {
+			return true
+		}
+		cur = cur[:len(cur)-1]
+		todo = append(strings.Split(target, "/"), todo...)
+	}
+	return false
+}
+
+// parseMarker parses text of marker following the leading dashes.
+// It returns nil if text isn't a valid marker.
+func parseMarker(spec string) *fileEntry {
+	fields := strings.Fields(spec)
+	e := &fileEntry{name: fields[0]}
+	if len(fields) == 3 && fields[1] == "->" {
+		e.link = fields[2]
+		return e
+	}
+	flags := fields[1:]
+	if len(fields) >= 3 && fields[1] == "<=" {
+		e.from = fields[2]
+		flags = fields[3:]
+	}
+	for _, f := range flags {
+		switch {
+		case modeRe.MatchString(f) && !e.modeSet:
+			m, _ := strconv.ParseUint(f, 8, 32)
+			e.mode = fs.FileMode(m)
+			e.modeSet = true
+		case f == "(dir)" && !e.dir:
+			e.dir = true
+		case f == "(base64)" && !e.base64:
+			e.base64 = true
+		case archiveFormats[f] && e.archive == "":
+			e.archive = f[1 : len(f)-1]
+		case f == "(ro)" && !e.readOnly:
+			e.readOnly = true
+		case strings.HasPrefix(f, "@") && e.mtime.IsZero():
+			t := parseTime(f[1:])
+			if t.IsZero() {
+				return nil
+			}
+			e.mtime = t
+		default:
+			return nil
+		}
+	}
+	if e.dir && (e.base64 || e.from != "") || e.base64 && e.from != "" ||
+		e.archive != "" && (e.dir || e.base64 || e.from != "") {
+		return nil
+	}
+	if e.readOnly {
+		if !e.modeSet {
+			e.mode = 0644
+			if e.dir {
+				e.mode = 0755
+			}
+		}
+		e.mode &^= 0222
+		e.modeSet = true
+	}
+	return e
+}
+
+var timeLayouts = []string{
+	"2006-01-02",
+	"2006-01-02T15:04:05",
+	time.RFC3339,
+	time.RFC3339Nano,
+}
+
+// parseTime parses time of marker flag "@time".
+// Time without time zone is taken as UTC.
+// It returns the zero time if text isn't valid.
+func parseTime(text string) time.Time {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, text); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// modTime returns modification time of e, which defaults to
+// time given by option WithModTime.
+func (e *fileEntry) modTime(c *prepareConfig) time.Time {
+	if e.mtime.IsZero() {
+		return c.modTime
+	}
+	return e.mtime
+}
+
+// finishEntries sets modification times of entries l written to dir
+// and modes of directories.
+// It must be called after all entries have been written, because
+// creating a file changes the modification time of its directory
+// and fails in a read-only directory.
+// Symbolic links are left unchanged.
+func finishEntries(dir string, l []*fileEntry, c *prepareConfig) error {
+	for _, e := range l {
+		t := e.modTime(c)
+		if t.IsZero() || e.link != "" {
+			continue
+		}
+		file := filepath.Join(dir, filepath.FromSlash(e.name))
+		if err := os.Chtimes(file, t, t); err != nil {
+			return err
+		}
+	}
+	for _, e := range l {
+		if e.dir && e.modeSet {
+			file := filepath.Join(dir, filepath.FromSlash(e.name))
+			if err := os.Chmod(file, e.mode); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// restoreWritable adds write permission for the owner to files and
+// directories of l in dir, such that dir can be removed.
+func restoreWritable(dir string, l []*fileEntry) {
+	for _, e := range l {
+		if e.modeSet && e.mode&0200 == 0 && e.link == "" {
+			file := filepath.Join(dir, filepath.FromSlash(e.name))
+			os.Chmod(file, e.mode|0200)
+		}
+	}
+}
+
+// checkName checks that name of file is valid on current
+// operating system and stays inside the prepared directory.
+func checkName(name string, c *prepareConfig) error {
+	if !c.unsafePaths {
+		if isAbsPath(name) {
+			return fmt.Errorf("absolute file name %q isn't allowed", name)
+		}
+		for _, part := range strings.Split(name, "/") {
+			if part == ".." {
+				return fmt.Errorf("file name %q must not contain \"..\"", name)
+			}
+		}
+	}
+	invalid := "\x00"
+	if runtime.GOOS == "windows" {
+		invalid += `<>:"|?*\`
+		for c := byte(1); c < 32; c++ {
+			invalid += string(c)
+		}
+	}
+	if i := strings.IndexAny(name, invalid); i != -1 {
+		return fmt.Errorf("invalid character %q in file name %q", name[i], name)
+	}
+	return nil
+}
+
+// isAbsPath reports whether name is an absolute path or has a volume
+// name on current operating system.
+func isAbsPath(name string) bool {
+	return path.IsAbs(name) || filepath.IsAbs(filepath.FromSlash(name)) ||
+		filepath.VolumeName(filepath.FromSlash(name)) != ""
+}
+
+// writeEntry creates file described by e in directory dir.
+func writeEntry(dir string, e *fileEntry) error {
+	file := filepath.Join(dir, filepath.FromSlash(e.name))
+	if e.dir {
+		return os.MkdirAll(file, 0755)
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return fmt.Errorf("can't create directory for '%s': %v", file, err)
+	}
+	if e.link != "" {
+		return os.Symlink(filepath.FromSlash(e.link), file)
+	}
+	if e.from != "" {
+		if err := copyFile(e.from, file); err != nil {
+			return err
+		}
+		if e.modeSet {
+			return os.Chmod(file, e.mode)
+		}
+		return nil
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	// Write string directly, to avoid a copy of large content.
+	if _, err := f.WriteString(e.data); err != nil {
+		f.Close()
+		return err
+	}
+	return closeEntry(f, e)
+}
+
+// closeEntry closes file f, written from e, and sets its mode.
+func closeEntry(f *os.File, e *fileEntry) error {
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if e.modeSet {
+		return os.Chmod(f.Name(), e.mode)
+	}
+	return nil
+}
+
+func copyFile(from, to string) error {
+	r, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// DirToBlocks reads directory tree dir and returns it in the format
+// of input of PrepareInDir.
+// Files are given in lexical order.
+// Content of a file is base64 encoded if it isn't valid UTF-8,
+// contains a line looking like a marker, or if it doesn't end with a
+// newline and isn't the last file.
+// It is an error, if a name has white space.
+func DirToBlocks(dir string) (string, error) {
+	l, err := readEntries(dir)
+	if err != nil {
+		return "", err
+	}
+	return entriesToBlocks(l)
+}
+
+// entriesToBlocks returns entries l in the format of input of
+// PrepareInDir, as described for DirToBlocks.
+// It is an error, if the name of an entry or the target of a symbolic
+// link can't be given in a marker.
+func entriesToBlocks(l []*fileEntry) (string, error) {
+	var b strings.Builder
+	for i, e := range l {
+		for _, n := range []string{e.name, e.link} {
+			if strings.ContainsFunc(n, unicode.IsSpace) {
+				return "", fmt.Errorf("can't write %q in file marker", n)
+			}
+		}
+		m := e.name
+		data := e.data
+		switch {
+		case e.dir:
+			m += "/ (dir)"
+		case e.link != "":
+			m += " -> " + e.link
+		}
+		if e.modeSet {
+			m += fmt.Sprintf(" %04o", e.mode)
+		}
+		if !utf8.ValidString(data) || hasMarker(data) ||
+			data != "" && !strings.HasSuffix(data, "\n") && i+1 < len(l) {
+			m += " (base64)"
+			data = encodeBase64(data)
+		}
+		b.WriteString("---- " + m + "\n")
+		b.WriteString(data)
+	}
+	return b.String(), nil
+}
+
+// readEntries reads directory tree dir in lexical order.
+// Directories are only returned, if they are empty.
+// Mode is only set, if it differs from default mode.
+func readEntries(dir string) ([]*fileEntry, error) {
+	var l []*fileEntry
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == dir {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		e := &fileEntry{name: filepath.ToSlash(rel)}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		e.mode = info.Mode().Perm()
+		switch {
+		case d.IsDir():
+			entries, err := os.ReadDir(p)
+			if err != nil {
+				return err
+			}
+			if len(entries) != 0 {
+				return nil
+			}
+			e.dir = true
+			e.modeSet = e.mode != 0755
+		case info.Mode()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			e.link = filepath.ToSlash(target)
+		case info.Mode().IsRegular():
+			data, err := os.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			e.data = string(data)
+			e.modeSet = e.mode != 0644
+		default:
+			return fmt.Errorf("unsupported type of file %s", p)
+		}
+		l = append(l, e)
+		return nil
+	})
+	return l, err
+}
+
+// hasMarker reports whether text contains a line,
+// that would be recognized as file marker.
+func hasMarker(text string) bool {
+	for _, m := range markerRe.FindAllStringSubmatch(text, -1) {
+		if parseMarker(m[1]) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeBase64 encodes data in lines of at most 76 characters.
+func encodeBase64(data string) string {
+	enc := base64.StdEncoding.EncodeToString([]byte(data))
+	var b strings.Builder
+	for len(enc) > 76 {
+		b.WriteString(enc[:76] + "\n")
+		enc = enc[76:]
+	}
+	if enc != "" {
+		b.WriteString(enc + "\n")
+	}
+	return b.String()
+}
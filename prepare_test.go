@@ -0,0 +1,482 @@
+package testtxt
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// describeEntry describes the flags of e in a single line.
+func describeEntry(e *fileEntry) string {
+	if e == nil {
+		return "invalid"
+	}
+	l := []string{e.name}
+	if e.link != "" {
+		l = append(l, "link="+e.link)
+	}
+	if e.from != "" {
+		l = append(l, "from="+e.from)
+	}
+	if e.modeSet {
+		l = append(l, fmt.Sprintf("mode=%04o", e.mode))
+	}
+	if e.dir {
+		l = append(l, "dir")
+	}
+	if e.base64 {
+		l = append(l, "base64")
+	}
+	if e.archive != "" {
+		l = append(l, "archive="+e.archive)
+	}
+	if e.readOnly {
+		l = append(l, "ro")
+	}
+	if !e.mtime.IsZero() {
+		l = append(l, "mtime="+e.mtime.UTC().Format("2006-01-02T15:04:05Z"))
+	}
+	return strings.Join(l, " ")
+}
+
+func TestParseMarker(t *testing.T) {
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{"a", "a"},
+		{"dir/a.txt", "dir/a.txt"},
+		{"a 0600", "a mode=0600"},
+		{"a 0600 0644", "invalid"},
+		{"a 600", "invalid"},
+		{"a 0800", "invalid"},
+		{"d (dir)", "d dir"},
+		{"d (dir) 0700", "d mode=0700 dir"},
+		{"d (dir) (dir)", "invalid"},
+		{"a (base64)", "a base64"},
+		{"a (base64) 0755", "a mode=0755 base64"},
+		{"l -> a", "l link=a"},
+		{"l -> a 0644", "invalid"},
+		{"a <= ../x", "a from=../x"},
+		{"a <= ../x 0600", "a from=../x mode=0600"},
+		{"a <=", "invalid"},
+		{"x.tar (tar)", "x.tar archive=tar"},
+		{"x.tgz (tgz)", "x.tgz archive=tgz"},
+		{"x.zip (zip) @2020-01-02", "x.zip archive=zip mtime=2020-01-02T00:00:00Z"},
+		{"x (tar) (zip)", "invalid"},
+		{"a (ro)", "a mode=0444 ro"},
+		{"a (ro) 0664", "a mode=0444 ro"},
+		{"d (dir) (ro)", "d mode=0555 dir ro"},
+		{"a @2021-03-04T05:06:07", "a mtime=2021-03-04T05:06:07Z"},
+		{"a @2021-03-04T05:06:07+01:00", "a mtime=2021-03-04T04:06:07Z"},
+		{"a @2021-13-01", "invalid"},
+		{"a @2021-01-01 @2021-01-02", "invalid"},
+		{"a (unknown)", "invalid"},
+		{"d (dir) (base64)", "invalid"},
+		{"d (dir) <= x", "invalid"},
+		{"a <= x (base64)", "invalid"},
+		{"x (tar) (dir)", "invalid"},
+		{"x (tar) (base64)", "invalid"},
+		{"x <= y (zip)", "invalid"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.spec, func(t *testing.T) {
+			if got := describeEntry(parseMarker(tc.spec)); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+// prepareFuncs are the functions, that write files from input into a
+// directory.
+var prepareFuncs = []struct {
+	name string
+	f    func(dir, input string, opts ...PrepareOption) error
+}{
+	{"PrepareInDirE", func(dir, input string, opts ...PrepareOption) error {
+		_, err := PrepareInDirE(dir, "input", input, opts...)
+		return err
+	}},
+	{"DirWriter", func(dir, input string, opts ...PrepareOption) error {
+		w := NewDirWriter(dir, opts...)
+		if _, err := io.WriteString(w, input); err != nil {
+			return err
+		}
+		return w.Close()
+	}},
+	{"SyncDir", SyncDir},
+	{"PrepareMapFS", func(dir, input string, opts ...PrepareOption) error {
+		_, err := PrepareMapFS(input, opts...)
+		return err
+	}},
+}
+
+func TestUnsafePaths(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		unsafe bool
+		err    string
+	}{
+		{
+			name:  "parent directory",
+			input: "---- ../x\n",
+			err:   `file name "../x" must not contain ".." at line 1`,
+		},
+		{
+			name:  "absolute name",
+			input: "---- /x\n",
+			err:   `absolute file name "/x" isn't allowed at line 1`,
+		},
+		{
+			name:  "file below link",
+			input: "---- l -> d\n---- d (dir)\n---- l/x\ny\n",
+			err:   "file l/x at line 3 is below symbolic link l at line 1",
+		},
+		{
+			name:  "file below later link",
+			input: "---- l/x\ny\n---- l -> d\n",
+			err:   "file l/x at line 1 is below symbolic link l at line 3",
+		},
+		{
+			name:   "file below link with unsafe paths",
+			input:  "---- l -> /\n---- l/x\ny\n",
+			unsafe: true,
+			err:    "file l/x at line 2 is below symbolic link l at line 1",
+		},
+		{
+			name:  "target outside of subdirectory",
+			input: "---- d/l -> ../../x\n",
+			err:   `target "../../x" of symbolic link d/l at line 1 is outside of directory`,
+		},
+		{
+			name:  "target through other link",
+			input: "---- a -> .\n---- b -> a/..\n",
+			err:   `target "a/.." of symbolic link b at line 2 is outside of directory`,
+		},
+		{
+			name:  "target through later link",
+			input: "---- b -> a/../x\n---- a -> d/..\n---- d (dir)\n",
+			err:   `target "a/../x" of symbolic link b at line 1 is outside of directory`,
+		},
+		{
+			name:  "target through absolute link",
+			input: "---- a -> /tmp\n---- b -> a/x\n",
+			err:   `target "/tmp" of symbolic link a at line 1 is outside of directory`,
+		},
+		{
+			name:  "target inside",
+			input: "---- d/l -> ../a\n---- a -> d/./l/..\n---- x -> d/l\n",
+		},
+		{
+			name:  "loop",
+			input: "---- a -> b\n---- b -> a/x\n",
+		},
+		{
+			name:   "unsafe target",
+			input:  "---- d/l -> ../../x\n",
+			unsafe: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var opts []PrepareOption
+			if tc.unsafe {
+				opts = append(opts, AllowUnsafePaths())
+			}
+			for _, p := range prepareFuncs {
+				parent := t.TempDir()
+				dir := filepath.Join(parent, "dir")
+				err := p.f(dir, tc.input, opts...)
+				if tc.err == "" && err != nil {
+					t.Errorf("%s: %v", p.name, err)
+				}
+				if tc.err != "" && (err == nil || err.Error() != tc.err) {
+					t.Errorf("%s: got error %v, want %q", p.name, err, tc.err)
+				}
+				l, _ := os.ReadDir(parent)
+				if len(l) > 1 {
+					t.Errorf("%s: created %s outside of directory",
+						p.name, l[len(l)-1].Name())
+				}
+			}
+		})
+	}
+}
+
+func TestSymlinkMarker(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		unsafe bool
+		links  map[string]string
+		err    string
+	}{
+		{
+			name:  "relative target",
+			input: "---- releases/v2/conf\nx\n---- link/current -> ../releases/v2\n",
+			links: map[string]string{"link/current": "../releases/v2"},
+		},
+		{
+			name:  "dangling target",
+			input: "---- l -> missing\n",
+			links: map[string]string{"l": "missing"},
+		},
+		{
+			name:  "absolute target",
+			input: "---- l -> /abs\n",
+			err:   `target "/abs" of symbolic link l at line 1 is outside of directory`,
+		},
+		{
+			name:  "target in parent directory",
+			input: "---- l -> ../..\n",
+			err:   `target "../.." of symbolic link l at line 1 is outside of directory`,
+		},
+		{
+			name:   "absolute target with unsafe paths",
+			input:  "---- l -> /abs\n",
+			unsafe: true,
+			links:  map[string]string{"l": "/abs"},
+		},
+		{
+			name:   "target in parent directory with unsafe paths",
+			input:  "---- l -> ../..\n",
+			unsafe: true,
+			links:  map[string]string{"l": "../.."},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var opts []PrepareOption
+			if tc.unsafe {
+				opts = append(opts, AllowUnsafePaths())
+			}
+			dir := t.TempDir()
+			_, err := PrepareInDirE(dir, "input", tc.input, opts...)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("got error %v, want %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, want := range tc.links {
+				got, err := os.Readlink(filepath.Join(dir, filepath.FromSlash(name)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if filepath.ToSlash(got) != want {
+					t.Errorf("%s: got target %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPrepareMapFSModes(t *testing.T) {
+	m, err := PrepareMapFS(
+		"---- a 0600\n---- d (dir) (ro)\n---- l -> a\n---- r (ro)\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for name, f := range m {
+		got[name] = f.Mode.String()
+	}
+	want := map[string]string{
+		"a": "-rw-------",
+		"d": "dr-xr-xr-x",
+		"l": "Lrwxrwxrwx",
+		"r": "-r--r--r--",
+	}
+	if d := cmp.Diff(want, got); d != "" {
+		t.Error(d)
+	}
+}
+
+func TestSplitFiles(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  map[string]string
+		err   string
+	}{
+		{
+			name:  "single file",
+			input: "---- a\nabc\n",
+			want:  map[string]string{"a": "abc\n"},
+		},
+		{
+			name:  "several files",
+			input: "---- a\n1\n---- b/c\n2\n3\n-- d\n",
+			want:  map[string]string{"a": "1\n", "b/c": "2\n3\n", "d": ""},
+		},
+		{
+			name:  "dashes without valid marker are content",
+			input: "---- a\n---- b (unknown)\n",
+			want:  map[string]string{"a": "---- b (unknown)\n"},
+		},
+		{
+			name:  "directories and links are left out",
+			input: "---- d (dir)\n---- l -> a\n---- a\nx\n",
+			want:  map[string]string{"a": "x\n"},
+		},
+		{
+			name:  "base64",
+			input: "---- a (base64)\nYWJj\nZGVm\n",
+			want:  map[string]string{"a": "abcdef"},
+		},
+		{
+			name:  "no marker",
+			input: "abc\n",
+			err:   "missing file marker in first line",
+		},
+		{
+			name:  "marker not in first line",
+			input: "abc\n---- a\n",
+			err:   "missing file marker in first line",
+		},
+		{
+			name:  "duplicate",
+			input: "---- a\n---- b\n---- ./a\n",
+			err:   "duplicate file a at lines 1 and 3",
+		},
+		{
+			name:  "content of directory",
+			input: "---- a\n---- d (dir)\nx\n",
+			err:   "unexpected content for d at line 2",
+		},
+		{
+			name:  "content of link",
+			input: "---- l -> a\nx\n",
+			err:   "unexpected content for l at line 1",
+		},
+		{
+			name:  "invalid base64",
+			input: "---- a\n\n---- b (base64)\n!!\n",
+			err: "invalid base64 content for b at line 3:" +
+				" illegal base64 data at input byte 0",
+		},
+		{
+			name:  "absolute name",
+			input: "---- /a\n",
+			err:   `absolute file name "/a" isn't allowed at line 1`,
+		},
+		{
+			name:  "parent directory",
+			input: "---- a\n---- b/../../c\n",
+			err:   `file name "b/../../c" must not contain ".." at line 2`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := SplitFiles(tc.input)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("got error %v, want %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := cmp.Diff(tc.want, got); d != "" {
+				t.Error(d)
+			}
+		})
+	}
+}
+
+func TestEntriesToBlocks(t *testing.T) {
+	tests := []struct {
+		name string
+		l    []*fileEntry
+		want string
+		err  string
+	}{
+		{
+			name: "files",
+			l: []*fileEntry{
+				{name: "a", data: "x\n"},
+				{name: "b", mode: 0755, modeSet: true, data: "y\n"},
+			},
+			want: "---- a\nx\n---- b 0755\ny\n",
+		},
+		{
+			name: "directory and link",
+			l: []*fileEntry{
+				{name: "d", dir: true},
+				{name: "l", link: "a"},
+			},
+			want: "---- d/ (dir)\n---- l -> a\n",
+		},
+		{
+			name: "missing newline at end",
+			l: []*fileEntry{
+				{name: "a", data: "x"},
+				{name: "b", data: "y"},
+			},
+			want: "---- a (base64)\neA==\n---- b\ny",
+		},
+		{
+			name: "content with marker",
+			l:    []*fileEntry{{name: "a", data: "---- b\n"}},
+			want: "---- a (base64)\nLS0tLSBiCg==\n",
+		},
+		{
+			name: "space in name",
+			l:    []*fileEntry{{name: "a b", data: "x\n"}},
+			err:  `can't write "a b" in file marker`,
+		},
+		{
+			name: "space in target of link",
+			l:    []*fileEntry{{name: "l", link: "a\tb"}},
+			err:  `can't write "a\tb" in file marker`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := entriesToBlocks(tc.l)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("got error %v, want %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+			// Result must be accepted as input.
+			if _, err := SplitFiles(got); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestDirToBlocks(t *testing.T) {
+	input := "---- a\nx\n---- b/c 0755\ny\n---- b/l -> ../a\n" +
+		"---- d/ (dir)\n---- e (base64)\n/w==\n---- f\nno newline"
+	dir := t.TempDir()
+	if _, err := PrepareInDirE(dir, "input", input); err != nil {
+		t.Fatal(err)
+	}
+	got, err := DirToBlocks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != input {
+		t.Errorf("got %q, want %q", got, input)
+	}
+}
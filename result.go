@@ -0,0 +1,97 @@
+package testtxt
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Result describes the outcome of a test run by Run, RunTests or a
+// command runner. It is passed to hooks of option ResultHook.
+type Result struct {
+	// Name is the full name of the subtest.
+	Name  string
+	Title string
+	// Tags are the tags of attribute =TAGS=.
+	Tags     []string
+	Duration time.Duration
+	// Status is one of "pass", "fail" or "skip".
+	Status string
+	// Diff summarizes the differences reported by comparison helpers
+	// of this package, like Equal or CompareDir. It is limited to
+	// resultDiffLines lines and empty if no difference was reported.
+	Diff string
+}
+
+// Maximum number of lines in Result.Diff.
+const resultDiffLines = 20
+
+// ResultHook adds fn, that is called with the result of each test,
+// that has been run or skipped. Tests left out by =ONLY= or by
+// selection of tags or titles aren't reported.
+// Fn is called from parallel tests concurrently.
+// A test with =XFAIL= or =FLAKY= is reported once by the parent
+// process; hooks aren't called in its subprocess.
+func ResultHook(fn func(Result)) RunOption {
+	return func(c *runConfig) { c.resultHooks = append(c.resultHooks, fn) }
+}
+
+// diffLog collects differences reported for a test.
+type diffLog struct {
+	mu    sync.Mutex
+	diffs []string
+}
+
+// Differences by running test, if reported to hooks of ResultHook.
+var diffLogs sync.Map
+
+// reportDiff reports difference d as error of t and records it for
+// Result.Diff.
+func reportDiff(t testing.TB, d string) {
+	t.Helper()
+	if v, ok := diffLogs.Load(t); ok {
+		l := v.(*diffLog)
+		l.mu.Lock()
+		l.diffs = append(l.diffs, d)
+		l.mu.Unlock()
+	}
+	t.Errorf("%s", d)
+}
+
+// trackResult arranges that hooks of ResultHook are called with the
+// result of test t, when it has finished. It returns a function, that
+// starts measuring the duration of the test.
+func (c *runConfig) trackResult(t *testing.T, r Result) func() {
+	if c.resultHooks == nil || os.Getenv(childEnv) != "" {
+		return func() {}
+	}
+	log := new(diffLog)
+	diffLogs.Store(testing.TB(t), log)
+	start := time.Now()
+	t.Cleanup(func() {
+		diffLogs.Delete(testing.TB(t))
+		r.Name = t.Name()
+		r.Duration = time.Since(start)
+		switch {
+		case t.Skipped():
+			r.Status = "skip"
+		case t.Failed():
+			r.Status = "fail"
+		default:
+			r.Status = "pass"
+		}
+		log.mu.Lock()
+		lines := strings.Split(strings.Join(log.diffs, "\n"), "\n")
+		log.mu.Unlock()
+		if len(lines) > resultDiffLines {
+			lines = append(lines[:resultDiffLines], "...")
+		}
+		r.Diff = strings.TrimSpace(strings.Join(lines, "\n"))
+		for _, fn := range c.resultHooks {
+			fn(r)
+		}
+	})
+	return func() { start = time.Now() }
+}
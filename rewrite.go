@@ -0,0 +1,253 @@
+package testtxt
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Rewriter changes the text of attributes in the source of a file of
+// test descriptions. Everything else, like comments, templates,
+// =SUBST= lines and white space, is left unchanged.
+type Rewriter struct {
+	src []byte
+}
+
+// NewRewriter returns a Rewriter for src.
+func NewRewriter(src []byte) *Rewriter {
+	return &Rewriter{src: src}
+}
+
+// Bytes returns the current source.
+func (r *Rewriter) Bytes() []byte {
+	return r.src
+}
+
+// SetAttr sets text of attribute attr in test with given title.
+// The test is found by the unexpanded text of its title attribute,
+// which is the first attribute of the file. A missing attribute is
+// added after the last attribute of test. Source is left unchanged, if
+// the attribute already has the given text.
+// Attributes, that use templates, =SUBST= or a mode like =OUTPUT:RE=,
+// can't be changed.
+func (r *Rewriter) SetAttr(title, attr, text string) error {
+	b, err := findAttr(r.src, title, attr)
+	if err != nil {
+		return err
+	}
+	if b.dynamic {
+		return fmt.Errorf("can't change =%s= of test %q,"+
+			" because it uses templates, =SUBST= or a mode", attr, title)
+	}
+	if b.start != b.end && b.text == text {
+		return nil
+	}
+	// Text without =END= would take following empty lines and
+	// comments as part of it.
+	rest, _, _ := bytes.Cut(r.src[b.end:], []byte("\n"))
+	hasEnd := b.hasEnd ||
+		b.end < len(r.src) && new(state).checkDef(string(rest)) == ""
+	def, err := formatAttr(attr, text, hasEnd)
+	if err != nil {
+		return fmt.Errorf("can't change =%s= of test %q: %v", attr, title, err)
+	}
+	if b.start > 0 && r.src[b.start-1] != '\n' {
+		def = "\n" + def
+	}
+	var out bytes.Buffer
+	out.Write(r.src[:b.start])
+	out.WriteString(def)
+	out.Write(r.src[b.end:])
+	r.src = out.Bytes()
+	return nil
+}
+
+// RenameAttr renames each definition of attribute old to new,
+// keeping a mode like in =OLD:RE=. Text of attributes and templates
+// and everything else is left unchanged.
+// It returns the number of renamed definitions. It is an error, if
+// some test already has attribute new.
+func (r *Rewriter) RenameAttr(old, new string) (int, error) {
+	if !isName(new) || new == "" {
+		return 0, fmt.Errorf("invalid name of attribute: %q", new)
+	}
+	s := &state{src: r.src, rest: r.src}
+	s.titleAttr = s.firstAttr()
+	var found []int
+	var seen map[string]bool
+	title := ""
+	for {
+		name, pos, err := s.readDef()
+		if err != nil {
+			return 0, err
+		}
+		if name == "" {
+			break
+		}
+		switch name {
+		case "TEMPL":
+			s.readTemplName()
+			s.readText()
+			s.applySubst("")
+			continue
+		case "SUBST":
+			s.skipLine()
+			continue
+		}
+		text, _ := s.readText()
+		if bytes.HasSuffix(r.src[:s.offset()], []byte("=END=")) {
+			s.skipLine()
+		}
+		s.applySubst(text)
+		base, _, _ := strings.Cut(name, ":")
+		if base == s.titleAttr {
+			seen = make(map[string]bool)
+			title = text
+		}
+		if seen[new] && base == old || seen[old] && base == new {
+			return 0, fmt.Errorf("test %q already has =%s=", title, new)
+		}
+		seen[base] = true
+		if base == old {
+			found = append(found, pos+1)
+		}
+	}
+	var out bytes.Buffer
+	prev := 0
+	for _, i := range found {
+		out.Write(r.src[prev:i])
+		out.WriteString(new)
+		prev = i + len(old)
+	}
+	out.Write(r.src[prev:])
+	r.src = out.Bytes()
+	return len(found), nil
+}
+
+// RewriteAttr sets text of attribute attr in test with given title in
+// file by Rewriter.SetAttr. File is only written, if it changes.
+func RewriteAttr(file, title, attr, text string) error {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	r := NewRewriter(src)
+	if err := r.SetAttr(title, attr, text); err != nil {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+	if bytes.Equal(src, r.src) {
+		return nil
+	}
+	return os.WriteFile(file, r.src, 0644)
+}
+
+// attrBlock locates definition of an attribute in source of a file.
+type attrBlock struct {
+	start, end int    // byte offsets of definition including its text
+	text       string // unexpanded text
+	dynamic    bool   // text uses templates, =SUBST= or mode
+	hasEnd     bool   // text is terminated by =END=
+}
+
+// findAttr locates definition of attribute attr in test with given
+// title in src. Title is compared with unexpanded text.
+// If the test has no such attribute, an empty block placed after the
+// last attribute of test is returned.
+func findAttr(src []byte, title, attr string) (*attrBlock, error) {
+	s := &state{src: src, rest: src}
+	s.titleAttr = s.firstAttr()
+	var last *attrBlock
+	found := false
+	for {
+		name, pos, err := s.readDef()
+		if err != nil {
+			return nil, err
+		}
+		if name == "" {
+			break
+		}
+		switch name {
+		case "TEMPL":
+			s.readTemplName()
+			s.readText()
+			s.applySubst("")
+			continue
+		case "SUBST":
+			s.skipLine()
+			continue
+		}
+		text, _ := s.readText()
+		b := &attrBlock{start: pos, text: text,
+			dynamic: strings.Contains(text, "[[")}
+		if bytes.HasSuffix(src[:s.offset()], []byte("=END=")) {
+			b.hasEnd = true
+			s.skipLine()
+		}
+		b.end = s.offset()
+		s.applySubst(text)
+		if s.offset() != b.end {
+			b.dynamic = true
+		}
+		if name == s.titleAttr {
+			if found {
+				break
+			}
+			found = text == title
+		}
+		if !found {
+			continue
+		}
+		if base, mode, _ := strings.Cut(name, ":"); base == attr {
+			if mode != "" {
+				b.dynamic = true
+			}
+			return b, nil
+		}
+		last = b
+		// Insert behind substitutions.
+		last.end = s.offset()
+	}
+	if !found {
+		return nil, fmt.Errorf("missing test with =%s=%s", s.titleAttr, title)
+	}
+	// Inserted text with multiple lines must be terminated by =END=,
+	// otherwise following comments would be taken as part of it.
+	return &attrBlock{start: last.end, end: last.end, hasEnd: true}, nil
+}
+
+// FormatAttr returns the definition of attribute name with given text
+// in the format read by ParseFile. Name may have a mode, like
+// "OUTPUT:RE". Text with multiple lines is terminated by =END=.
+// It is an error, if text would be read differently: text of a single
+// line with leading or trailing white space, text of multiple lines
+// without final newline or with a line, that would be taken as
+// definition. Template calls in text aren't escaped.
+func FormatAttr(name, text string) (string, error) {
+	return formatAttr(name, text, true)
+}
+
+// formatAttr returns definition of attribute name with given text in
+// the format read by ParseFile.
+func formatAttr(name, text string, hasEnd bool) (string, error) {
+	def := "=" + name + "="
+	if text != "" && !strings.Contains(text, "\n") &&
+		strings.TrimSpace(text) == text {
+		return def + text + "\n", nil
+	}
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		if !strings.Contains(text, "\n") {
+			return "", fmt.Errorf(
+				"text of single line must not start or end with white space")
+		}
+		return "", fmt.Errorf("text with multiple lines must end with newline")
+	}
+	if err := checkDefLines(text); err != nil {
+		return "", err
+	}
+	def += "\n" + text
+	if hasEnd {
+		def += "=END=\n"
+	}
+	return def, nil
+}
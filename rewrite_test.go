@@ -0,0 +1,320 @@
+package testtxt
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSetAttr(t *testing.T) {
+	tests := []struct {
+		name        string
+		src         string
+		title, attr string
+		text        string
+		want        string
+		err         string
+	}{
+		{
+			name:  "single line",
+			src:   "=TITLE=a\n=OUTPUT=x\n\n=TITLE=b\n=OUTPUT=x\n",
+			title: "b",
+			attr:  "OUTPUT",
+			text:  "y",
+			want:  "=TITLE=a\n=OUTPUT=x\n\n=TITLE=b\n=OUTPUT=y\n",
+		},
+		{
+			name:  "single line to multi line",
+			src:   "=TITLE=a\n=OUTPUT=x\n=INPUT=i\n",
+			title: "a",
+			attr:  "OUTPUT",
+			text:  "y\nz\n",
+			want:  "=TITLE=a\n=OUTPUT=\ny\nz\n=INPUT=i\n",
+		},
+		{
+			name:  "single line to multi line before empty line",
+			src:   "=TITLE=a\n=OUTPUT=x\n\n=TITLE=b\n",
+			title: "a",
+			attr:  "OUTPUT",
+			text:  "y\nz\n",
+			want:  "=TITLE=a\n=OUTPUT=\ny\nz\n=END=\n\n=TITLE=b\n",
+		},
+		{
+			name:  "single line to multi line before comment",
+			src:   "=TITLE=a\n=OUTPUT=x\n# c\n=TITLE=b\n",
+			title: "a",
+			attr:  "OUTPUT",
+			text:  "y\n",
+			want:  "=TITLE=a\n=OUTPUT=\ny\n=END=\n# c\n=TITLE=b\n",
+		},
+		{
+			name:  "single line to multi line at end of file",
+			src:   "=TITLE=a\n=OUTPUT=x\n",
+			title: "a",
+			attr:  "OUTPUT",
+			text:  "y\n",
+			want:  "=TITLE=a\n=OUTPUT=\ny\n",
+		},
+		{
+			name:  "multi line without END",
+			src:   "=TITLE=a\n=OUTPUT=\nx\n\n=TITLE=b\n",
+			title: "a",
+			attr:  "OUTPUT",
+			text:  "y\n",
+			want:  "=TITLE=a\n=OUTPUT=\ny\n=TITLE=b\n",
+		},
+		{
+			name:  "keep END",
+			src:   "=TITLE=a\n=OUTPUT=\nx\n=END=\n# c\n",
+			title: "a",
+			attr:  "OUTPUT",
+			text:  "y\n",
+			want:  "=TITLE=a\n=OUTPUT=\ny\n=END=\n# c\n",
+		},
+		{
+			name:  "unchanged",
+			src:   "=TITLE=a\n=OUTPUT=  x\n",
+			title: "a",
+			attr:  "OUTPUT",
+			text:  "x",
+			want:  "=TITLE=a\n=OUTPUT=  x\n",
+		},
+		{
+			name:  "insert single line",
+			src:   "=TITLE=a\n=INPUT=i\n\n=TITLE=b\n",
+			title: "a",
+			attr:  "OUTPUT",
+			text:  "o",
+			want:  "=TITLE=a\n=INPUT=i\n=OUTPUT=o\n\n=TITLE=b\n",
+		},
+		{
+			name:  "insert multi line with END",
+			src:   "=TITLE=a\n=INPUT=i\n# comment of b\n=TITLE=b\n",
+			title: "a",
+			attr:  "OUTPUT",
+			text:  "o\n",
+			want: "=TITLE=a\n=INPUT=i\n=OUTPUT=\no\n=END=\n" +
+				"# comment of b\n=TITLE=b\n",
+		},
+		{
+			name:  "insert after substitution",
+			src:   "=TITLE=a\n=INPUT=i\n=SUBST=/i/j/\n",
+			title: "a",
+			attr:  "OUTPUT",
+			text:  "o",
+			want:  "=TITLE=a\n=INPUT=i\n=SUBST=/i/j/\n=OUTPUT=o\n",
+		},
+		{
+			name:  "insert at end without newline",
+			src:   "=TITLE=a\n=INPUT=i",
+			title: "a",
+			attr:  "OUTPUT",
+			text:  "o",
+			want:  "=TITLE=a\n=INPUT=i\n=OUTPUT=o\n",
+		},
+		{
+			name:  "missing test",
+			src:   "=TITLE=a\n",
+			title: "b",
+			attr:  "OUTPUT",
+			text:  "o",
+			err:   "missing test with =TITLE=b",
+		},
+		{
+			name:  "mode",
+			src:   "=TITLE=a\n=OUTPUT:RE=x\n",
+			title: "a",
+			attr:  "OUTPUT",
+			text:  "o",
+			err: `can't change =OUTPUT= of test "a",` +
+				" because it uses templates, =SUBST= or a mode",
+		},
+		{
+			name:  "template",
+			src:   "=TEMPL=t\nx\n=END=\n=TITLE=a\n=OUTPUT=[[t]]\n",
+			title: "a",
+			attr:  "OUTPUT",
+			text:  "o",
+			err: `can't change =OUTPUT= of test "a",` +
+				" because it uses templates, =SUBST= or a mode",
+		},
+		{
+			name:  "substitution",
+			src:   "=TITLE=a\n=OUTPUT=x\n=SUBST=/x/y/\n",
+			title: "a",
+			attr:  "OUTPUT",
+			text:  "o",
+			err: `can't change =OUTPUT= of test "a",` +
+				" because it uses templates, =SUBST= or a mode",
+		},
+		{
+			name:  "definition in text",
+			src:   "=TITLE=a\n=OUTPUT=x\n",
+			title: "a",
+			attr:  "OUTPUT",
+			text:  "=END=\n",
+			err: `can't change =OUTPUT= of test "a":` +
+				" line would be taken as =END=: =END=",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRewriter([]byte(tc.src))
+			err := r.SetAttr(tc.title, tc.attr, tc.text)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("got error %v, want %s", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := cmp.Diff(tc.want, string(r.Bytes())); d != "" {
+				t.Error(d)
+			}
+			// Result must be parsed with new text.
+			l, err := ParseTests(writeTemp(t, "x.t", string(r.Bytes())))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, test := range l {
+				if test.Title == tc.title {
+					if got, _ := test.Get(tc.attr); got != tc.text {
+						t.Errorf("parsed %q, want %q", got, tc.text)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestFormatAttr(t *testing.T) {
+	tests := []struct {
+		name, text string
+		want       string
+		err        string
+	}{
+		{"A", "x", "=A=x\n", ""},
+		{"A", "=x", "=A==x\n", ""},
+		{"A:RE", "x", "=A:RE=x\n", ""},
+		{"A", "", "=A=\n=END=\n", ""},
+		{"A", "x\n", "=A=\nx\n=END=\n", ""},
+		{"A", "x\ny\n", "=A=\nx\ny\n=END=\n", ""},
+		{"A", " x", "",
+			"text of single line must not start or end with white space"},
+		{"A", "x\ny", "", "text with multiple lines must end with newline"},
+		{"A", "x\n=B=y\n", "", "line would be taken as =B=: =B=y"},
+	}
+	for _, tc := range tests {
+		got, err := FormatAttr(tc.name, tc.text)
+		if tc.err != "" {
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("%q: got error %v, want %s", tc.text, err, tc.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", tc.text, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%q: got %q, want %q", tc.text, got, tc.want)
+		}
+		// Text must be read back unchanged.
+		l, err := ParseTests(writeTemp(t, "x.t", "=TITLE=t\n"+got))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a, _ := l[0].attr(tc.name[:1]); a.Text != tc.text {
+			t.Errorf("%q: read back as %q", tc.text, a.Text)
+		}
+	}
+}
+
+func TestRenameAttr(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		old, new string
+		want     string
+		n        int
+		err      string
+	}{
+		{
+			name: "rename with mode",
+			src:  "=TITLE=a\n=OUT=x\n\n=TITLE=b\n=OUT:RE=y\n=SUBST=/y/z/\n",
+			old:  "OUT",
+			new:  "OUTPUT",
+			want: "=TITLE=a\n=OUTPUT=x\n\n=TITLE=b\n=OUTPUT:RE=y\n=SUBST=/y/z/\n",
+			n:    2,
+		},
+		{
+			name: "text and templates unchanged",
+			src: "=TEMPL=t\nx =OUT=\n=END=\n" +
+				"=TITLE=a\n=IN=\nin =OUT= text\n=END=\n=OUT=[[t]]\n",
+			old: "OUT",
+			new: "OUTPUT",
+			want: "=TEMPL=t\nx =OUT=\n=END=\n" +
+				"=TITLE=a\n=IN=\nin =OUT= text\n=END=\n=OUTPUT=[[t]]\n",
+			n: 1,
+		},
+		{
+			name: "rename title",
+			src:  "=TITLE=a\n\n=TITLE=b\n",
+			old:  "TITLE",
+			new:  "NAME",
+			want: "=NAME=a\n\n=NAME=b\n",
+			n:    2,
+		},
+		{
+			name: "not found",
+			src:  "=TITLE=a\n=IN=x\n",
+			old:  "OUT",
+			new:  "OUTPUT",
+			want: "=TITLE=a\n=IN=x\n",
+		},
+		{
+			name: "already defined",
+			src:  "=TITLE=a\n=OUT=x\n=OUTPUT=y\n",
+			old:  "OUT",
+			new:  "OUTPUT",
+			err:  `test "a" already has =OUTPUT=`,
+		},
+		{
+			name: "already defined before",
+			src:  "=TITLE=a\n=OUTPUT=y\n=OUT=x\n",
+			old:  "OUT",
+			new:  "OUTPUT",
+			err:  `test "a" already has =OUTPUT=`,
+		},
+		{
+			name: "invalid name",
+			src:  "=TITLE=a\n",
+			old:  "OUT",
+			new:  "a b",
+			err:  `invalid name of attribute: "a b"`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRewriter([]byte(tc.src))
+			n, err := r.RenameAttr(tc.old, tc.new)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("got error %v, want %s", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != tc.n {
+				t.Errorf("renamed %d times, want %d", n, tc.n)
+			}
+			if d := cmp.Diff(tc.want, string(r.Bytes())); d != "" {
+				t.Error(d)
+			}
+		})
+	}
+}
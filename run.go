@@ -0,0 +1,694 @@
+package testtxt
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"os/exec"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// RunOption changes the behaviour of Run.
+type RunOption func(*runConfig)
+
+type runConfig struct {
+	sequential  bool
+	sanitize    bool
+	timeout     time.Duration
+	parseOpts   []Option
+	compareOpts []CompareOption
+	input       bool
+	chdir       bool
+	prepareOpts []PrepareOption
+	beforeEach  []func(testing.TB, any)
+	afterEach   []func(testing.TB, any)
+	beforeFile  []func(testing.TB, any)
+	afterFile   []func(testing.TB, any)
+	flakyHooks  []func(testing.TB, any, int)
+	resultHooks []func(Result)
+	shuffle     bool
+	tags        string
+	title       string
+}
+
+func newRunConfig(opts []RunOption) *runConfig {
+	c := new(runConfig)
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+// Sequential runs tests one after another instead of in parallel.
+func Sequential() RunOption {
+	return func(c *runConfig) { c.sequential = true }
+}
+
+// SanitizeTitles replaces characters of titles, that are not letters,
+// digits, '.', '-' or '_', by '_' in names of subtests.
+func SanitizeTitles() RunOption {
+	return func(c *runConfig) { c.sanitize = true }
+}
+
+// Timeout sets the timeout of each test, that has no value in field
+// Timeout of its description.
+func Timeout(d time.Duration) RunOption {
+	return func(c *runConfig) { c.timeout = d }
+}
+
+// Shuffle runs tests in random order to find dependencies between
+// tests. The seed is logged and can be given by environment variable
+// TESTTXT_SHUFFLE to reproduce that order. Shuffling is enabled by
+// TESTTXT_SHUFFLE as well, with value "on" or a seed, even without
+// this option. Value "off" disables shuffling.
+func Shuffle() RunOption {
+	return func(c *runConfig) { c.shuffle = true }
+}
+
+// SelectTags runs only tests, whose field Tags, i.e. attribute =TAGS=
+// with whitespace separated tags, matches expression expr.
+// The expression combines tags by "&&", "||", "!" and parentheses,
+// e.g. "ipv6 && !slow". An expression in environment variable
+// TESTTXT_RUN is applied additionally.
+func SelectTags(expr string) RunOption {
+	return func(c *runConfig) { c.tags = expr }
+}
+
+// SelectTitle runs only tests, whose title matches regular expression
+// re. Unlike -run of go test, the unchanged title is matched.
+// An expression in environment variable TESTTXT_TITLE is applied
+// additionally.
+func SelectTitle(re string) RunOption {
+	return func(c *runConfig) { c.title = re }
+}
+
+// ParseOptions passes options to ParseFile.
+func ParseOptions(opts ...Option) RunOption {
+	return func(c *runConfig) { c.parseOpts = append(c.parseOpts, opts...) }
+}
+
+// FlakyHook adds fn, that is called when a test with =FLAKY= has
+// passed only after retry attempts. Fn gets a pointer to the
+// description of the test and the number of retries.
+func FlakyHook(fn func(t testing.TB, descr any, retries int)) RunOption {
+	return func(c *runConfig) { c.flakyHooks = append(c.flakyHooks, fn) }
+}
+
+// PrepareInput creates the files of field Input of each test, i.e.
+// attribute =INPUT= in the format of input of PrepareInDir, in a
+// temporary directory, before hooks and fn are called. The directory
+// is available by InputDir. Options are passed to Prepare.
+func PrepareInput(opts ...PrepareOption) RunOption {
+	return func(c *runConfig) {
+		c.input = true
+		c.prepareOpts = append(c.prepareOpts, opts...)
+	}
+}
+
+// Chdir is like PrepareInput and additionally changes the current
+// directory to the directory of =INPUT= while a test is run. The
+// previous directory is restored when the test has finished.
+// Since the current directory is shared by the whole process, tests
+// aren't run in parallel then.
+func Chdir(opts ...PrepareOption) RunOption {
+	return func(c *runConfig) {
+		c.input = true
+		c.chdir = true
+		c.prepareOpts = append(c.prepareOpts, opts...)
+	}
+}
+
+// Directories of =INPUT= by running test, set by options PrepareInput
+// and Chdir.
+var inputDirs sync.Map
+
+// InputDir returns the directory of =INPUT= of test t, that has been
+// created by option PrepareInput or Chdir of Run. It returns the empty
+// string for other tests.
+func InputDir(t testing.TB) string {
+	dir, _ := inputDirs.Load(t)
+	s, _ := dir.(string)
+	return s
+}
+
+// BeforeEach adds fn, that is called in each test before it is run.
+// Fn gets a pointer to the description of the test, that may be
+// modified by fn.
+func BeforeEach(fn func(t testing.TB, descr any)) RunOption {
+	return func(c *runConfig) { c.beforeEach = append(c.beforeEach, fn) }
+}
+
+// AfterEach adds fn, that is called with a pointer to the description
+// when a test has finished, even if it failed.
+// Hooks are called in reverse order, like functions of t.Cleanup.
+func AfterEach(fn func(t testing.TB, descr any)) RunOption {
+	return func(c *runConfig) { c.afterEach = append(c.afterEach, fn) }
+}
+
+// BeforeFile adds fn, that is called with the slice of all parsed
+// descriptions, before the first test of file is run.
+// Hooks of BeforeFile and AfterFile are called in the subprocess, that
+// runs a test with =XFAIL= or =FLAKY=, as well.
+func BeforeFile(fn func(t testing.TB, l any)) RunOption {
+	return func(c *runConfig) { c.beforeFile = append(c.beforeFile, fn) }
+}
+
+// AfterFile adds fn, that is called with the slice of all parsed
+// descriptions, when all tests of file, including parallel ones, have
+// finished.
+func AfterFile(fn func(t testing.TB, l any)) RunOption {
+	return func(c *runConfig) { c.afterFile = append(c.afterFile, fn) }
+}
+
+// Run parses file into l like ParseFile and runs fn for each test as
+// subtest of t, named by title of the test. Parse errors are reported
+// once as fatal error of t.
+// Parameter fn must be a function with parameters *testing.T and the
+// element type of l or a pointer to it.
+// Tests are run in parallel, unless option Sequential is given.
+// A test with attribute =SERIAL=, i.e. a field Serial of type bool or
+// string, isn't run in parallel. All such tests are run one after
+// another, before parallel tests of file are started.
+//
+// A field Env of type map[string]string collects attributes =ENV_*=,
+// e.g. =ENV_HOME=/tmp sets key "HOME". These variables are set by
+// t.Setenv for the test, before hooks and fn are called. Since t.Setenv
+// can't be used in parallel tests, a test with variables is run like a
+// test with =SERIAL=.
+//
+// Options PrepareInput and Chdir create the files of =INPUT= for each
+// test, see InputDir.
+// A test fails, if it runs longer than given by a field Timeout of type
+// time.Duration, e.g. from attribute =TIMEOUT=1s, or by option Timeout.
+// Fn is then run on a separate goroutine. It should stop, when the
+// context returned by Context is done, since the test finishes at
+// once, without waiting for fn.
+//
+// Fields Skip, Only and Xfail of type bool or string are interpreted
+// as follows, where a string field is set, if it is not empty:
+//   - =SKIP= skips the test, using the text as reason.
+//   - =ONLY= runs only tests with this attribute, others are left out.
+//   - =XFAIL= expects the test to fail. The test is run again in a
+//     subprocess and passes, if the subprocess fails.
+//
+// A test with a field Flaky of type int, e.g. from =FLAKY=3, is run in
+// a subprocess and retried up to that many times on failure. Passing
+// on retry is logged and reported to hooks of option FlakyHook.
+func Run(t *testing.T, file string, l any, fn any, opts ...RunOption) {
+	t.Helper()
+	c := newRunConfig(opts)
+	if err := ParseFile(file, l, c.parseOpts...); err != nil {
+		t.Fatal(err)
+	}
+	slice := reflect.ValueOf(l).Elem()
+	f := reflect.ValueOf(fn)
+	elType := slice.Type().Elem()
+	ft := f.Type()
+	if ft.Kind() != reflect.Func || ft.NumIn() != 2 || ft.NumOut() != 0 ||
+		ft.In(0) != reflect.TypeOf((*testing.T)(nil)) ||
+		ft.In(1) != elType && ft.In(1) != reflect.PointerTo(elType) {
+		t.Fatalf("expecting func(*testing.T, %v), got %v", elType, ft)
+	}
+	c.runAll(t, slice, func(t *testing.T, el reflect.Value) {
+		arg := el
+		if ft.In(1).Kind() == reflect.Pointer {
+			arg = el.Addr()
+		}
+		f.Call([]reflect.Value{reflect.ValueOf(t), arg})
+	})
+}
+
+// RunTests parses file into a slice of T like ParseFile and runs fn
+// for each test as subtest of t like Run.
+func RunTests[T any](t *testing.T, file string, fn func(*testing.T, T),
+	opts ...RunOption,
+) {
+	t.Helper()
+	c := newRunConfig(opts)
+	var l []T
+	if err := ParseFile(file, &l, c.parseOpts...); err != nil {
+		t.Fatal(err)
+	}
+	c.runAll(t, reflect.ValueOf(l), func(t *testing.T, el reflect.Value) {
+		fn(t, el.Interface().(T))
+	})
+}
+
+// runAll runs body for each element of slice as subtest of t.
+func (c *runConfig) runAll(t *testing.T, slice reflect.Value,
+	body func(*testing.T, reflect.Value),
+) {
+	t.Helper()
+	c.runFileHooks(t, slice)
+	names := c.testNames(slice)
+	only := hasOnly(slice)
+	selected := c.selector(t)
+	for _, i := range c.order(t, slice.Len()) {
+		name := names[i]
+		el := slice.Index(i)
+		if !selected(el) {
+			continue
+		}
+		if only {
+			if on, _ := attrFlag(el, "ONLY"); !on {
+				continue
+			}
+		}
+		t.Run(name, func(t *testing.T) {
+			start := c.trackResult(t, c.result(el))
+			if skip, reason := attrFlag(el, "SKIP"); skip {
+				if reason == "" {
+					reason = "skipped by =SKIP="
+				}
+				t.Skip(reason)
+			}
+			env := attrEnv(el)
+			serial, _ := attrFlag(el, "SERIAL")
+			if !serial && len(env) == 0 && !c.chdir && !c.sequential {
+				t.Parallel()
+			}
+			start()
+			if os.Getenv(childEnv) != t.Name() {
+				if xfail, _ := attrFlag(el, "XFAIL"); xfail {
+					runXFail(t)
+					return
+				}
+				if f := el.FieldByName("Flaky"); f.IsValid() &&
+					f.Kind() == reflect.Int && f.Int() > 0 {
+					c.runFlaky(t, el, int(f.Int()))
+					return
+				}
+			}
+			Setenv(t, env)
+			c.prepareInput(t, el)
+			c.runEachHooks(t, el)
+			c.runBody(t, el, body)
+		})
+	}
+}
+
+// selector returns a function, that reports whether a test is
+// selected by options SelectTags, SelectTitle and environment
+// variables TESTTXT_RUN, TESTTXT_TITLE.
+func (c *runConfig) selector(t testing.TB) func(el reflect.Value) bool {
+	t.Helper()
+	var exprs []tagExpr
+	for _, s := range []string{c.tags, os.Getenv("TESTTXT_RUN")} {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		e, err := parseTagExpr(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		exprs = append(exprs, e)
+	}
+	var res []*regexp.Regexp
+	for _, s := range []string{c.title, os.Getenv("TESTTXT_TITLE")} {
+		if s == "" {
+			continue
+		}
+		re, err := regexp.Compile(s)
+		if err != nil {
+			t.Fatalf("invalid title pattern: %v", err)
+		}
+		res = append(res, re)
+	}
+	return func(el reflect.Value) bool {
+		if exprs != nil {
+			tags := make(map[string]bool)
+			for _, tag := range attrTags(el) {
+				tags[tag] = true
+			}
+			for _, e := range exprs {
+				if !e(tags) {
+					return false
+				}
+			}
+		}
+		title := fmt.Sprint(el.Field(0).Interface())
+		for _, re := range res {
+			if !re.MatchString(title) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// result returns a Result with title and tags of el.
+func (c *runConfig) result(el reflect.Value) Result {
+	return Result{
+		Title: fmt.Sprint(el.Field(0).Interface()),
+		Tags:  attrTags(el),
+	}
+}
+
+// attrTags returns the whitespace separated tags of field Tags of el.
+func attrTags(el reflect.Value) []string {
+	if f := el.FieldByName("Tags"); f.IsValid() && f.Kind() == reflect.String {
+		return strings.Fields(f.String())
+	}
+	return nil
+}
+
+// order returns indexes of n tests in the order, they are run.
+// Order is shuffled if requested by option Shuffle or environment
+// variable TESTTXT_SHUFFLE.
+func (c *runConfig) order(t testing.TB, n int) []int {
+	t.Helper()
+	shuffle := c.shuffle
+	seed := time.Now().UnixNano()
+	switch v := os.Getenv("TESTTXT_SHUFFLE"); v {
+	case "":
+	case "off":
+		shuffle = false
+	case "on":
+		shuffle = true
+	default:
+		var err error
+		if seed, err = strconv.ParseInt(v, 10, 64); err != nil {
+			t.Fatalf("invalid value of TESTTXT_SHUFFLE: %q", v)
+		}
+		shuffle = true
+	}
+	if !shuffle {
+		l := make([]int, n)
+		for i := range l {
+			l[i] = i
+		}
+		return l
+	}
+	t.Logf("shuffled order of tests, reproduce with TESTTXT_SHUFFLE=%d", seed)
+	return rand.New(rand.NewSource(seed)).Perm(n)
+}
+
+// runFileHooks calls hooks of BeforeFile with slice and registers
+// hooks of AfterFile as cleanup functions of t.
+func (c *runConfig) runFileHooks(t testing.TB, slice reflect.Value) {
+	t.Helper()
+	for _, fn := range c.beforeFile {
+		fn(t, slice.Interface())
+	}
+	for _, fn := range c.afterFile {
+		fn := fn
+		t.Cleanup(func() { fn(t, slice.Interface()) })
+	}
+}
+
+// runEachHooks calls hooks of BeforeEach with a pointer to el and
+// registers hooks of AfterEach as cleanup functions of t.
+func (c *runConfig) runEachHooks(t testing.TB, el reflect.Value) {
+	t.Helper()
+	for _, fn := range c.beforeEach {
+		fn(t, el.Addr().Interface())
+	}
+	for _, fn := range c.afterEach {
+		fn := fn
+		t.Cleanup(func() { fn(t, el.Addr().Interface()) })
+	}
+}
+
+// prepareInput creates files of field Input of el for test t, if
+// requested by options PrepareInput or Chdir.
+func (c *runConfig) prepareInput(t testing.TB, el reflect.Value) {
+	t.Helper()
+	if !c.input {
+		return
+	}
+	dir := t.TempDir()
+	if f := el.FieldByName("Input"); f.IsValid() &&
+		f.Kind() == reflect.String && f.String() != "" {
+		dir = Prepare(t, "", f.String(), c.prepareOpts...).Dir
+	}
+	inputDirs.Store(t, dir)
+	t.Cleanup(func() { inputDirs.Delete(t) })
+	if !c.chdir {
+		return
+	}
+	prev, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := os.Chdir(prev); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+// attrEnv returns the map of field Env of el, that collects
+// attributes =ENV_*=.
+func attrEnv(el reflect.Value) map[string]string {
+	if f := el.FieldByName("Env"); f.IsValid() {
+		if env, ok := f.Interface().(map[string]string); ok {
+			return env
+		}
+	}
+	return nil
+}
+
+// hasOnly reports whether some element of slice has attribute =ONLY=.
+func hasOnly(slice reflect.Value) bool {
+	for i := 0; i < slice.Len(); i++ {
+		if on, _ := attrFlag(slice.Index(i), "ONLY"); on {
+			return true
+		}
+	}
+	return false
+}
+
+// attrFlag reports whether the field of el for attribute attr is set.
+// A bool field is set if true, a string field if not empty.
+// The text of a string field is returned as well.
+func attrFlag(el reflect.Value, attr string) (bool, string) {
+	for _, sf := range reflect.VisibleFields(el.Type()) {
+		if !sf.IsExported() || toSnakeCase(sf.Name) != attr {
+			continue
+		}
+		f := el.FieldByIndex(sf.Index)
+		switch f.Kind() {
+		case reflect.Bool:
+			return f.Bool(), ""
+		case reflect.String:
+			text := strings.TrimSpace(f.String())
+			return text != "", text
+		}
+	}
+	return false, ""
+}
+
+// Environment variable, that marks the subprocess of runChild with the
+// name of the test to run.
+const childEnv = "TESTTXT_CHILD"
+
+// runChild runs test t again in a subprocess of the test binary.
+// It returns the output of the subprocess and whether it succeeded.
+func runChild(t *testing.T) ([]byte, bool) {
+	t.Helper()
+	var parts []string
+	for _, p := range strings.Split(t.Name(), "/") {
+		parts = append(parts, "^"+regexp.QuoteMeta(p)+"$")
+	}
+	args := append(childFlags(),
+		"-test.run="+strings.Join(parts, "/"), "-test.v")
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), childEnv+"="+t.Name())
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		return out, true
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatal(err)
+	}
+	return out, false
+}
+
+// Flags of the test binary, that aren't passed to a subprocess of
+// runChild. They select other tests or write files, which would be
+// overwritten by the parent process.
+var noChildFlags = map[string]bool{
+	"test.run": true, "test.v": true, "test.list": true,
+	"test.bench": true, "test.fuzz": true, "test.fuzzworker": true,
+	"test.testlogfile": true, "test.coverprofile": true,
+	"test.cpuprofile": true, "test.memprofile": true,
+	"test.blockprofile": true, "test.mutexprofile": true,
+	"test.trace": true, "test.outputdir": true,
+}
+
+// childFlags returns the flags "test.*" of the test binary, that have
+// been set, as arguments for a subprocess of runChild. Coverage of the
+// subprocess is collected, if flag test.gocoverdir is set.
+func childFlags() []string {
+	var args []string
+	flag.Visit(func(f *flag.Flag) {
+		if strings.HasPrefix(f.Name, "test.") && !noChildFlags[f.Name] {
+			args = append(args, "-"+f.Name+"="+f.Value.String())
+		}
+	})
+	return args
+}
+
+// runXFail runs test t in a subprocess.
+// Test t fails, if the subprocess succeeds.
+func runXFail(t *testing.T) {
+	t.Helper()
+	out, ok := runChild(t)
+	if ok {
+		t.Errorf("test with =XFAIL= passed unexpectedly\n%s", out)
+		return
+	}
+	t.Logf("failed as expected by =XFAIL=\n%s", out)
+}
+
+// runFlaky runs test t with element el in a subprocess and retries up
+// to n times on failure. Test t fails, if all attempts fail.
+func (c *runConfig) runFlaky(t *testing.T, el reflect.Value, n int) {
+	t.Helper()
+	for retry := 0; ; retry++ {
+		out, ok := runChild(t)
+		if ok {
+			if retry > 0 {
+				t.Logf("passed on retry %d of =FLAKY=%d\n%s", retry, n, out)
+				for _, fn := range c.flakyHooks {
+					fn(t, el.Addr().Interface(), retry)
+				}
+			}
+			return
+		}
+		if retry == n {
+			t.Errorf("failed %d times with =FLAKY=%d\n%s", n+1, n, out)
+			return
+		}
+	}
+}
+
+// runBody runs body for test el. If the test has a timeout, body is
+// run on a separate goroutine. When the timeout is exceeded, the
+// context returned by Context is canceled and the test fails at once,
+// without waiting for body.
+func (c *runConfig) runBody(t *testing.T, el reflect.Value,
+	body func(*testing.T, reflect.Value),
+) {
+	t.Helper()
+	d := c.timeout
+	if f := el.FieldByName("Timeout"); f.IsValid() &&
+		f.Type() == durationType && f.Int() > 0 {
+		d = time.Duration(f.Int())
+	}
+	if d <= 0 {
+		body(t, el)
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	testContexts.Store(t, ctx)
+	done := make(chan struct{})
+	var panicked any
+	go func() {
+		defer func() {
+			// Is nil after runtime.Goexit, e.g. from t.FailNow.
+			panicked = recover()
+			cancel()
+			testContexts.Delete(t)
+			close(done)
+		}()
+		body(t, el)
+	}()
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-done:
+		if panicked != nil {
+			panic(panicked)
+		}
+	case <-timer.C:
+		cancel()
+		title := reflect.VisibleFields(el.Type())[0].Index
+		t.Fatalf("timeout exceeded after %v in test %v",
+			d, el.FieldByIndex(title).Interface())
+	}
+}
+
+// Contexts of running tests with timeout.
+var testContexts sync.Map
+
+// Context returns a context of test t, that is run by Run or RunTests.
+// If the test has a timeout, the context is canceled, when the timeout
+// is exceeded. The test fails in this case and finishes without waiting
+// for fn. Long running tests should hence stop, when the context is
+// done, and must not use t afterwards. Otherwise the context is never
+// canceled.
+func Context(t testing.TB) context.Context {
+	if ctx, found := testContexts.Load(t); found {
+		return ctx.(context.Context)
+	}
+	return context.Background()
+}
+
+// testNames returns unique names of subtests for elements of slice,
+// derived from their titles. Repeated titles get suffix "#2", "#3", ...
+func (c *runConfig) testNames(slice reflect.Value) []string {
+	title := reflect.VisibleFields(slice.Type().Elem())[0].Index
+	seen := make(map[string]int)
+	names := make([]string, slice.Len())
+	for i := range names {
+		name := fmt.Sprint(slice.Index(i).FieldByIndex(title).Interface())
+		if c.sanitize {
+			name = unsafeChars.ReplaceAllString(name, "_")
+		}
+		seen[name]++
+		if n := seen[name]; n > 1 {
+			name = fmt.Sprintf("%s#%d", name, n)
+		}
+		names[i] = name
+	}
+	return names
+}
+
+// RunBench parses file into a slice of T like ParseFile and runs fn for
+// each test as sub-benchmark of b, named like subtests of RunTests.
+// Helpers like PrepareTempDir accept testing.TB and can be used with b.
+// Attributes =SKIP= and =ONLY= and hooks are handled like in Run,
+// =XFAIL= is ignored.
+func RunBench[T any](b *testing.B, file string, fn func(*testing.B, T),
+	opts ...RunOption,
+) {
+	b.Helper()
+	c := newRunConfig(opts)
+	var l []T
+	if err := ParseFile(file, &l, c.parseOpts...); err != nil {
+		b.Fatal(err)
+	}
+	slice := reflect.ValueOf(l)
+	c.runFileHooks(b, slice)
+	only := hasOnly(slice)
+	for i, name := range c.testNames(slice) {
+		el := slice.Index(i)
+		if on, _ := attrFlag(el, "ONLY"); only && !on {
+			continue
+		}
+		b.Run(name, func(b *testing.B) {
+			if skip, reason := attrFlag(el, "SKIP"); skip {
+				if reason == "" {
+					reason = "skipped by =SKIP="
+				}
+				b.Skip(reason)
+			}
+			c.runEachHooks(b, el)
+			fn(b, el.Interface().(T))
+		})
+	}
+}
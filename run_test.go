@@ -0,0 +1,511 @@
+package testtxt
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type runDescr struct {
+	Title  string
+	Input  string
+	Output string
+}
+
+type flagDescr struct {
+	Title string
+	Input string
+	Tags  string
+	Skip  bool
+	Only  bool
+	Xfail bool
+}
+
+type skipDescr struct {
+	Title string
+	Skip  string
+}
+
+type flakyDescr struct {
+	Title string
+	Input string
+	Flaky int
+}
+
+type tagDescr struct {
+	Title string
+	Tags  string
+}
+
+type timeoutDescr struct {
+	Title   string
+	Timeout time.Duration
+}
+
+func TestRun(t *testing.T) {
+	file := writeTemp(t, "x.t",
+		"=TITLE=a\n=INPUT=1\n=OUTPUT=2\n\n=TITLE=b/c\n=INPUT=3\n")
+	var mu sync.Mutex
+	got := make(map[string]string)
+	record := func(t *testing.T, d runDescr) {
+		mu.Lock()
+		defer mu.Unlock()
+		got[t.Name()] = d.Input + d.Output
+	}
+	t.Run("value", func(t *testing.T) {
+		var l []runDescr
+		Run(t, file, &l, record)
+	})
+	t.Run("pointer", func(t *testing.T) {
+		var l []runDescr
+		Run(t, file, &l, func(t *testing.T, d *runDescr) {
+			d.Input += "!"
+			record(t, *d)
+		}, Sequential())
+		if len(l) != 2 || l[0].Input != "1!" {
+			t.Errorf("descriptions not changed by pointer: %v", l)
+		}
+	})
+	want := map[string]string{
+		"TestRun/value/a":     "12",
+		"TestRun/value/b/c":   "3",
+		"TestRun/pointer/a":   "1!2",
+		"TestRun/pointer/b/c": "3!",
+	}
+	if d := cmp.Diff(want, got); d != "" {
+		t.Error(d)
+	}
+}
+
+func TestRunTests(t *testing.T) {
+	file := writeTemp(t, "x.t",
+		"=TITLE=a b\n=INPUT=1\n\n=TITLE=a b\n=INPUT=2\n\n"+
+			"=TITLE=x/y?\n=INPUT=3\n")
+	tests := []struct {
+		name string
+		opts []RunOption
+		want map[string]string
+	}{
+		{
+			name: "unique names",
+			want: map[string]string{
+				"a_b": "1", "a_b#2": "2", "x/y?": "3",
+			},
+		},
+		{
+			name: "sanitized",
+			opts: []RunOption{SanitizeTitles()},
+			want: map[string]string{
+				"a_b": "1", "a_b#2": "2", "x_y_": "3",
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var mu sync.Mutex
+			got := make(map[string]string)
+			t.Run("run", func(t *testing.T) {
+				RunTests(t, file, func(t *testing.T, d runDescr) {
+					mu.Lock()
+					defer mu.Unlock()
+					name, _ := strings.CutPrefix(t.Name(), "TestRunTests/"+
+						strings.ReplaceAll(tc.name, " ", "_")+"/run/")
+					got[name] = d.Input
+				}, tc.opts...)
+			})
+			if d := cmp.Diff(tc.want, got); d != "" {
+				t.Error(d)
+			}
+		})
+	}
+}
+
+// Environment variable, that marks the subprocess started by outcome.
+const outcomeEnv = "TESTTXT_OUTCOME"
+
+// event writes a line to stdout, that is collected by outcome.
+func event(format string, args ...any) {
+	fmt.Printf("event: "+format+"\n", args...)
+}
+
+var resultRe = regexp.MustCompile(`^( *)--- (PASS|FAIL|SKIP): (\S+)`)
+
+// outcome runs test t again in a subprocess with environment variable
+// outcomeEnv set and returns the results of t and its subtests
+// together with lines written by event, in order of output.
+// Results are given as status and name of subtest relative to t, e.g.
+// "PASS a" or "FAIL" for t itself. Output of nested subprocesses,
+// e.g. of =XFAIL=, is left out.
+// Second value is the complete output.
+func outcome(t *testing.T) ([]string, string) {
+	t.Helper()
+	var parts []string
+	for _, p := range strings.Split(t.Name(), "/") {
+		parts = append(parts, "^"+regexp.QuoteMeta(p)+"$")
+	}
+	cmd := exec.Command(os.Args[0],
+		"-test.run="+strings.Join(parts, "/"), "-test.v")
+	cmd.Env = append(os.Environ(), outcomeEnv+"=1")
+	out, _ := cmd.CombinedOutput()
+	var l []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if ev, found := strings.CutPrefix(line, "event: "); found {
+			l = append(l, ev)
+			continue
+		}
+		m := resultRe.FindStringSubmatch(line)
+		if m == nil || len(m[1]) != 4*strings.Count(m[3], "/") {
+			continue
+		}
+		if m[3] == t.Name() {
+			l = append(l, m[2])
+		} else if rel, found := strings.CutPrefix(m[3], t.Name()+"/"); found {
+			l = append(l, m[2]+" "+rel)
+		}
+	}
+	return l, string(out)
+}
+
+// TestRunOutcome checks results of tests, that may fail. Each case is
+// run in a subprocess by outcome.
+func TestRunOutcome(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		run  func(t *testing.T, file string)
+		want []string
+		// Fragments of output.
+		output []string
+		// Fragments, that must not occur in output.
+		absent []string
+	}{
+		{
+			name: "subtests",
+			src:  "=TITLE=a\n=INPUT=1\n\n=TITLE=b\n=INPUT=2\n",
+			run: func(t *testing.T, file string) {
+				var l []runDescr
+				Run(t, file, &l, func(t *testing.T, d runDescr) {
+					event("run %s", d.Title)
+					if d.Input == "2" {
+						t.Error("failed")
+					}
+				}, Sequential())
+			},
+			want: []string{"run a", "run b", "FAIL", "PASS a", "FAIL b"},
+		},
+		{
+			name: "invalid function",
+			src:  "=TITLE=a\n",
+			run: func(t *testing.T, file string) {
+				var l []runDescr
+				Run(t, file, &l, func(t *testing.T, s string) {})
+			},
+			want: []string{"FAIL"},
+			output: []string{"expecting func(*testing.T, testtxt.runDescr), " +
+				"got func(*testing.T, string)"},
+		},
+		{
+			name: "parse error",
+			src:  "=TITLE=a\n=UNKNOWN=x\n",
+			run: func(t *testing.T, file string) {
+				var l []runDescr
+				Run(t, file, &l, func(t *testing.T, d runDescr) {
+					event("run %s", d.Title)
+				})
+			},
+			want:   []string{"FAIL"},
+			output: []string{"x.t:2:1: unexpected =UNKNOWN= in test with =TITLE=a"},
+		},
+		{
+			name: "parse error of RunTests",
+			src:  "=TITLE=a\n=COUNT=x\n",
+			run: func(t *testing.T, file string) {
+				RunTests(t, file, func(t *testing.T, d struct {
+					Title string
+					Count int
+				}) {
+					event("run %s", d.Title)
+				})
+			},
+			want:   []string{"FAIL"},
+			output: []string{"x.t:2:8: invalid value for struct field \"Count\""},
+		},
+		{
+			name: "timeout",
+			src: "=TITLE=a\n=TIMEOUT=10ms\n\n=TITLE=b\n\n" +
+				"=TITLE=c\n=TIMEOUT=1h\n\n=TITLE=d\n=TIMEOUT=1h\n",
+			run: func(t *testing.T, file string) {
+				RunTests(t, file, func(t *testing.T, d timeoutDescr) {
+					switch d.Title {
+					case "a":
+						<-Context(t).Done()
+						event("canceled %s", d.Title)
+						select {}
+					case "b":
+						// Blocks without stopping at canceled context.
+						select {}
+					case "c":
+						t.Fatal("failed")
+					case "d":
+						t.Skip("skipped")
+					}
+				}, Timeout(20*time.Millisecond), Sequential())
+			},
+			want: []string{"canceled a", "FAIL", "FAIL a", "FAIL b",
+				"FAIL c", "SKIP d"},
+			output: []string{
+				"timeout exceeded after 10ms in test a",
+				"timeout exceeded after 20ms in test b",
+			},
+		},
+		{
+			name: "skip",
+			src:  "=TITLE=a\n=SKIP=\n\n=TITLE=b\n=SKIP=later\n\n=TITLE=c\n",
+			run: func(t *testing.T, file string) {
+				RunTests(t, file, func(t *testing.T, d skipDescr) {
+					event("run %s", d.Title)
+				}, Sequential())
+			},
+			// Empty string field doesn't skip.
+			want:   []string{"run a", "run c", "PASS", "PASS a", "SKIP b", "PASS c"},
+			output: []string{"later"},
+		},
+		{
+			name: "only",
+			src: "=TITLE=a\n=ONLY=\n\n=TITLE=b\n\n=TITLE=c\n=ONLY=\n\n" +
+				"=TITLE=d\n=ONLY=\n=SKIP=\n",
+			run: func(t *testing.T, file string) {
+				RunTests(t, file, func(t *testing.T, d flagDescr) {
+					event("run %s", d.Title)
+				}, Sequential())
+			},
+			want: []string{"run a", "run c", "PASS", "PASS a", "PASS c",
+				"SKIP d"},
+			output: []string{"skipped by =SKIP="},
+		},
+		{
+			name: "hooks",
+			src:  "=TITLE=a\n=INPUT=1\n\n=TITLE=b\n=INPUT=2\n",
+			run: func(t *testing.T, file string) {
+				each := func(name string) func(testing.TB, any) {
+					return func(t testing.TB, d any) {
+						event("%s %s", name, d.(*runDescr).Title)
+						d.(*runDescr).Input += name
+					}
+				}
+				var l []runDescr
+				Run(t, file, &l, func(t *testing.T, d runDescr) {
+					event("run %s %s", d.Title, d.Input)
+					if d.Title == "b" {
+						t.Fatal("failed")
+					}
+				}, Sequential(),
+					BeforeEach(each("B1")), BeforeEach(each("B2")),
+					AfterEach(each("A1")), AfterEach(each("A2")),
+					AfterFile(func(t testing.TB, l any) {
+						event("after file %v", l)
+					}))
+			},
+			want: []string{
+				"B1 a", "B2 a", "run a 1B1B2", "A2 a", "A1 a",
+				"B1 b", "B2 b", "run b 2B1B2", "A2 b", "A1 b",
+				"after file [{a 1B1B2A2A1 } {b 2B1B2A2A1 }]",
+				"FAIL", "PASS a", "FAIL b",
+			},
+		},
+		{
+			name: "xfail with file hooks",
+			src: "=TITLE=a\n=XFAIL=\n\n=TITLE=b\n=XFAIL=\n=INPUT=ok\n\n" +
+				"=TITLE=c\n",
+			run: func(t *testing.T, file string) {
+				RunTests(t, file, func(t *testing.T, d flagDescr) {
+					event("run %s", d.Title)
+					// Fails in subprocess, if hook has been called.
+					if d.Input == "ready" {
+						t.Error("failed")
+					}
+				}, Sequential(),
+					BeforeFile(func(t testing.TB, l any) {
+						event("before file")
+						for i, d := range l.([]flagDescr) {
+							if d.Input == "" {
+								l.([]flagDescr)[i].Input = "ready"
+							}
+						}
+					}),
+					AfterFile(func(t testing.TB, l any) {
+						event("after file")
+					}))
+			},
+			want: []string{"before file", "run c", "after file",
+				"FAIL", "PASS a", "FAIL b", "FAIL c"},
+			output: []string{"failed as expected by =XFAIL=",
+				"test with =XFAIL= passed unexpectedly"},
+		},
+		{
+			name: "flaky",
+			src:  "=TITLE=a\n=FLAKY=3\n\n=TITLE=b\n=FLAKY=1\n\n=TITLE=c\n=FLAKY=1\n",
+			run: func(t *testing.T, file string) {
+				// Attempts are counted in directory shared by subprocesses.
+				dir := os.Getenv("TESTTXT_FLAKY")
+				if dir == "" {
+					dir = t.TempDir()
+					t.Setenv("TESTTXT_FLAKY", dir)
+				}
+				RunTests(t, file, func(t *testing.T, d flakyDescr) {
+					if d.Input != "ready" {
+						t.Fatal("BeforeFile not called")
+					}
+					count := filepath.Join(dir, d.Title)
+					data, _ := os.ReadFile(count)
+					data = append(data, 'x')
+					if err := os.WriteFile(count, data, 0644); err != nil {
+						t.Fatal(err)
+					}
+					switch {
+					case d.Title == "a" && len(data) < 3, d.Title == "b":
+						t.Error("failed")
+					}
+				}, Sequential(),
+					BeforeFile(func(t testing.TB, l any) {
+						for i := range l.([]flakyDescr) {
+							l.([]flakyDescr)[i].Input = "ready"
+						}
+					}),
+					FlakyHook(func(t testing.TB, d any, retries int) {
+						event("flaky %s %d", d.(*flakyDescr).Title, retries)
+					}))
+			},
+			want: []string{"flaky a 2", "FAIL", "PASS a", "FAIL b", "PASS c"},
+			output: []string{"passed on retry 2 of =FLAKY=3",
+				"failed 2 times with =FLAKY=1"},
+		},
+		{
+			name: "select",
+			src: "=TITLE=a1\n=TAGS=net\tslow\n\n=TITLE=a2\n=TAGS=net\n\n" +
+				"=TITLE=b1\n=TAGS=net  ipv6\n\n=TITLE=b2\n",
+			run: func(t *testing.T, file string) {
+				t.Setenv("TESTTXT_RUN", "!slow")
+				t.Setenv("TESTTXT_TITLE", "2")
+				RunTests(t, file, func(t *testing.T, d tagDescr) {
+					event("run %s", d.Title)
+				}, Sequential(), SelectTags("net\t||\tipv6"), SelectTitle("^[ab]"))
+			},
+			want: []string{"run a2", "PASS", "PASS a2"},
+		},
+		{
+			name: "invalid selection",
+			src:  "=TITLE=a\n",
+			run: func(t *testing.T, file string) {
+				RunTests(t, file, func(t *testing.T, d tagDescr) {},
+					SelectTags("a ||"))
+			},
+			want:   []string{"FAIL"},
+			output: []string{`invalid tag expression "a ||": unexpected end`},
+		},
+		{
+			name: "result hook",
+			src: "=TITLE=a\n=TAGS=x y\n\n=TITLE=b\n=XFAIL=\n\n" +
+				"=TITLE=c\n=SKIP=\n\n=TITLE=d\n=INPUT=x\n",
+			run: func(t *testing.T, file string) {
+				RunTests(t, file, func(t *testing.T, d flagDescr) {
+					switch d.Title {
+					case "b":
+						t.Error("failed")
+					case "d":
+						Equal(t, "a\n", d.Input+"\n")
+					}
+				}, Sequential(), ResultHook(func(r Result) {
+					event("result %s %s %v %q %q",
+						r.Name[strings.LastIndex(r.Name, "/")+1:],
+						r.Status, r.Tags, r.Title, r.Diff)
+				}))
+			},
+			want: []string{
+				`result a pass [x y] "a" ""`,
+				`result b pass [] "b" ""`,
+				`result c skip [] "c" ""`,
+				`result d fail [] "d" "--- expected\n+++ got\n` +
+					`@@ -1 +1 @@\n-a\n+x"`,
+				"FAIL", "PASS a", "PASS b", "SKIP c", "FAIL d",
+			},
+			// Not reported by subprocess of =XFAIL=.
+			absent: []string{" event: result"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if os.Getenv(outcomeEnv) != "" {
+				tc.run(t, writeTemp(t, "x.t", tc.src))
+				return
+			}
+			got, out := outcome(t)
+			if d := cmp.Diff(tc.want, got); d != "" {
+				t.Errorf("%s\n%s", d, out)
+			}
+			for _, s := range tc.output {
+				if !strings.Contains(out, s) {
+					t.Errorf("missing %q in output\n%s", s, out)
+				}
+			}
+			for _, s := range tc.absent {
+				if strings.Contains(out, s) {
+					t.Errorf("unexpected %q in output\n%s", s, out)
+				}
+			}
+		})
+	}
+}
+
+func TestRunBench(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{
+			name: "all",
+			src:  "=TITLE=a\n=INPUT=1\n\n=TITLE=a\n=INPUT=2\n",
+			want: []string{"a:1", "a:2"},
+		},
+		{
+			name: "skip and only",
+			src: "=TITLE=a\n=ONLY=\n\n=TITLE=b\n\n" +
+				"=TITLE=c\n=ONLY=\n=SKIP=\n",
+			want: []string{"a:"},
+		},
+	}
+	type descr struct {
+		Title string
+		Input string
+		Only  bool
+		Skip  bool
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			file := writeTemp(t, "x.t", tc.src)
+			var got []string
+			seen := make(map[string]bool)
+			testing.Benchmark(func(b *testing.B) {
+				RunBench(b, file, func(b *testing.B, d descr) {
+					// Benchmarks run by testing.Benchmark have no name.
+					if v := d.Title + ":" + d.Input; !seen[v] {
+						seen[v] = true
+						got = append(got, v)
+					}
+				})
+			})
+			if d := cmp.Diff(tc.want, got); d != "" {
+				t.Error(d)
+			}
+		})
+	}
+}
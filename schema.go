@@ -0,0 +1,122 @@
+package testtxt
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// GenerateSchema returns a JSON Schema, draft 2020-12, describing test
+// descriptions of target in the JSON format of command
+// "testtxt convert -to json": an array of objects, that map names of
+// attributes to their text. Target is a struct, a slice of struct or
+// a pointer to one of these.
+//
+// Each struct field becomes a property with the name of its attribute.
+// Values are always strings; the format of int, bool and
+// time.Duration fields is given as pattern. A field of type Expected
+// gets a property for each mode, e.g. OUTPUT and OUTPUT:RE. A field
+// of type map[string]string gets a pattern property, e.g. ENV_* for
+// field Env. The title attribute is required.
+//
+// Struct tag `testtxt:"..."` adds comma separated options:
+//   - required: attribute is required.
+//   - enum=a|b|c: text of attribute must be one of given values.
+func GenerateSchema(target any) ([]byte, error) {
+	typ := reflect.TypeOf(target)
+	for typ != nil && (typ.Kind() == reflect.Pointer ||
+		typ.Kind() == reflect.Slice) {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil, targetError("expecting struct or slice of struct")
+	}
+	fields := reflect.VisibleFields(typ)
+	if len(fields) == 0 {
+		return nil, targetError("expecting struct with at least one field")
+	}
+	props := make(map[string]any)
+	patterns := make(map[string]any)
+	required := []string{toSnakeCase(fields[0].Name)}
+	for _, f := range fields {
+		if !f.IsExported() || f.Anonymous && f.Type.Kind() == reflect.Struct {
+			continue
+		}
+		name := toSnakeCase(f.Name)
+		prop, err := schemaProp(f)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case f.Type == expectedType:
+			for mode := range matchModes {
+				key := name
+				if mode != "" {
+					key += ":" + mode
+				}
+				props[key] = prop
+			}
+		case f.Type == reflect.TypeOf(map[string]string(nil)):
+			patterns["^"+regexp.QuoteMeta(name)+"_[A-Za-z0-9_]+$"] = prop
+		default:
+			props[name] = prop
+		}
+		for _, opt := range strings.Split(f.Tag.Get("testtxt"), ",") {
+			if opt == "required" && !slices.Contains(required, name) {
+				required = append(required, name)
+			}
+		}
+	}
+	item := map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"required":             required,
+		"additionalProperties": false,
+	}
+	if len(patterns) > 0 {
+		item["patternProperties"] = patterns
+	}
+	schema := map[string]any{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title":   typ.Name(),
+		"type":    "array",
+		"items":   item,
+	}
+	b, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// schemaProp returns the JSON Schema of the text of an attribute for
+// struct field f.
+func schemaProp(f reflect.StructField) (map[string]any, error) {
+	p := map[string]any{"type": "string"}
+	switch {
+	case f.Type == durationType:
+		p["pattern"] = `^[-+]?([0-9]*(\.[0-9]*)?(ns|us|µs|ms|s|m|h))+$|^0$`
+	case f.Type == expectedType,
+		f.Type == reflect.TypeOf(map[string]string(nil)):
+	default:
+		switch f.Type.Kind() {
+		case reflect.String:
+		case reflect.Int:
+			p["pattern"] = `^[-+]?[0-9]+$`
+		case reflect.Bool:
+			p["description"] = "flag, text is ignored"
+		default:
+			return nil, targetError(fmt.Sprintf(
+				"unexpected type %v of struct field %q", f.Type.Kind(), f.Name))
+		}
+	}
+	for _, opt := range strings.Split(f.Tag.Get("testtxt"), ",") {
+		if values, found := strings.CutPrefix(opt, "enum="); found {
+			p["enum"] = strings.Split(values, "|")
+		}
+	}
+	return p, nil
+}
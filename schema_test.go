@@ -0,0 +1,100 @@
+package testtxt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGenerateSchema(t *testing.T) {
+	type descr struct {
+		Title   string
+		Input   string
+		Output  Expected
+		Count   int
+		Only    bool
+		Timeout time.Duration
+		Env     map[string]string
+		Kind    string `testtxt:"required,enum=a|b"`
+	}
+	got, err := GenerateSchema(&[]descr{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "items": {
+    "additionalProperties": false,
+    "patternProperties": {
+      "^ENV_[A-Za-z0-9_]+$": {
+        "type": "string"
+      }
+    },
+    "properties": {
+      "COUNT": {
+        "pattern": "^[-+]?[0-9]+$",
+        "type": "string"
+      },
+      "INPUT": {
+        "type": "string"
+      },
+      "KIND": {
+        "enum": [
+          "a",
+          "b"
+        ],
+        "type": "string"
+      },
+      "ONLY": {
+        "description": "flag, text is ignored",
+        "type": "string"
+      },
+      "OUTPUT": {
+        "type": "string"
+      },
+      "OUTPUT:CONTAINS": {
+        "type": "string"
+      },
+      "OUTPUT:RE": {
+        "type": "string"
+      },
+      "TIMEOUT": {
+        "pattern": "^[-+]?([0-9]*(\\.[0-9]*)?(ns|us|µs|ms|s|m|h))+$|^0$",
+        "type": "string"
+      },
+      "TITLE": {
+        "type": "string"
+      }
+    },
+    "required": [
+      "TITLE",
+      "KIND"
+    ],
+    "type": "object"
+  },
+  "title": "descr",
+  "type": "array"
+}
+`
+	if d := cmp.Diff(want, string(got)); d != "" {
+		t.Error(d)
+	}
+}
+
+func TestGenerateSchemaError(t *testing.T) {
+	for _, tc := range []struct {
+		target any
+		want   string
+	}{
+		{"x", "expecting struct or slice of struct"},
+		{&struct{}{}, "expecting struct with at least one field"},
+		{&struct{ Title []string }{},
+			`unexpected type slice of struct field "Title"`},
+	} {
+		_, err := GenerateSchema(tc.target)
+		if err == nil || err.Error() != targetError(tc.want).Error() {
+			t.Errorf("%T: got error %v, want %q", tc.target, err, tc.want)
+		}
+	}
+}
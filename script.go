@@ -0,0 +1,166 @@
+package testtxt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/txtar"
+)
+
+// CmdScript returns test d, as it would be run by RunExec with
+// program, as script in the format of package
+// github.com/rogpeppe/go-internal/testscript. The script is a txtar
+// archive with commands as comment and files of =INPUT= and
+// =STDIN= as files, such that declarative tests and scripted
+// scenarios can be run together by testscript.Run.
+//
+// The translation is not exact:
+//   - A non zero exit code only checks, that the program fails.
+//   - =OUTPUT= only checks the listed files; other files in the
+//     working directory are ignored.
+//
+// It is an error, if d uses =XFAIL=, directories or symbolic links
+// in =OUTPUT=, or text that can't be represented in a script.
+func CmdScript(d *Cmd, program string) (*txtar.Archive, error) {
+	if d.Xfail != "" {
+		return nil, fmt.Errorf("can't convert =XFAIL= to script")
+	}
+	a, err := toTxtar(d.Input)
+	if err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", strings.ReplaceAll(d.Title, "\n", " "))
+	if d.Skip != "" {
+		fmt.Fprintf(&b, "skip %s\n", scriptQuote(d.Skip))
+	}
+	keys := make([]string, 0, len(d.Env))
+	for k := range d.Env {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		if strings.Contains(d.Env[k], "\n") {
+			return nil, fmt.Errorf("can't convert =ENV_%s= with multiple lines",
+				k)
+		}
+		fmt.Fprintf(&b, "env %s\n", scriptQuote(k+"="+d.Env[k]))
+	}
+	addFile := func(name, data string) string {
+		// Use name, that isn't used by =INPUT=.
+		for slices.ContainsFunc(a.Files, func(f txtar.File) bool {
+			return f.Name == name
+		}) {
+			name = "_" + name
+		}
+		a.Files = append(a.Files, txtar.File{Name: name, Data: []byte(data)})
+		return name
+	}
+	if d.Stdin != "" {
+		fmt.Fprintf(&b, "stdin %s\n", addFile("stdin.txt", d.Stdin))
+	}
+	if d.Exit != 0 {
+		b.WriteString("! ")
+	}
+	b.WriteString("exec " + scriptQuote(program))
+	for _, arg := range strings.Fields(d.Args) {
+		b.WriteString(" " + scriptQuote(arg))
+	}
+	b.WriteString("\n")
+	for _, s := range []struct {
+		name string
+		exp  Expected
+	}{{"stdout", d.Stdout}, {"stderr", d.Stderr}} {
+		switch text := s.exp.Text; s.exp.Mode {
+		case MatchRegexp:
+			fmt.Fprintf(&b, "%s %s\n", s.name,
+				scriptQuote(`\A(?:`+text+`)\z`))
+		case MatchContains:
+			for _, line := range strings.Split(text, "\n") {
+				line = strings.TrimSuffix(line, "\r")
+				if line == "" {
+					continue
+				}
+				neg, found := strings.CutPrefix(line, "!")
+				if found {
+					b.WriteString("! ")
+					line = neg
+				}
+				fmt.Fprintf(&b, "%s %s\n", s.name,
+					scriptQuote(regexp.QuoteMeta(line)))
+			}
+		default:
+			if text == "" {
+				fmt.Fprintf(&b, "! %s .\n", s.name)
+				break
+			}
+			if !strings.HasSuffix(text, "\n") {
+				return nil, fmt.Errorf("can't convert =%s= without final newline",
+					strings.ToUpper(s.name))
+			}
+			fmt.Fprintf(&b, "cmp %s %s\n", s.name, addFile(s.name+".golden", text))
+		}
+	}
+	if d.Output != "" {
+		if header, _ := splitIgnoreHeader(d.Output); header != "" {
+			return nil, fmt.Errorf("can't convert #ignore of =OUTPUT= to script")
+		}
+		out, err := toTxtar(d.Output)
+		if err != nil {
+			return nil, fmt.Errorf("=OUTPUT=: %v", err)
+		}
+		for _, f := range out.Files {
+			fmt.Fprintf(&b, "cmp %s %s\n", scriptQuote(f.Name),
+				scriptQuote(addFile("want/"+f.Name, string(f.Data))))
+		}
+	}
+	a.Comment = []byte(b.String())
+	return a, nil
+}
+
+// WriteScripts parses file into a slice of Cmd and writes a script of
+// each test, as returned by CmdScript, into directory dir, which is
+// created if needed. Scripts are named by sanitized titles with
+// extension ".txtar", such that dir can be used as testscript.Params.Dir.
+func WriteScripts(file, dir, program string, opts ...Option) error {
+	var l []Cmd
+	if err := ParseFile(file, &l, opts...); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	names := newRunConfig([]RunOption{SanitizeTitles()}).
+		testNames(reflect.ValueOf(l))
+	for i := range l {
+		a, err := CmdScript(&l[i], program)
+		if err != nil {
+			return fmt.Errorf("%s: test %q: %v", file, l[i].Title, err)
+		}
+		name := filepath.Join(dir, names[i]+".txtar")
+		if err := os.WriteFile(name, txtar.Format(a), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scriptQuote quotes s as argument of a command in a script, if
+// needed. Newlines are written as "\n", which is only valid in
+// regular expressions.
+func scriptQuote(s string) string {
+	if s != "" && !strings.ContainsFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' ||
+			r >= '0' && r <= '9' || strings.ContainsRune("-_./=:,+@", r))
+	}) {
+		return s
+	}
+	s = strings.ReplaceAll(s, "'", "''")
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return "'" + s + "'"
+}
@@ -0,0 +1,163 @@
+package testtxt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/tools/txtar"
+)
+
+func TestCmdScript(t *testing.T) {
+	tests := []struct {
+		name string
+		cmd  Cmd
+		want string // txtar format
+		err  string
+	}{
+		{
+			name: "files and output",
+			cmd: Cmd{
+				Title:  "copy\nfile",
+				Args:   "-v a.txt b.txt",
+				Stdin:  "in\n",
+				Input:  "---- a.txt\nx\n",
+				Stdout: Expected{Text: "copied\n"},
+				Output: "---- a.txt\nx\n---- b.txt\nx\n",
+				Env:    map[string]string{"LANG": "C", "A": "x y"},
+			},
+			want: `# copy file
+env 'A=x y'
+env LANG=C
+stdin stdin.txt
+exec prog -v a.txt b.txt
+cmp stdout stdout.golden
+! stderr .
+cmp a.txt want/a.txt
+cmp b.txt want/b.txt
+-- a.txt --
+x
+-- stdin.txt --
+in
+-- stdout.golden --
+copied
+-- want/a.txt --
+x
+-- want/b.txt --
+x
+`,
+		},
+		{
+			name: "failure, skip and patterns",
+			cmd: Cmd{
+				Title:  "fail",
+				Skip:   "not today",
+				Input:  "---- stdout.golden\ny\n",
+				Stdout: Expected{Text: "x\n", Mode: MatchExact},
+				Stderr: Expected{Text: "can't\n!panic\n", Mode: MatchContains},
+				Exit:   1,
+			},
+			want: `# fail
+skip 'not today'
+! exec prog
+cmp stdout _stdout.golden
+stderr 'can''t'
+! stderr panic
+-- stdout.golden --
+y
+-- _stdout.golden --
+x
+`,
+		},
+		{
+			name: "regular expression",
+			cmd: Cmd{
+				Title:  "re",
+				Stdout: Expected{Text: "a.*\nb", Mode: MatchRegexp},
+			},
+			want: `# re
+exec prog
+stdout '\A(?:a.*\nb)\z'
+! stderr .
+`,
+		},
+		{
+			name: "xfail",
+			cmd:  Cmd{Title: "x", Xfail: "bug"},
+			err:  "can't convert =XFAIL= to script",
+		},
+		{
+			name: "multi line environment",
+			cmd:  Cmd{Title: "x", Env: map[string]string{"A": "1\n2"}},
+			err:  "can't convert =ENV_A= with multiple lines",
+		},
+		{
+			name: "missing newline",
+			cmd:  Cmd{Title: "x", Stderr: Expected{Text: "e"}},
+			err:  "can't convert =STDERR= without final newline",
+		},
+		{
+			name: "ignored files",
+			cmd:  Cmd{Title: "x", Output: "#ignore *.o\n---- a\n"},
+			err:  "can't convert #ignore of =OUTPUT= to script",
+		},
+		{
+			name: "directory in output",
+			cmd:  Cmd{Title: "x", Output: "---- d/ (dir)\n"},
+			err:  "=OUTPUT=: can't store directory d/ in txtar",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			a, err := CmdScript(&tc.cmd, "prog")
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("got error %v, want %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := cmp.Diff(tc.want, string(txtar.Format(a))); d != "" {
+				t.Error(d)
+			}
+		})
+	}
+}
+
+func TestWriteScripts(t *testing.T) {
+	file := writeTemp(t, "x.t",
+		"=TITLE=a b\n=ARGS=x\n=STDOUT=\n1\n=END=\n\n=TITLE=c/d\n=EXIT=2\n")
+	dir := filepath.Join(t.TempDir(), "scripts")
+	if err := WriteScripts(file, dir, "prog"); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"a_b.txtar": "# a b\nexec prog x\ncmp stdout stdout.golden\n" +
+			"! stderr .\n-- stdout.golden --\n1\n",
+		"c_d.txtar": "# c/d\n! exec prog\n! stdout .\n! stderr .\n",
+	}
+	got := make(map[string]string)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[e.Name()] = string(data)
+	}
+	if d := cmp.Diff(want, got); d != "" {
+		t.Error(d)
+	}
+	bad := writeTemp(t, "y.t", "=TITLE=a\n=XFAIL=bug\n")
+	err = WriteScripts(bad, dir, "prog")
+	if want := bad + `: test "a": can't convert =XFAIL= to script`; err == nil ||
+		err.Error() != want {
+		t.Errorf("got error %v, want %q", err, want)
+	}
+}
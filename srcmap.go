@@ -0,0 +1,41 @@
+package testtxt
+
+// SourceMap maps lines of attribute values, that may have been
+// produced by templates and substitutions, back to lines of the file
+// of test descriptions.
+// Lines produced by a template are mapped to the line of the template
+// call.
+type SourceMap struct {
+	File string
+	// For each test: attribute name -> line in file for each line of
+	// value.
+	tests []map[string][]int
+}
+
+// Lookup returns the line in file, that produced line n (1-based) of
+// the value of attribute attr in test with index i.
+// It reports false, if no such line exists.
+func (m *SourceMap) Lookup(i int, attr string, n int) (int, bool) {
+	if i < 0 || i >= len(m.tests) {
+		return 0, false
+	}
+	lines := m.tests[i][attr]
+	if n < 1 || n > len(lines) {
+		return 0, false
+	}
+	return lines[n-1], true
+}
+
+// add records origins of lines of value of attribute attr,
+// which was expanded most recently.
+func (m *SourceMap) add(s *state, i int, attr string) {
+	m.File = s.filename
+	for len(m.tests) <= i {
+		m.tests = append(m.tests, make(map[string][]int))
+	}
+	lines := make([]int, len(s.lineOrigins))
+	for j, off := range s.lineOrigins {
+		lines[j], _ = s.position(off)
+	}
+	m.tests[i][attr] = lines
+}
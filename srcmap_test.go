@@ -0,0 +1,50 @@
+package testtxt
+
+import (
+	"testing"
+)
+
+func TestSourceMap(t *testing.T) {
+	src := "=TEMPL=two\nx\ny\n=END=\n" + // lines 1-4
+		"=TITLE=a\n" + // line 5
+		"=INPUT=\n1\n[[two]]\n2\n=END=\n" + // lines 6-10
+		"=OUTPUT=z\n" + // line 11
+		"\n=TITLE=b\n=INPUT=\nq\n" // lines 12-15
+	var m SourceMap
+	var l []parseDescr
+	file := writeTemp(t, "x.t", src)
+	if err := ParseFile(file, &l, WithSourceMap(&m)); err != nil {
+		t.Fatal(err)
+	}
+	if m.File != file {
+		t.Errorf("got file %q, want %q", m.File, file)
+	}
+	tests := []struct {
+		test int
+		attr string
+		line int
+		want int // 0 if not found
+	}{
+		{0, "TITLE", 1, 5},
+		{0, "INPUT", 1, 7},
+		{0, "INPUT", 2, 8},
+		{0, "INPUT", 3, 8},
+		{0, "INPUT", 4, 9},
+		// Position after final newline is mapped to =END=.
+		{0, "INPUT", 5, 10},
+		{0, "INPUT", 6, 0},
+		{0, "INPUT", 0, 0},
+		{0, "OUTPUT", 1, 11},
+		{0, "COUNT", 1, 0},
+		{1, "INPUT", 1, 15},
+		{2, "INPUT", 1, 0},
+		{-1, "INPUT", 1, 0},
+	}
+	for _, tc := range tests {
+		got, found := m.Lookup(tc.test, tc.attr, tc.line)
+		if found != (tc.want != 0) || got != tc.want {
+			t.Errorf("Lookup(%d, %q, %d) = %d, %v, want %d",
+				tc.test, tc.attr, tc.line, got, found, tc.want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package testtxt
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// SyncDir updates existing directory dir to match the files given by
+// input in the format of input of PrepareInDir.
+// Only files with changed content, type or mode are written; files
+// and directories not given by input are removed.
+// Input without markers is stored in a file named "input" or as given
+// by option SingleFileName.
+// Directory dir is created if it doesn't exist.
+func SyncDir(dir, input string, opts ...PrepareOption) error {
+	if input == "NONE" {
+		input = ""
+	}
+	c := newPrepareConfig(opts)
+	l, err := splitInput(input, c)
+	if err != nil {
+		return err
+	}
+	if l == nil && input != "" {
+		l = []*fileEntry{{name: c.singleName(), data: input}}
+	}
+	want := make(map[string]*fileEntry)
+	parents := make(map[string]bool)
+	for _, e := range l {
+		name := path.Clean(e.name)
+		want[name] = e
+		for p := path.Dir(name); p != "." && p != "/"; p = path.Dir(p) {
+			parents[p] = true
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	// Remove files, that aren't given by input.
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == dir {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		isDir := d.IsDir()
+		keep := want[rel] != nil || parents[rel] && isDir
+		if !keep {
+			if err := os.RemoveAll(p); err != nil {
+				return err
+			}
+			if isDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isDir {
+			// Read-only directories must be changed temporarily.
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if info.Mode()&0200 == 0 {
+				return os.Chmod(p, info.Mode().Perm()|0200)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, e := range l {
+		file := filepath.Join(dir, filepath.FromSlash(e.name))
+		ok, err := syncEntry(file, e)
+		if err != nil {
+			return err
+		}
+		if ok {
+			continue
+		}
+		if err := os.RemoveAll(file); err != nil {
+			return err
+		}
+		if err := writeEntry(dir, e); err != nil {
+			return err
+		}
+	}
+	return finishEntries(dir, l, c)
+}
+
+// syncEntry reports whether existing file already matches e.
+// It changes only the mode of a regular file.
+func syncEntry(file string, e *fileEntry) (bool, error) {
+	info, err := os.Lstat(file)
+	if err != nil {
+		return false, nil
+	}
+	mode := info.Mode()
+	switch {
+	case e.dir:
+		return mode.IsDir(), nil
+	case e.link != "":
+		if mode&fs.ModeSymlink == 0 {
+			return false, nil
+		}
+		target, err := os.Readlink(file)
+		return err == nil && filepath.ToSlash(target) == e.link, nil
+	case !mode.IsRegular():
+		return false, nil
+	}
+	data := []byte(e.data)
+	if e.from != "" {
+		if data, err = os.ReadFile(e.from); err != nil {
+			return false, err
+		}
+	}
+	got, err := os.ReadFile(file)
+	if err != nil || !bytes.Equal(got, data) {
+		return false, nil
+	}
+	perm := fs.FileMode(0644)
+	if e.modeSet {
+		perm = e.mode
+	}
+	if mode.Perm() != perm {
+		return true, os.Chmod(file, perm)
+	}
+	return true, nil
+}
@@ -0,0 +1,101 @@
+package testtxt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSyncDir(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		same   []string // files, that must not be written again
+	}{
+		{
+			name:   "unchanged",
+			before: "---- a\nx\n---- b/c 0755\ny\n",
+			after:  "---- a\nx\n---- b/c 0755\ny\n",
+			same:   []string{"a", "b/c"},
+		},
+		{
+			name:   "changed content and mode",
+			before: "---- a\nx\n---- b\ny\n---- c\nz\n",
+			after:  "---- a\nX\n---- b 0600\ny\n---- c\nz\n",
+			same:   []string{"b", "c"},
+		},
+		{
+			name:   "removed and added",
+			before: "---- a\nx\n---- d/e\ny\n---- f/ (dir)\n",
+			after:  "---- a\nx\n---- g\nz\n",
+			same:   []string{"a"},
+		},
+		{
+			name:   "changed type",
+			before: "---- a\nx\n---- b\ny\n---- c -> a\n",
+			after:  "---- a/ (dir)\n---- b -> a\n---- c\nz\n",
+		},
+		{
+			name:   "changed target of link",
+			before: "---- a\n---- b\n---- l -> a\n",
+			after:  "---- a\n---- b\n---- l -> b\n",
+			same:   []string{"a", "b"},
+		},
+		{
+			name:   "read-only directory",
+			before: "---- d/x\n---- d/ (dir) (ro)\n",
+			after:  "---- d/y\n---- d/ (dir) (ro)\n",
+		},
+		{
+			name:   "single file",
+			before: "---- a\nx\n",
+			after:  "abc\n",
+		},
+	}
+	mtime := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			want := t.TempDir()
+			// Allow removal of read-only directories.
+			t.Cleanup(func() {
+				os.Chmod(filepath.Join(dir, "d"), 0755)
+				os.Chmod(filepath.Join(want, "d"), 0755)
+			})
+			if err := SyncDir(dir, tc.before, WithModTime(mtime)); err != nil {
+				t.Fatal(err)
+			}
+			infos := make(map[string]os.FileInfo)
+			for _, name := range tc.same {
+				fi, err := os.Stat(filepath.Join(dir, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				infos[name] = fi
+			}
+			if err := SyncDir(dir, tc.after, WithModTime(mtime)); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := PrepareInDirE(want, "input", tc.after,
+				WithModTime(mtime)); err != nil {
+				t.Fatal(err)
+			}
+			if d := cmp.Diff(snapshotDir(t, want), snapshotDir(t, dir)); d != "" {
+				t.Error(d)
+			}
+			for name, before := range infos {
+				fi, err := os.Stat(filepath.Join(dir, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !os.SameFile(before, fi) {
+					t.Errorf("%s was written again", name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package testtxt
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// tagExpr reports whether a set of tags matches a tag expression.
+type tagExpr func(tags map[string]bool) bool
+
+// parseTagExpr parses expression s, that combines tags by operators
+// "&&", "||", "!" and parentheses, e.g. "ipv6 && !slow".
+// Operator "!" binds stronger than "&&", which binds stronger than "||".
+func parseTagExpr(s string) (tagExpr, error) {
+	p := &tagParser{src: s}
+	p.next()
+	e, err := p.or()
+	if err == nil && p.tok != "" {
+		err = p.errorf("unexpected %q", p.tok)
+	}
+	return e, err
+}
+
+type tagParser struct {
+	src string
+	pos int
+	tok string
+}
+
+// next reads the next token into p.tok; it is empty at end of input.
+func (p *tagParser) next() {
+	// Skip white space like strings.Fields.
+	rest := strings.TrimLeftFunc(p.src[p.pos:], unicode.IsSpace)
+	p.pos = len(p.src) - len(rest)
+	n := 0
+	switch {
+	case rest == "":
+	case strings.HasPrefix(rest, "&&"), strings.HasPrefix(rest, "||"):
+		n = 2
+	case strings.ContainsRune("!()", rune(rest[0])):
+		n = 1
+	default:
+		n = strings.IndexFunc(rest, func(r rune) bool { return !isTagChar(r) })
+		if n == -1 {
+			n = len(rest)
+		} else if n == 0 {
+			n = 1
+		}
+	}
+	p.tok = rest[:n]
+	p.pos += n
+}
+
+func (p *tagParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("invalid tag expression %q: %s",
+		p.src, fmt.Sprintf(format, args...))
+}
+
+func (p *tagParser) or() (tagExpr, error) {
+	l, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok == "||" {
+		p.next()
+		r, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		a := l
+		l = func(tags map[string]bool) bool { return a(tags) || r(tags) }
+	}
+	return l, nil
+}
+
+func (p *tagParser) and() (tagExpr, error) {
+	l, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok == "&&" {
+		p.next()
+		r, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		a := l
+		l = func(tags map[string]bool) bool { return a(tags) && r(tags) }
+	}
+	return l, nil
+}
+
+func (p *tagParser) not() (tagExpr, error) {
+	switch tok := p.tok; {
+	case tok == "!":
+		p.next()
+		e, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return func(tags map[string]bool) bool { return !e(tags) }, nil
+	case tok == "(":
+		p.next()
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok != ")" {
+			return nil, p.errorf("missing \")\"")
+		}
+		p.next()
+		return e, nil
+	case tok == "":
+		return nil, p.errorf("unexpected end")
+	case !isTagChar(rune(tok[0])):
+		return nil, p.errorf("unexpected %q", tok)
+	default:
+		p.next()
+		return func(tags map[string]bool) bool { return tags[tok] }, nil
+	}
+}
+
+func isTagChar(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) ||
+		strings.ContainsRune("-_.", r)
+}
@@ -0,0 +1,55 @@
+package testtxt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseTagExpr(t *testing.T) {
+	tests := []struct {
+		expr string
+		tags string
+		want bool
+		err  string
+	}{
+		{expr: "a", tags: "a b", want: true},
+		{expr: "c", tags: "a b", want: false},
+		{expr: "a && b", tags: "a b", want: true},
+		{expr: "a&&c", tags: "a b", want: false},
+		{expr: "a || c", tags: "a", want: true},
+		{expr: "!a", tags: "a", want: false},
+		{expr: "!!a", tags: "a", want: true},
+		{expr: "a || b && c", tags: "a", want: true},
+		{expr: "(a || b) && c", tags: "a", want: false},
+		{expr: "ipv6 && !slow", tags: "ipv6", want: true},
+		{expr: "x-1.y_2", tags: "x-1.y_2", want: true},
+		{expr: "\ta\t&&\nb ", tags: "a b", want: true},
+		{expr: "", err: `invalid tag expression "": unexpected end`},
+		{expr: "a &&", err: `invalid tag expression "a &&": unexpected end`},
+		{expr: "a b", err: `invalid tag expression "a b": unexpected "b"`},
+		{expr: "(a", err: `invalid tag expression "(a": missing ")"`},
+		{expr: "a & b", err: `invalid tag expression "a & b": unexpected "&"`},
+		{expr: "|| a", err: `invalid tag expression "|| a": unexpected "||"`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.expr, func(t *testing.T) {
+			e, err := parseTagExpr(tc.expr)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Errorf("got error %v, want %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			tags := make(map[string]bool)
+			for _, tag := range strings.Fields(tc.tags) {
+				tags[tag] = true
+			}
+			if got := e(tags); got != tc.want {
+				t.Errorf("got %v with tags %q", got, tc.tags)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package testtxt
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// TAPWriter writes results of tests and diagnostics of files in the
+// format of the Test Anything Protocol, version 13. The plan is
+// written by Close after the last test point, such that the number
+// of tests needn't be known in advance.
+//
+// Method Result can be used as hook of option ResultHook:
+//
+//	tw := testtxt.NewTAPWriter(f)
+//	t.Cleanup(func() { tw.Close() })
+//	testtxt.RunTests(t, file, fn, testtxt.ResultHook(tw.Result))
+type TAPWriter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	n   int
+	err error
+}
+
+// NewTAPWriter returns a TAPWriter, that writes to w.
+func NewTAPWriter(w io.Writer) *TAPWriter {
+	return &TAPWriter{w: w}
+}
+
+// Result writes a test point for result r of a test. A difference is
+// added as YAML block.
+func (w *TAPWriter) Result(r Result) {
+	name := r.Title
+	if name == "" {
+		name = r.Name
+	}
+	diag := make(map[string]string)
+	if r.Diff != "" {
+		diag["message"] = r.Diff
+	}
+	if r.Duration > 0 {
+		diag["duration_ms"] = fmt.Sprintf("%.3f",
+			float64(r.Duration.Microseconds())/1000)
+	}
+	w.point(r.Status != "fail", name, r.Status == "skip", diag)
+}
+
+// Diagnostics writes a test point for file with diagnostics l, as
+// returned by Lint. The test point fails, if l has an error. Messages
+// of diagnostics are added as YAML block.
+func (w *TAPWriter) Diagnostics(file string, l []Diagnostic) {
+	ok := true
+	var msgs []string
+	for _, d := range l {
+		if d.Severity == SeverityError {
+			ok = false
+		}
+		msgs = append(msgs, d.String())
+	}
+	diag := make(map[string]string)
+	if msgs != nil {
+		diag["message"] = strings.Join(msgs, "\n")
+	}
+	w.point(ok, file, false, diag)
+}
+
+// Close writes the plan and returns the first error of writing.
+func (w *TAPWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.header()
+	w.printf("1..%d\n", w.n)
+	return w.err
+}
+
+// point writes a test point with given description and YAML block
+// with keys and values of diag.
+func (w *TAPWriter) point(ok bool, descr string, skip bool,
+	diag map[string]string,
+) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.header()
+	w.n++
+	status := "ok"
+	if !ok {
+		status = "not ok"
+	}
+	descr = strings.ReplaceAll(descr, `\`, `\\`)
+	descr = strings.ReplaceAll(descr, "#", `\#`)
+	descr = strings.ReplaceAll(descr, "\n", " ")
+	w.printf("%s %d - %s", status, w.n, descr)
+	if skip {
+		w.printf(" # SKIP")
+	}
+	w.printf("\n")
+	if len(diag) == 0 {
+		return
+	}
+	w.printf("  ---\n")
+	for _, key := range []string{"message", "duration_ms"} {
+		v, found := diag[key]
+		switch {
+		case !found:
+		case strings.Contains(v, "\n"):
+			w.printf("  %s: |\n", key)
+			// Block scalar adds final newline.
+			v = strings.TrimSuffix(v, "\n")
+			for _, line := range strings.Split(v, "\n") {
+				w.printf("    %s\n", line)
+			}
+		case key == "message":
+			w.printf("  %s: %s\n", key, strconv.Quote(v))
+		default:
+			w.printf("  %s: %s\n", key, v)
+		}
+	}
+	w.printf("  ...\n")
+}
+
+// header writes the version line before the first output.
+func (w *TAPWriter) header() {
+	if w.n == 0 && w.err == nil {
+		w.printf("TAP version 13\n")
+	}
+}
+
+func (w *TAPWriter) printf(format string, args ...any) {
+	if w.err == nil {
+		_, w.err = fmt.Fprintf(w.w, format, args...)
+	}
+}
@@ -0,0 +1,71 @@
+package testtxt
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTAPWriter(t *testing.T) {
+	var b strings.Builder
+	w := NewTAPWriter(&b)
+	w.Result(Result{Name: "TestX/a", Title: "a", Status: "pass",
+		Duration: 1500 * time.Microsecond})
+	w.Result(Result{Name: "TestX/b#1", Title: "b #1\nc", Status: "fail",
+		Diff: "--- expected\n+++ got\n"})
+	w.Result(Result{Name: "TestX/d", Status: "skip"})
+	w.Diagnostics("x.t", []Diagnostic{
+		{File: "x.t", Line: 2, Column: 1, Severity: SeverityWarning,
+			Code: "tab", Message: "tab"},
+	})
+	w.Diagnostics("y.t", []Diagnostic{
+		{File: "y.t", Line: 1, Column: 8, Severity: SeverityError,
+			Message: "bad"},
+		{File: "y.t", Line: 3, Column: 1, Severity: SeverityWarning,
+			Message: "odd"},
+	})
+	w.Diagnostics("z.t", nil)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := `TAP version 13
+ok 1 - a
+  ---
+  duration_ms: 1.500
+  ...
+not ok 2 - b \#1 c
+  ---
+  message: |
+    --- expected
+    +++ got
+  ...
+ok 3 - TestX/d # SKIP
+ok 4 - x.t
+  ---
+  message: "x.t:2:1: warning: tab"
+  ...
+not ok 5 - y.t
+  ---
+  message: |
+    y.t:1:8: error: bad
+    y.t:3:1: warning: odd
+  ...
+ok 6 - z.t
+1..6
+`
+	if d := cmp.Diff(want, b.String()); d != "" {
+		t.Error(d)
+	}
+}
+
+func TestTAPWriterEmpty(t *testing.T) {
+	var b strings.Builder
+	if err := NewTAPWriter(&b).Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := b.String(), "TAP version 13\n1..0\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
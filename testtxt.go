@@ -26,109 +26,226 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"log"
 	"os"
-	"path"
+	"path/filepath"
 	"reflect"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
-	"testing"
 	"text/template"
 	"time"
+	"unicode/utf8"
 
 	"gopkg.in/yaml.v3"
 )
 
 // ParseFile parses the named file as a list of test descriptions.
-func ParseFile(file string, l any) error {
-	data, err := os.ReadFile(file)
+// Parameter l must be a pointer to an empty slice of struct.
+// A struct field of type map[string]string, e.g. Env, collects all
+// attributes with its name as prefix, e.g. =ENV_HOME= as key "HOME".
+func ParseFile(file string, l any, opts ...Option) error {
+	data, err := readSource(file)
 	if err != nil {
 		return err
 	}
 	v := reflect.ValueOf(l)
 	if v.Kind() != reflect.Pointer {
-		return fmt.Errorf("Expecting pointer to empty slice")
+		return targetError("expecting pointer to empty slice")
 	}
 	v = v.Elem()
 	if v.Kind() != reflect.Slice || v.Len() != 0 {
-		return fmt.Errorf("Expecting pointer to empty slice")
+		return targetError("expecting pointer to empty slice")
 	}
 	s := &state{
 		src:       data,
 		rest:      data,
-		templates: make(map[string]*template.Template),
+		templates: make(map[string]*templ),
 		filename:  file,
 		slice:     v,
 	}
+	for _, o := range opts {
+		o(&s.config)
+	}
+	if err := s.parseAll(); err != nil {
+		// Keep warnings recorded in strict mode before fatal error.
+		s.errs = append(s.errs, err)
+	}
+	return errors.Join(s.errs...)
+}
+
+// parseAll reads template files and parses source of s.
+func (s *state) parseAll() error {
+	for _, f := range s.templateFiles {
+		if err := s.readTemplateFile(f); err != nil {
+			return err
+		}
+	}
+	if err := s.prepareSrc(); err != nil {
+		return err
+	}
 	return s.parse()
 }
 
 type state struct {
+	config
 	src       []byte
 	rest      []byte
-	templates map[string]*template.Template
+	templates map[string]*templ
 	filename  string
 	slice     reflect.Value
+	// Errors collected in mode collectErrors.
+	errs []error
+	// Byte offsets in file of each line of most recently expanded text.
+	lineOrigins []int
+	// Byte offset of title of each test, used for lint.
+	titles map[string]int
+	// Name of title attribute.
+	titleAttr string
+	// Value of title attribute of current test.
+	title  string
+	inTest bool
+}
+
+func targetError(msg string) error {
+	return &ParseError{Kind: KindTarget, Msg: msg}
+}
+
+// errorf returns a ParseError for given byte offset of current file.
+// Title of current test is added if available.
+func (s *state) errorf(kind ErrorKind, off int, format string, args ...any,
+) error {
+	line, col := s.position(off)
+	e := &ParseError{
+		File:   s.filename,
+		Line:   line,
+		Column: col,
+		Kind:   kind,
+		Msg:    fmt.Sprintf(format, args...),
+	}
+	if s.inTest {
+		e.TestTitle = s.title
+		e.titleAttr = s.titleAttr
+	}
+	return e
+}
+
+// addErr records err if errors are collected.
+// It reports whether parsing can go on.
+func (s *state) addErr(err error) bool {
+	if !s.collectErrors {
+		return false
+	}
+	s.errs = append(s.errs, err)
+	return true
 }
 
 func (s *state) parse() error {
 	el := addElement(s.slice)
 	if el.Kind() != reflect.Struct {
-		return fmt.Errorf("Expecting slice of struct.")
+		return targetError("expecting slice of struct")
 	}
 	fields := reflect.VisibleFields(el.Type())
 	if len(fields) == 0 {
-		return fmt.Errorf("Expecting struct with at least one field.")
+		return targetError("expecting struct with at least one field")
+	}
+	title := toSnakeCase(fields[0].Name)
+	if s.valueHook != nil {
+		title = s.firstAttr()
 	}
-	title := strings.ToUpper(fields[0].Name)
-	var seen map[string]bool
-	tVal := ""
-	first := true
+	s.titleAttr = title
+	// Maps name of attribute of current test to its byte offset.
+	var seen map[string]int
 	for {
-		name, err := s.readDef()
+		name, pos, err := s.readDef()
 		if err != nil {
-			return err
+			if !s.addErr(err) {
+				return err
+			}
+			s.skipLine()
+			continue
 		}
 		if name == "" { // EOF
-			if first {
-				return fmt.Errorf("missing =%s= in first test", title)
+			s.checkUnusedTemplates()
+			if !s.inTest {
+				err := s.errorf(KindAttribute, pos,
+					"missing =%s= in first test", title)
+				if !s.addErr(err) {
+					return err
+				}
 			}
 			return nil
 		}
 		switch name {
 		case "TEMPL":
-			if err := s.templDef(); err != nil {
+			if err := s.templDef(); err != nil && !s.addErr(err) {
 				return err
 			}
 			continue
 		case "SUBST":
-			return fmt.Errorf(
-				"=SUBST= is only valid at bottom of text block in test with =%s=%s",
-				title, tVal)
+			err := s.errorf(KindSubst, pos,
+				"=SUBST= is only valid at bottom of text block")
+			if !s.addErr(err) {
+				return err
+			}
+			s.skipLine()
+			continue
 		}
-		text, err := s.readExpandedText()
-		if err != nil {
-			return err
+		name, mode, _ := strings.Cut(name, ":")
+		if name == title {
+			// Errors in title don't belong to previous test.
+			s.inTest = false
+		}
+		text, textPos, textErr := s.readExpandedText()
+		if textErr != nil && !s.addErr(textErr) {
+			return textErr
 		}
 		if name == title {
-			if seen[name] {
+			if _, found := seen[name]; found {
 				el = addElement(s.slice)
 			}
-			tVal = text
-			first = false
-			seen = make(map[string]bool)
-		} else if first {
-			return fmt.Errorf("must define =%s= before =%s=", title, name)
+			s.title = text
+			if s.lint {
+				s.checkDupTitle(text, pos)
+			}
+			s.inTest = true
+			seen = make(map[string]int)
+		} else if !s.inTest {
+			err := s.errorf(KindAttribute, pos,
+				"must define =%s= before =%s=", title, name)
+			if !s.addErr(err) {
+				return err
+			}
+			continue
+		}
+		if prev, found := seen[name]; found {
+			l1, _ := s.position(prev)
+			l2, _ := s.position(pos)
+			err := s.errorf(KindAttribute, pos,
+				"found multiple =%s= at lines %d and %d", name, l1, l2)
+			if !s.addErr(err) {
+				return err
+			}
+			continue
 		}
-		if seen[name] {
-			return fmt.Errorf(
-				"found multiple =%s= in test with =%s=%s", name, title, tVal)
+		if s.attrHook != nil {
+			s.attrHook(s, name, pos)
 		}
-		if err := setVal(el, name, text); err != nil {
-			return fmt.Errorf("%v in test with =%s=%s", err, title, tVal)
+		if s.sourceMap != nil {
+			s.sourceMap.add(s, s.slice.Len()-1, name)
+		}
+		if textErr == nil {
+			var err error
+			if s.valueHook != nil {
+				err = s.valueHook(s, name, mode, text, pos)
+			} else {
+				err = s.setVal(el, name, mode, text, pos, textPos)
+			}
+			if err != nil && !s.addErr(err) {
+				return err
+			}
 		}
-		seen[name] = true
+		seen[name] = pos
 	}
 }
 
@@ -141,44 +258,127 @@ func addElement(v reflect.Value) reflect.Value {
 	return v.Index(ln - 1)
 }
 
-func setVal(el reflect.Value, name, text string) error {
+// setVal stores text in field of el corresponding to attribute name.
+// Parameter mode is the optional suffix of name, e.g. "RE" in =OUTPUT:RE=.
+// Parameters pos and textPos are byte offsets of name and of text.
+func (s *state) setVal(el reflect.Value, name, mode, text string,
+	pos, textPos int,
+) error {
 	for _, f := range reflect.VisibleFields(el.Type()) {
 		if toSnakeCase(f.Name) == name {
 			if !f.IsExported() {
-				return fmt.Errorf("struct field %q must be exported", f.Name)
+				return s.errorf(KindTarget, pos,
+					"struct field %q must be exported", f.Name)
 			}
 			v := el.FieldByIndex(f.Index)
+			if v.Type() == expectedType {
+				m, found := matchModes[mode]
+				if !found {
+					return s.errorf(KindAttribute, pos,
+						"unknown mode in =%s:%s=", name, mode)
+				}
+				v.Set(reflect.ValueOf(Expected{Text: text, Mode: m}))
+				return nil
+			}
+			if mode != "" {
+				return s.errorf(KindTarget, pos,
+					"struct field %q must have type testtxt.Expected for =%s:%s=",
+					f.Name, name, mode)
+			}
+			if v.Type() == durationType {
+				d, err := time.ParseDuration(text)
+				if err != nil {
+					return s.errorf(KindValue, textPos,
+						"invalid value for struct field %q of =%s=: %v",
+						f.Name, name, err)
+				}
+				v.SetInt(int64(d))
+				return nil
+			}
 			switch v.Kind() {
 			case reflect.String:
 				v.SetString(text)
 			case reflect.Int:
 				i, err := strconv.ParseInt(text, 10, 64)
 				if err != nil {
-					fmt.Errorf("invalid value for struct field %q: %v", f.Name, err)
+					return s.errorf(KindValue, textPos,
+						"invalid value for struct field %q of =%s=: %v",
+						f.Name, name, err)
 				}
 				v.SetInt(i)
 			case reflect.Bool:
 				v.SetBool(true)
 			default:
-				return fmt.Errorf("unexpected type %v of struct field %q",
-					v.Kind(), f.Name)
+				return s.errorf(KindTarget, pos,
+					"unexpected type %v of struct field %q", v.Kind(), f.Name)
 			}
 			return nil
 		}
 	}
-	return fmt.Errorf("unexpected =%s=", name)
+	mapType := reflect.TypeOf(map[string]string(nil))
+	for _, f := range reflect.VisibleFields(el.Type()) {
+		key, found := strings.CutPrefix(name, toSnakeCase(f.Name)+"_")
+		if !found || key == "" || f.Type != mapType {
+			continue
+		}
+		if !f.IsExported() {
+			return s.errorf(KindTarget, pos,
+				"struct field %q must be exported", f.Name)
+		}
+		if mode != "" {
+			return s.errorf(KindAttribute, pos,
+				"unexpected mode in =%s:%s=", name, mode)
+		}
+		v := el.FieldByIndex(f.Index)
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(mapType))
+		}
+		v.SetMapIndex(reflect.ValueOf(key), reflect.ValueOf(text))
+		return nil
+	}
+	return s.errorf(KindAttribute, pos, "unexpected =%s=", name)
 }
 
+var durationType = reflect.TypeOf(time.Duration(0))
+
 var matchFirstCap = regexp.MustCompile("(.)([A-Z][a-z]+)")
 var matchAllCap = regexp.MustCompile("([a-z0-9])([A-Z])")
 
+// AttrName returns the name of the attribute, that sets struct field
+// with given name, e.g. "OUTPUT_FILE" for field OutputFile.
+func AttrName(field string) string {
+	return toSnakeCase(field)
+}
+
 func toSnakeCase(str string) string {
 	snake := matchFirstCap.ReplaceAllString(str, "${1}_${2}")
 	snake = matchAllCap.ReplaceAllString(snake, "${1}_${2}")
 	return strings.ToUpper(snake)
 }
 
-func (s *state) readDef() (string, error) {
+// firstAttr returns name of first attribute defined in rest of file,
+// ignoring definitions of templates.
+func (s *state) firstAttr() string {
+	rest := s.rest
+	for len(rest) > 0 {
+		line := rest
+		if idx := bytes.IndexByte(rest, '\n'); idx != -1 {
+			line = rest[:idx+1]
+		}
+		rest = rest[len(line):]
+		switch name := s.checkDef(string(line)); name {
+		case "", "TEMPL", "SUBST", "END":
+		default:
+			return name
+		}
+	}
+	return ""
+}
+
+// readDef reads name of next definition and returns it together with
+// byte offset of its leading "=".
+// At EOF, an empty name is returned.
+func (s *state) readDef() (string, int, error) {
 	var line string
 	for {
 		// Skip empty lines and comments
@@ -188,32 +388,121 @@ func (s *state) readDef() (string, error) {
 		} else {
 			line = string(s.rest[:idx])
 		}
-		line = strings.TrimSpace(line)
-		if line == "" || line[0] == '#' {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed[0] == '#' {
 			if idx == -1 {
 				s.rest = s.rest[len(s.rest):]
 				// Found EOF.
-				return "", nil
+				return "", s.offset(), nil
 			} else {
 				s.rest = s.rest[idx+1:]
 				continue
 			}
-		} else {
-			break
 		}
+		// Skip leading white space.
+		s.rest = s.rest[strings.Index(line, trimmed):]
+		line = trimmed
+		break
 	}
+	pos := s.offset()
 	name := s.checkDef(line)
 	if name == "" {
-		nr := s.currentLine()
-		return "", fmt.Errorf("expected token '=...=' at line %d of file %s: %s",
-			nr, s.filename, line)
+		if strings.HasPrefix(line, bom) {
+			return "", pos, s.errorf(KindSyntax, pos,
+				"unexpected byte order mark")
+		}
+		return "", pos, s.errorf(KindSyntax, pos,
+			"expected token '=...=': %s", line)
 	}
 	s.rest = s.rest[len(name)+2:]
-	return name, nil
+	return name, pos, nil
+}
+
+// prepareSrc checks encoding and line endings of file
+// and normalizes line endings if requested.
+func (s *state) prepareSrc() error {
+	// Skip byte order mark at start of file. It is removed from s.src
+	// as well, such that it isn't counted in columns of first line.
+	s.src = bytes.TrimPrefix(s.src, []byte(bom))
+	s.rest = s.src
+	if err := s.checkUTF8(); err != nil {
+		return err
+	}
+	s.checkLineEndings()
+	if s.normalizeCRLF {
+		s.src = bytes.ReplaceAll(s.src, []byte("\r\n"), []byte("\n"))
+		s.rest = s.src
+	}
+	return nil
+}
+
+// Byte order mark in UTF-8.
+const bom = "\uFEFF"
+
+// checkLineEndings warns if file has mixed line endings "\n" and "\r\n".
+// The first line, that differs from the first line, is reported.
+func (s *state) checkLineEndings() {
+	crlf := false
+	off := 0
+	for i, line := range bytes.SplitAfter(s.src, []byte("\n")) {
+		if !bytes.HasSuffix(line, []byte("\n")) {
+			break
+		}
+		if bytes.HasSuffix(line, []byte("\r\n")) != crlf {
+			if i != 0 {
+				s.warnf("mixed-line-endings", off,
+					"mixed line endings: line differs from first line")
+				return
+			}
+			crlf = true
+		}
+		off += len(line)
+	}
+}
+
+// checkUTF8 checks that file is valid UTF-8.
+// The first invalid byte of each line is reported.
+func (s *state) checkUTF8() error {
+	if s.rawBytes || utf8.Valid(s.src) {
+		return nil
+	}
+	lineStart := 0
+	for _, line := range bytes.SplitAfter(s.src, []byte("\n")) {
+		for i := 0; i < len(line); {
+			r, size := utf8.DecodeRune(line[i:])
+			if r == utf8.RuneError && size == 1 {
+				off := lineStart + i
+				err := s.errorf(KindEncoding, off,
+					"invalid UTF-8 byte 0x%02x at offset %d", line[i], off)
+				if !s.addErr(err) {
+					return err
+				}
+				break
+			}
+			i += size
+		}
+		lineStart += len(line)
+	}
+	return nil
 }
 
-func (s *state) currentLine() int {
-	return 1 + bytes.Count(s.src[0:len(s.src)-len(s.rest)], []byte("\n"))
+// skipLine skips remaining text of current line.
+func (s *state) skipLine() {
+	s.rest = s.rest[len(s.getLine()):]
+}
+
+// offset returns byte offset of current position.
+func (s *state) offset() int {
+	return len(s.src) - len(s.rest)
+}
+
+// position returns line and column of byte offset off.
+// Both values are 1-based, column counts bytes.
+func (s *state) position(off int) (line, col int) {
+	before := s.src[:off]
+	line = 1 + bytes.Count(before, []byte("\n"))
+	col = off - bytes.LastIndexByte(before, '\n')
+	return
 }
 
 func (s *state) checkDef(line string) string {
@@ -225,42 +514,173 @@ func (s *state) checkDef(line string) string {
 		return ""
 	}
 	name := line[1 : idx+1]
-	if isName(name) {
+	// Name may be followed by mode, e.g. =OUTPUT:RE=.
+	base, mode, found := strings.Cut(name, ":")
+	if isName(base) && (!found || mode != "" && isName(mode)) {
 		return name
 	}
 	return ""
 }
 
+// templ is a named template defined by =TEMPL=.
+type templ struct {
+	*template.Template
+	file string // file of definition
+	line int    // line of definition
+	pos  int    // byte offset of name in file
+	used bool
+}
+
 func (s *state) templDef() error {
-	name, err := s.readTemplName()
+	name, pos, err := s.readTemplName()
+	text, _, err2 := s.readExpandedText()
 	if err != nil {
 		return err
 	}
-	text, err := s.readExpandedText()
-	if err != nil {
-		return err
+	if err2 != nil {
+		return err2
 	}
 	text = strings.TrimSuffix(text, "\n")
+	text = strings.TrimSuffix(text, "\r")
 	fMap := template.FuncMap{
 		"DATE": func(offset int) string {
 			return time.Now().AddDate(0, 0, offset).Format("2006-01-02")
 		},
 	}
-	s.templates[name], err =
+	for k, f := range s.funcs {
+		fMap[k] = f
+	}
+	t, err :=
 		template.New(name).Option("missingkey=zero").Funcs(fMap).Parse(text)
+	if err != nil {
+		return s.errorf(KindTemplate, pos, "%v", err)
+	}
+	line, _ := s.position(pos)
+	if prev := s.templates[name]; prev != nil {
+		if prev.file != s.filename {
+			return s.errorf(KindTemplate, pos,
+				"template %s is already defined at %s:%d",
+				name, prev.file, prev.line)
+		}
+		s.warnf("redefined-template", pos,
+			"template %s overrides definition at line %d", name, prev.line)
+		if !prev.used {
+			s.warnf("unused-template", prev.pos, "unused template %s", name)
+		}
+	}
+	s.templates[name] =
+		&templ{Template: t, file: s.filename, line: line, pos: pos}
+	return nil
+}
+
+// readSource reads file of test descriptions. A file with extension
+// ".toml" is converted by TOMLSource.
+func readSource(file string) ([]byte, error) {
+	data, err := os.ReadFile(file)
+	if err != nil || !strings.EqualFold(filepath.Ext(file), ".toml") {
+		return data, err
+	}
+	data, err = TOMLSource(data)
+	if err != nil {
+		return nil, tomlError(file, err)
+	}
+	return data, nil
+}
+
+// readTemplateFile reads templates from file,
+// that must only contain definitions of templates.
+func (s *state) readTemplateFile(file string) error {
+	data, err := readSource(file)
+	if err != nil {
+		return err
+	}
+	lib := &state{
+		config:    s.config,
+		src:       data,
+		rest:      data,
+		templates: s.templates,
+		filename:  file,
+	}
+	err = lib.prepareSrc()
+	if err == nil {
+		err = lib.parseTemplates()
+	}
+	s.errs = append(s.errs, lib.errs...)
 	return err
 }
 
-func (s *state) readTemplName() (string, error) {
+func (s *state) parseTemplates() error {
+	for {
+		name, pos, err := s.readDef()
+		if err != nil {
+			if !s.addErr(err) {
+				return err
+			}
+			s.skipLine()
+			continue
+		}
+		switch name {
+		case "": // EOF
+			return nil
+		case "TEMPL":
+			if err := s.templDef(); err != nil && !s.addErr(err) {
+				return err
+			}
+		default:
+			err := s.errorf(KindSyntax, pos,
+				"expected only =TEMPL= in template file, got =%s=", name)
+			if !s.addErr(err) {
+				return err
+			}
+			s.readExpandedText()
+		}
+	}
+}
+
+// execute applies template to data.
+// A panic, e.g. in a function given by option WithFuncs,
+// is returned as error.
+func (t *templ) execute(data any) (out string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	var b strings.Builder
+	err = t.Execute(&b, data)
+	return b.String(), err
+}
+
+// readTemplName returns name of template together with its byte offset.
+func (s *state) readTemplName() (string, int, error) {
 	line := s.getLine()
-	s.rest = s.rest[len(line)-1:] // don't skip trailing newline
 	name := strings.TrimSpace(line)
-	for _, ch := range name {
-		if !(isLetter(ch) || isDecimal(ch)) {
-			return "", errors.New("invalid name after =TEMPL=: " + name)
+	pos := s.offset() + strings.Index(line, name)
+	s.rest = s.rest[len(line)-1:] // don't skip trailing newline
+	if !isName(name) {
+		return "", pos, s.errorf(KindTemplate, pos,
+			"invalid name after =TEMPL=: %s", name)
+	}
+	return name, pos, nil
+}
+
+// checkUnusedTemplates warns about templates, that were never called.
+func (s *state) checkUnusedTemplates() {
+	var l []*templ
+	for _, t := range s.templates {
+		// Templates from template files need not be used in each file.
+		if !t.used && t.file == s.filename {
+			l = append(l, t)
 		}
 	}
-	return name, nil
+	slices.SortFunc(l, func(a, b *templ) int { return a.pos - b.pos })
+	// Templates don't belong to some test.
+	inTest := s.inTest
+	s.inTest = false
+	defer func() { s.inTest = inTest }()
+	for _, t := range l {
+		s.warnf("unused-template", t.pos, "unused template %s", t.Name())
+	}
 }
 
 func isName(n string) bool {
@@ -279,32 +699,75 @@ func isLetter(ch rune) bool {
 	return 'a' <= lower(ch) && lower(ch) <= 'z' || ch == '_'
 }
 
-func (s *state) readExpandedText() (string, error) {
-	line, err := s.doTemplSubst(s.readText())
-	if err != nil {
-		return "", err
+// readExpandedText reads text, expands templates and applies
+// substitutions.
+// It returns expanded text together with byte offset of original text.
+// Following =SUBST= lines are consumed even if an error occurs.
+func (s *state) readExpandedText() (string, int, error) {
+	text, pos := s.readText()
+	text, err := s.doTemplSubst(text, pos)
+	text, err2 := s.applySubst(text)
+	if err == nil {
+		err = err2
 	}
-	return s.applySubst(line)
+	return text, pos, err
 }
 
-func (s *state) readText() string {
+// readText reads text of single line or of multiple lines
+// and returns it together with its byte offset.
+func (s *state) readText() (string, int) {
 	// Check for single line
+	pos := s.offset()
 	line := s.getLine()
 	s.rest = s.rest[len(line):]
-	line = strings.TrimSpace(line)
-	if line != "" {
-		return line
+	trimmed := strings.TrimSpace(line)
+	if trimmed != "" {
+		return trimmed, pos + strings.Index(line, trimmed)
 	}
 	// Read multiple lines up to start of next definition
+	pos = s.offset()
 	text := s.rest
 	size := 0
+	// Offset of first comment line in trailing lines consisting only
+	// of comments and empty lines; -1 if not found.
+	commentPos := -1
 	for {
 		line := s.getLine()
+		if trimmed := strings.TrimLeft(line, " \t"); trimmed != line {
+			if name := s.checkDef(trimmed); name != "" {
+				s.warnf("indented-definition", s.offset()+len(line)-len(trimmed),
+					"indented =%s= is taken as part of text", name)
+			}
+		}
 		if name := s.checkDef(line); name != "" || line == "" {
-			if name == "END" {
+			switch name {
+			case "END":
 				s.rest = s.rest[len("=END="):]
+			case s.titleAttr:
+				// Comments in front of next test are probably meant to
+				// describe that test and not to be part of current text.
+				if commentPos != -1 {
+					l, _ := s.position(s.offset())
+					s.warnf("missing-end", commentPos,
+						"probably missing =END=:"+
+							" comment before =%s= at line %d is part of text", name, l)
+				}
+			}
+			return string(text[:size]), pos
+		}
+		switch trimmed := strings.TrimSpace(line); {
+		case trimmed == "":
+		case trimmed[0] == '#':
+			if commentPos == -1 {
+				commentPos = s.offset()
+			}
+		default:
+			commentPos = -1
+		}
+		if s.lint {
+			if i := strings.IndexByte(line, '\t'); i != -1 {
+				s.warnf("tab", s.offset()+i, "tab character in text")
 			}
-			return string(text[:size])
 		}
 		s.rest = s.rest[len(line):]
 		size += len(line)
@@ -313,65 +776,124 @@ func (s *state) readText() string {
 
 // Substitute occurrences of [[name yaml-data]] by text of evaluated
 // named template.
-func (s *state) doTemplSubst(text string) (string, error) {
+// Parameter pos is the byte offset of text.
+// Byte offsets in file, that produced each line of result, are stored
+// in s.lineOrigins.
+func (s *state) doTemplSubst(text string, pos int) (string, error) {
 	var result strings.Builder
 	prevIdx := 0
+	s.lineOrigins = append(s.lineOrigins[:0], pos)
+	addOrigins := func(t string, off func(i int) int) {
+		for i, c := range []byte(t) {
+			if c == '\n' {
+				s.lineOrigins = append(s.lineOrigins, off(i))
+			}
+		}
+	}
+	literal := func(i int) int { return pos + i + 1 }
 
 	// Take "]" in "]]]" as part of YAML sequence.
 	re := regexp.MustCompile(`(?s)\[\[.*?\]?\]\]`)
 	il := re.FindAllStringIndex(text, -1)
 	for _, p := range il {
 		result.WriteString(text[prevIdx:p[0]])
+		addOrigins(text[prevIdx:p[0]], func(i int) int {
+			return literal(prevIdx + i)
+		})
 		prevIdx = p[1]
 		pair := text[p[0]+2 : p[1]-2] // without "[[" and "]]"
+		callPos := pos + p[0]
 		var name string
 		var data interface{}
-		if i := strings.IndexAny(pair, " \t\n"); i != -1 {
+		if i := strings.IndexAny(pair, " \t\r\n"); i != -1 {
 			name = pair[:i]
 			y := pair[i+1:]
 			if err := yaml.Unmarshal([]byte(y), &data); err != nil {
-				log.Fatalf(
-					"Invalid YAML data in call to template [[%s]] of file %s: %v",
-					pair, s.filename, err)
+				msg, off := yamlErrPos(err, y)
+				return "", s.errorf(KindTemplate, callPos+2+i+1+off,
+					"invalid YAML data in call to template [[%s]]: %s", pair, msg)
 			}
 		} else {
 			name = pair
 		}
 		t := s.templates[name]
 		if t == nil {
-			log.Fatalf("Calling unknown template %s", name)
+			return "", s.errorf(KindTemplate, callPos,
+				"calling unknown template %s", name)
 		}
-		var b strings.Builder
-		if err := t.Execute(&b, data); err != nil {
-			log.Fatalf("Executing template %s: %v", name, err)
+		t.used = true
+		out, err := t.execute(data)
+		if err != nil {
+			return "", s.errorf(KindTemplate, callPos,
+				"executing template %s: %v", name, err)
 		}
-		result.WriteString(b.String())
+		result.WriteString(out)
+		// Lines produced by template are mapped to template call.
+		addOrigins(out, func(int) int { return callPos })
 	}
 	result.WriteString(text[prevIdx:])
+	addOrigins(text[prevIdx:], func(i int) int { return literal(prevIdx + i) })
 	return result.String(), nil
 }
 
+var yamlLineRe = regexp.MustCompile(`line (\d+): `)
+
+// yamlErrPos removes line numbers from message of YAML error and
+// returns byte offset in y of first referenced line.
+func yamlErrPos(err error, y string) (string, int) {
+	msg := err.Error()
+	m := yamlLineRe.FindStringSubmatch(msg)
+	if m == nil {
+		return msg, 0
+	}
+	n, _ := strconv.Atoi(m[1])
+	off := 0
+	for ; n > 1; n-- {
+		i := strings.IndexByte(y[off:], '\n')
+		if i == -1 {
+			break
+		}
+		off += i + 1
+	}
+	return yamlLineRe.ReplaceAllString(msg, ""), off
+}
+
 // Apply one or multiple substitutions to current textblock.
+// Invalid substitutions are skipped and the first error is returned.
 func (s *state) applySubst(text string) (string, error) {
+	var firstErr error
 	for {
 		line := s.getLine()
 		name := s.checkDef(line)
 		if name != "SUBST" {
 			break
 		}
+		pos := s.offset()
 		s.rest = s.rest[len(line):]
 		line = line[len("=SUBST="):]
+		pos += len("=SUBST=") + len(line) - len(strings.TrimLeft(line, " \t"))
 		line = strings.TrimSpace(line)
+		var err error
 		if len(line) == 0 {
-			return "", fmt.Errorf("invalid empty substitution in %s", s.filename)
+			err = s.errorf(KindSubst, pos, "invalid empty substitution")
+		} else {
+			parts := strings.Split(line[1:], line[0:1])
+			if len(parts) != 3 || parts[2] != "" {
+				err = s.errorf(KindSubst, pos,
+					"invalid substitution: =SUBST=%s", line)
+			} else {
+				if !strings.Contains(text, parts[0]) {
+					s.warnf("noop-subst", pos,
+						"substitution =SUBST=%s doesn't match", line)
+				}
+				text = strings.ReplaceAll(text, parts[0], parts[1])
+			}
 		}
-		parts := strings.Split(line[1:], line[0:1])
-		if len(parts) != 3 || parts[2] != "" {
-			return "", errors.New("invalid substitution: =SUBST=" + line)
+		if err != nil && firstErr == nil {
+			firstErr = err
 		}
-		text = strings.ReplaceAll(text, parts[0], parts[1])
 	}
-	return text, nil
+	return text, firstErr
 }
 
 func (s *state) getLine() string {
@@ -381,50 +903,3 @@ func (s *state) getLine() string {
 	}
 	return string(s.rest[:idx+1])
 }
-
-// Create inDir and fill it with files from input.
-// Parts of input are marked by single lines of dashes
-// followed by a filename.
-// If no markers are given, a file named single is created.
-// If single was used it returns path of single, otherwise returns
-// path of inDir.
-func PrepareInDir(t *testing.T, inDir, single, input string) string {
-	if input == "NONE" {
-		input = ""
-	}
-	re := regexp.MustCompile(`(?ms)^-+[ ]*\S+[ ]*\n`)
-	il := re.FindAllStringIndex(input, -1)
-
-	write := func(file, data string) {
-		dir := path.Dir(file)
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			t.Fatalf("Can't create directory for '%s': %v", file, err)
-		}
-		if err := os.WriteFile(file, []byte(data), 0644); err != nil {
-			t.Fatal(err)
-		}
-	}
-
-	// No filename
-	if il == nil {
-		file := path.Join(inDir, single)
-		write(file, input)
-		return file
-	}
-	if il[0][0] != 0 {
-		t.Fatal("Missing file marker in first line")
-	}
-	for i, p := range il {
-		marker := input[p[0] : p[1]-1] // without trailing "\n"
-		pName := strings.Trim(marker, "- ")
-		file := path.Join(inDir, pName)
-		start := p[1]
-		end := len(input)
-		if i+1 < len(il) {
-			end = il[i+1][0]
-		}
-		data := input[start:end]
-		write(file, data)
-	}
-	return inDir
-}
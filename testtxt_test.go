@@ -0,0 +1,254 @@
+package testtxt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// writeTemp writes content to a file with given name in a temporary
+// directory of t and returns its path.
+func writeTemp(t *testing.T, name, content string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// diagStrings returns l in short format for comparison.
+func diagStrings(l []Diagnostic) []string {
+	var result []string
+	for _, d := range l {
+		result = append(result,
+			fmt.Sprintf("%d:%d %s: %s", d.Line, d.Column, d.Code, d.Message))
+	}
+	return result
+}
+
+type parseDescr struct {
+	Title  string
+	Input  string
+	Output string
+	Count  int
+	Ok     bool
+	Env    map[string]string
+}
+
+func TestParseFile(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []parseDescr
+	}{
+		{
+			name: "single line is trimmed",
+			src:  "=TITLE= a \n=INPUT=  x y \n",
+			want: []parseDescr{{Title: "a", Input: "x y"}},
+		},
+		{
+			name: "multi line keeps line endings",
+			src:  "=TITLE=a\n=INPUT=\n x\r\ny\n=OUTPUT=z\n",
+			want: []parseDescr{{Title: "a", Input: " x\r\ny\n", Output: "z"}},
+		},
+		{
+			name: "multi line terminated by END",
+			src:  "=TITLE=a\n=INPUT=\nx\n=END=\n# comment\n\n=TITLE=b\n",
+			want: []parseDescr{{Title: "a", Input: "x\n"}, {Title: "b"}},
+		},
+		{
+			name: "int, bool and map",
+			src:  "=TITLE=a\n=COUNT=42\n=OK=\n=ENV_HOME=/tmp\n=ENV_X=1\n",
+			want: []parseDescr{{Title: "a", Count: 42, Ok: true,
+				Env: map[string]string{"HOME": "/tmp", "X": "1"}}},
+		},
+		{
+			name: "template and substitution",
+			src: "=TEMPL=greet\nHello {{.}}\n=END=\n" +
+				"=TITLE=a\n=OUTPUT=[[greet World]]\n=SUBST=/World/Go/\n",
+			want: []parseDescr{{Title: "a", Output: "Hello Go"}},
+		},
+		{
+			name: "byte order mark",
+			src:  bom + "=TITLE=a\n",
+			want: []parseDescr{{Title: "a"}},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var l []parseDescr
+			if err := ParseFile(writeTemp(t, "x.t", tc.src), &l); err != nil {
+				t.Fatal(err)
+			}
+			if d := cmp.Diff(tc.want, l); d != "" {
+				t.Error(d)
+			}
+		})
+	}
+}
+
+func TestParseFileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		collect bool
+		strict  bool
+		want    []string
+	}{
+		{
+			name: "unexpected attribute",
+			src:  "=TITLE=a\n=UNKNOWN=x\n",
+			want: []string{"2:1 attribute: unexpected =UNKNOWN="},
+		},
+		{
+			name: "duplicate attribute",
+			src:  "=TITLE=a\n=INPUT=x\n=INPUT=y\n",
+			want: []string{"3:1 attribute: found multiple =INPUT= at lines 2 and 3"},
+		},
+		{
+			name: "missing title",
+			src:  "=INPUT=x\n",
+			want: []string{"1:1 attribute: must define =TITLE= before =INPUT="},
+		},
+		{
+			name: "invalid value",
+			src:  "=TITLE=a\n=COUNT=abc\n",
+			want: []string{`2:8 value: invalid value for struct field "Count"` +
+				` of =COUNT=: strconv.ParseInt: parsing "abc": invalid syntax`},
+		},
+		{
+			name: "unknown template",
+			src:  "=TITLE=a\n=INPUT= [[nope]]\n",
+			want: []string{"2:9 template: calling unknown template nope"},
+		},
+		{
+			name: "invalid substitution",
+			src:  "=TITLE=a\n=INPUT=x\n=SUBST= /a\n",
+			want: []string{"3:9 subst: invalid substitution: =SUBST=/a"},
+		},
+		{
+			name: "invalid UTF-8",
+			src:  "=TITLE=a\n=INPUT=\xff\n",
+			want: []string{"2:8 encoding: invalid UTF-8 byte 0xff at offset 16"},
+		},
+		{
+			name: "syntax",
+			src:  "junk\n=TITLE=a\n",
+			want: []string{"1:1 syntax: expected token '=...=': junk"},
+		},
+		{
+			name: "column after byte order mark",
+			src:  bom + "=TITLE=a [[nope]]\n",
+			want: []string{"1:10 template: calling unknown template nope"},
+		},
+		{
+			name: "first error only",
+			src: "=TITLE=a\n=UNKNOWN=x\n=COUNT=abc\n\n" +
+				"=TITLE=b\n=INPUT=[[nope]]\n",
+			want: []string{"2:1 attribute: unexpected =UNKNOWN="},
+		},
+		{
+			name: "collect errors",
+			src: "=TITLE=a\n=UNKNOWN=x\n=COUNT=abc\n\n" +
+				"=TITLE=b\n=INPUT=[[nope]]\n",
+			collect: true,
+			want: []string{
+				"2:1 attribute: unexpected =UNKNOWN=",
+				`3:8 value: invalid value for struct field "Count"` +
+					` of =COUNT=: strconv.ParseInt: parsing "abc": invalid syntax`,
+				"6:8 template: calling unknown template nope",
+			},
+		},
+		{
+			name:   "warning is error in strict mode",
+			src:    "=TITLE=a\n=INPUT=x\n=SUBST=/y/z/\n",
+			strict: true,
+			want:   []string{"3:8 warning: substitution =SUBST=/y/z/ doesn't match"},
+		},
+		{
+			name:   "warning is kept before fatal error in strict mode",
+			src:    "=TITLE=a\n=INPUT=x\n=SUBST=/y/z/\n=UNKNOWN=x\n",
+			strict: true,
+			want: []string{
+				"3:8 warning: substitution =SUBST=/y/z/ doesn't match",
+				"4:1 attribute: unexpected =UNKNOWN=",
+			},
+		},
+		{
+			name: "no error without strict mode",
+			src:  "=TITLE=a\n=INPUT=x\n=SUBST=/y/z/\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var opts []Option
+			if tc.collect {
+				opts = append(opts, CollectErrors())
+			}
+			if tc.strict {
+				opts = append(opts, Strict())
+			}
+			var l []parseDescr
+			err := ParseFile(writeTemp(t, "x.t", tc.src), &l, opts...)
+			if d := cmp.Diff(tc.want, diagStrings(Diagnostics(err))); d != "" {
+				t.Error(d)
+			}
+		})
+	}
+}
+
+func TestParseFileWarnings(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{
+			name: "substitution doesn't match",
+			src:  "=TITLE=a\n=INPUT=x\n=SUBST=/y/z/\n",
+			want: []string{"3:8 noop-subst: substitution =SUBST=/y/z/ doesn't match"},
+		},
+		{
+			name: "unused template",
+			src:  "=TEMPL=t\nx\n=END=\n=TITLE=a\n",
+			want: []string{"1:8 unused-template: unused template t"},
+		},
+		{
+			name: "indented definition",
+			src:  "=TITLE=a\n=INPUT=\nx\n\t=OUTPUT=y\n",
+			want: []string{
+				"4:2 indented-definition: indented =OUTPUT= is taken as part of text",
+			},
+		},
+		{
+			name: "comment before next test",
+			src:  "=TITLE=a\n=INPUT=\nx\n# comment\n=TITLE=b\n",
+			want: []string{"4:1 missing-end: probably missing =END=:" +
+				" comment before =TITLE= at line 5 is part of text"},
+		},
+		{
+			name: "comment after END",
+			src:  "=TITLE=a\n=INPUT=\nx\n=END=\n# comment\n=TITLE=b\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var warnings []Diagnostic
+			var l []parseDescr
+			err := ParseFile(writeTemp(t, "x.t", tc.src), &l,
+				WithWarningHandler(func(d Diagnostic) {
+					warnings = append(warnings, d)
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := cmp.Diff(tc.want, diagStrings(warnings)); d != "" {
+				t.Error(d)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package testtxt
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// TOMLSource converts data, test descriptions in TOML format, into the
+// equivalent source in the format of ParseFile. ParseFile and template
+// files given by WithTemplateFile accept files with extension ".toml"
+// and convert them by TOMLSource. Line numbers in messages about such
+// files refer to the converted source.
+//
+// Each test is a table of array "test", that maps names of attributes,
+// optionally with mode, to their text. Text may be a string, an
+// integer, a boolean or an inline table with keys "text" and "subst".
+// True gives an attribute without text, false omits the attribute.
+// Table "templates" maps names of templates to their text.
+// Text may call templates and is expanded like in native format:
+//
+//	[templates]
+//	greet = "Hello {{.}}"
+//
+//	[[test]]
+//	TITLE = "greeting"
+//	"OUTPUT:RE" = { text = "[[greet x]]", subst = ["/x/World/"] }
+func TOMLSource(data []byte) ([]byte, error) {
+	var doc struct {
+		Templates map[string]string `toml:"templates"`
+		Test      []map[string]any  `toml:"test"`
+	}
+	md, err := toml.Decode(string(data), &doc)
+	if err != nil {
+		return nil, err
+	}
+	// Values of attributes are decoded into type any and may have
+	// undecoded keys of inline tables, which are checked below.
+	for _, k := range md.Undecoded() {
+		if len(k) == 1 {
+			return nil, fmt.Errorf("unexpected key %s", k)
+		}
+	}
+	// Take order of templates and attributes from source.
+	var templates []string
+	var attrs [][]string
+	for _, k := range md.Keys() {
+		switch {
+		case len(k) == 2 && k[0] == "templates":
+			templates = append(templates, k[1])
+		case len(k) == 1 && k[0] == "test":
+			attrs = append(attrs, nil)
+		case len(k) == 2 && k[0] == "test":
+			if i := len(attrs) - 1; !slices.Contains(attrs[i], k[1]) {
+				attrs[i] = append(attrs[i], k[1])
+			}
+		}
+	}
+	var b strings.Builder
+	for _, name := range templates {
+		text := doc.Templates[name]
+		if !isName(name) || name == "" {
+			return nil, fmt.Errorf("invalid name of template: %s", name)
+		}
+		if err := checkDefLines(text); err != nil {
+			return nil, fmt.Errorf("template %s: %v", name, err)
+		}
+		// Parser removes one trailing newline.
+		fmt.Fprintf(&b, "=TEMPL=%s\n%s\n=END=\n\n", name, text)
+	}
+	for i, t := range doc.Test {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		for _, name := range attrs[i] {
+			def, err := tomlAttr(name, t[name])
+			if err != nil {
+				return nil, fmt.Errorf("test %d: =%s=: %v", i+1, name, err)
+			}
+			b.WriteString(def)
+		}
+	}
+	return []byte(b.String()), nil
+}
+
+// tomlError converts err of TOMLSource for file into a ParseError.
+// Errors of TOML syntax have the position in file.
+func tomlError(file string, err error) error {
+	e := &ParseError{File: file, Kind: KindSyntax, Msg: err.Error()}
+	var pe toml.ParseError
+	if errors.As(err, &pe) {
+		e.Line, e.Column, e.Msg = pe.Position.Line, pe.Position.Col, pe.Message
+	}
+	return e
+}
+
+// tomlAttr returns the definition of attribute name with value v of
+// TOML.
+func tomlAttr(name string, v any) (string, error) {
+	var subst []any
+	if m, ok := v.(map[string]any); ok {
+		for k := range m {
+			if k != "text" && k != "subst" {
+				return "", fmt.Errorf("unexpected key %s", k)
+			}
+		}
+		v = m["text"]
+		subst, ok = m["subst"].([]any)
+		if m["subst"] != nil && !ok {
+			return "", fmt.Errorf("subst must be array of strings")
+		}
+	}
+	var text string
+	switch x := v.(type) {
+	case string:
+		text = x
+	case int64:
+		text = fmt.Sprint(x)
+	case bool:
+		if !x {
+			return "", nil
+		}
+	case nil:
+	default:
+		return "", fmt.Errorf("unsupported value of type %T", v)
+	}
+	// =SUBST= terminates text instead of =END=.
+	def, err := formatAttr(name, text, len(subst) == 0)
+	if err != nil {
+		return "", err
+	}
+	for _, s := range subst {
+		str, ok := s.(string)
+		if !ok || strings.Contains(str, "\n") {
+			return "", fmt.Errorf("subst must be array of single line strings")
+		}
+		def += "=SUBST=" + str + "\n"
+	}
+	return def, nil
+}
+
+// checkDefLines returns an error, if text has a line, that would be
+// taken as definition.
+func checkDefLines(text string) error {
+	s := new(state)
+	for _, line := range splitLines(text) {
+		if n := s.checkDef(line); n != "" {
+			return fmt.Errorf("line would be taken as =%s=: %s",
+				n, strings.TrimSuffix(line, "\n"))
+		}
+	}
+	return nil
+}
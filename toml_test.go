@@ -0,0 +1,119 @@
+package testtxt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTOMLSource(t *testing.T) {
+	tests := []struct {
+		name   string
+		toml   string
+		native string
+		values map[string]string
+	}{
+		{
+			name: "single line with subst",
+			toml: `
+[[test]]
+TITLE = "t"
+OUTPUT = { text = "a b", subst = ["/a/x/"] }
+`,
+			native: "=TITLE=t\n=OUTPUT=a b\n=SUBST=/a/x/\n",
+			values: map[string]string{"OUTPUT": "x b"},
+		},
+		{
+			name: "multi line with subst",
+			toml: `
+[[test]]
+TITLE = "t"
+"OUTPUT:RE" = { text = "a\nb\n", subst = ["/a/x/", "|b|y|"] }
+INPUT = "c"
+`,
+			native: "=TITLE=t\n=OUTPUT:RE=\na\nb\n=SUBST=/a/x/\n=SUBST=|b|y|\n" +
+				"=INPUT=c\n",
+			values: map[string]string{"OUTPUT": "x\ny\n", "INPUT": "c"},
+		},
+		{
+			name: "multi line without subst",
+			toml: `
+[[test]]
+TITLE = "t"
+INPUT = "a\n"
+`,
+			native: "=TITLE=t\n=INPUT=\na\n=END=\n",
+			values: map[string]string{"INPUT": "a\n"},
+		},
+		{
+			name: "template and flags",
+			toml: `
+[templates]
+greet = "Hello {{.}}"
+
+[[test]]
+TITLE = "t"
+OUTPUT = "[[greet you]]"
+COUNT = 3
+ON = true
+OFF = false
+`,
+			native: "=TEMPL=greet\nHello {{.}}\n=END=\n\n" +
+				"=TITLE=t\n=OUTPUT=[[greet you]]\n=COUNT=3\n=ON=\n=END=\n",
+			values: map[string]string{
+				"OUTPUT": "Hello you", "COUNT": "3", "ON": "",
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			src, err := TOMLSource([]byte(tc.toml))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(src) != tc.native {
+				t.Errorf("got native source\n%s\nwant\n%s", src, tc.native)
+			}
+			file := filepath.Join(t.TempDir(), "x.toml")
+			if err := os.WriteFile(file, []byte(tc.toml), 0644); err != nil {
+				t.Fatal(err)
+			}
+			l, err := ParseTests(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(l) != 1 {
+				t.Fatalf("got %d tests, want 1", len(l))
+			}
+			for name, want := range tc.values {
+				if got, _ := l[0].Get(name); got != want {
+					t.Errorf("=%s=: got %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTOMLSourceError(t *testing.T) {
+	tests := []struct {
+		name string
+		toml string
+		err  string
+	}{
+		{"unexpected key", "other = 1\n", "unexpected key other"},
+		{"definition in text", "[[test]]\nTITLE = \"t\"\nIN = \"=END=\\n\"\n",
+			"test 1: =IN=: line would be taken as =END=: =END="},
+		{"bad subst", "[[test]]\nTITLE = \"t\"\nIN = { subst = [1] }\n",
+			"test 1: =IN=: subst must be array of single line strings"},
+		{"unexpected inline key", "[[test]]\nTITLE = \"t\"\nIN = { x = 1 }\n",
+			"test 1: =IN=: unexpected key x"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := TOMLSource([]byte(tc.toml))
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("got error %v, want %s", err, tc.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package testtxt
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/tools/txtar"
+)
+
+// ToTxtar returns the files given by input, in the format of input of
+// PrepareInDir, as txtar archive.
+// Input without file markers is stored in a file named "input".
+// Modes and modification times of files are lost.
+// ToTxtar panics, if input is invalid or has directories or symbolic
+// links, or content of a file, that can't be represented in txtar
+// format. Use FormatTxtar to get an error instead.
+func ToTxtar(input string) *txtar.Archive {
+	a, err := toTxtar(input)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+func toTxtar(input string) (*txtar.Archive, error) {
+	if input == "NONE" {
+		input = ""
+	}
+	c := newPrepareConfig(nil)
+	l, err := splitInput(input, c)
+	if err != nil {
+		return nil, err
+	}
+	if l == nil && input != "" {
+		l = []*fileEntry{{name: c.singleName(), data: input}}
+	}
+	a := new(txtar.Archive)
+	for _, e := range l {
+		switch {
+		case e.dir:
+			return nil, fmt.Errorf("can't store directory %s in txtar", e.name)
+		case e.link != "":
+			return nil, fmt.Errorf("can't store symbolic link %s in txtar",
+				e.name)
+		}
+		data := e.data
+		if e.from != "" {
+			d, err := os.ReadFile(e.from)
+			if err != nil {
+				return nil, err
+			}
+			data = string(d)
+		}
+		if data != "" && !strings.HasSuffix(data, "\n") {
+			return nil, fmt.Errorf(
+				"can't store %s in txtar: content must end with newline", e.name)
+		}
+		for _, line := range splitLines(data) {
+			if _, found := txtarMarker(line); found {
+				return nil, fmt.Errorf(
+					"can't store %s in txtar: content has marker line %q",
+					e.name, strings.TrimSuffix(line, "\n"))
+			}
+		}
+		a.Files = append(a.Files, txtar.File{Name: e.name, Data: []byte(data)})
+	}
+	return a, nil
+}
+
+// FromTxtar returns the files of txtar archive a in the format of input
+// of PrepareInDir. The comment of a is ignored. Content, that would be
+// taken as file marker, is stored in base64 encoding. FromTxtar
+// panics, if a has an invalid or duplicate file name or a name with
+// white space. Use ParseTxtar to get an error instead.
+func FromTxtar(a *txtar.Archive) string {
+	s, err := fromTxtar(a)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func fromTxtar(a *txtar.Archive) (string, error) {
+	var l []*fileEntry
+	c := newPrepareConfig(nil)
+	seen := make(map[string]*fileEntry)
+	// Line numbers refer to a in txtar format.
+	line := 1 + strings.Count(string(a.Comment), "\n")
+	for _, f := range a.Files {
+		e := &fileEntry{name: f.Name, data: string(f.Data), line: line}
+		if err := checkEntry(e, c, seen); err != nil {
+			return "", err
+		}
+		l = append(l, e)
+		line += 1 + strings.Count(e.data, "\n")
+	}
+	return entriesToBlocks(l)
+}
+
+// FormatTxtar returns the files given by input like ToTxtar, but as
+// archive in txtar format. It returns an error, where ToTxtar panics.
+func FormatTxtar(input string) ([]byte, error) {
+	a, err := toTxtar(input)
+	if err != nil {
+		return nil, err
+	}
+	return txtar.Format(a), nil
+}
+
+// ParseTxtar returns the files of data in txtar format like FromTxtar.
+// It returns an error, where FromTxtar panics.
+func ParseTxtar(data []byte) (string, error) {
+	return fromTxtar(txtar.Parse(data))
+}
+
+// txtarMarker returns the file name of line, if it is a file marker
+// "-- name --" of txtar format.
+func txtarMarker(line string) (string, bool) {
+	line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+	if !strings.HasPrefix(line, "-- ") || !strings.HasSuffix(line, " --") ||
+		len(line) < len("-- x --") {
+		return "", false
+	}
+	name := strings.TrimSpace(line[3 : len(line)-3])
+	return name, name != ""
+}
@@ -0,0 +1,112 @@
+package testtxt
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/tools/txtar"
+)
+
+func TestTxtar(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string // txtar format
+		back  string // FromTxtar of archive; equals input if empty
+	}{
+		{
+			name:  "single file",
+			input: "a\nb\n",
+			want:  "-- input --\na\nb\n",
+			back:  "---- input\na\nb\n",
+		},
+		{
+			name:  "files",
+			input: "---- a.t\nx\n---- dir/b.t\n---- c\ny\nz\n",
+			want:  "-- a.t --\nx\n-- dir/b.t --\n-- c --\ny\nz\n",
+		},
+		{
+			name:  "no files",
+			input: "NONE",
+			want:  "",
+			back:  "",
+		},
+		{
+			name:  "marker of testtxt in content",
+			input: "---- a (base64)\nLS0tLSBiCg==\n",
+			want:  "-- a --\n---- b\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			a := ToTxtar(tc.input)
+			if d := cmp.Diff(tc.want, string(txtar.Format(a))); d != "" {
+				t.Error(d)
+			}
+			back := tc.back
+			if back == "" && tc.input != "NONE" {
+				back = tc.input
+			}
+			if d := cmp.Diff(back, FromTxtar(a)); d != "" {
+				t.Error(d)
+			}
+			// Round trip through txtar format.
+			got, err := ParseTxtar(txtar.Format(a))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := cmp.Diff(back, got); d != "" {
+				t.Error(d)
+			}
+		})
+	}
+}
+
+func TestTxtarErrors(t *testing.T) {
+	for _, tc := range []struct {
+		input string
+		want  string
+	}{
+		{"---- d/ (dir)\n", "can't store directory d/ in txtar"},
+		{"---- l -> x\n", "can't store symbolic link l in txtar"},
+		{"---- a (base64)\neA==\n",
+			"can't store a in txtar: content must end with newline"},
+		{"---- a (base64)\nLS0gYiAtLQo=\n",
+			`can't store a in txtar: content has marker line "-- b --"`},
+	} {
+		_, err := FormatTxtar(tc.input)
+		if err == nil || err.Error() != tc.want {
+			t.Errorf("FormatTxtar(%q): got error %v, want %q",
+				tc.input, err, tc.want)
+		}
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("ToTxtar(%q) didn't panic", tc.input)
+				}
+			}()
+			ToTxtar(tc.input)
+		}()
+	}
+	for _, tc := range []struct {
+		data string
+		want string
+	}{
+		{"-- a --\n-- a --\n", "duplicate file a at lines 1 and 2"},
+		{"comment\n-- a b --\nx\n", `can't write "a b" in file marker`},
+	} {
+		_, err := ParseTxtar([]byte(tc.data))
+		if err == nil || err.Error() != tc.want {
+			t.Errorf("ParseTxtar(%q): got error %v, want %q",
+				tc.data, err, tc.want)
+		}
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("FromTxtar(%q) didn't panic", tc.data)
+				}
+			}()
+			FromTxtar(txtar.Parse([]byte(tc.data)))
+		}()
+	}
+}
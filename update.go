@@ -0,0 +1,200 @@
+package testtxt
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// CheckOutput compares got with the expected text of attribute attr in
+// the test with given title in file.
+// If they differ, the test fails with a unified diff.
+//
+// In update mode, file is rewritten instead, with got as new text of
+// attr. Update mode is enabled by environment variable TESTTXT_UPDATE
+// with a true value like "1" or by a boolean flag "update" defined by
+// the test package. Comments, templates and other attributes of file
+// are left unchanged. Attributes, that use templates or =SUBST=, can't
+// be updated.
+//
+// An attribute with mode, like =OUTPUT:RE= or =OUTPUT:CONTAINS=, is
+// matched like by MatchOutput. It is never rewritten in update mode.
+//
+// If the test has attribute =ATTR_FILE= instead of =ATTR=, e.g.
+// =OUTPUT_FILE=golden/t1.out, expected text is read from that file,
+// given relative to the directory of file. In update mode, that file
+// is written.
+// It reports whether got matched or file has been updated.
+func CheckOutput(t testing.TB, file, title, attr, got string) bool {
+	t.Helper()
+	test, titleAttr := findTest(t, file, title)
+	if golden, found := test.Get(attr + "_FILE"); found {
+		if _, found := test.Get(attr); found {
+			t.Fatalf("%s: must not use both =%s= and =%s_FILE= in test %q",
+				file, attr, attr, title)
+		}
+		return checkGolden(t, file, strings.TrimSpace(golden), got)
+	}
+	a, _ := test.attr(attr)
+	if a.Mode != "" {
+		mode, found := matchModes[a.Mode]
+		if !found {
+			t.Fatalf("%s: unknown mode of =%s:%s= in test %q",
+				file, attr, a.Mode, title)
+		}
+		ok := newCompareConfig(nil).match(t, attr,
+			Expected{Text: a.Text, Mode: mode}, got)
+		if !ok && updateMode() {
+			t.Logf("Can't update =%s:%s= of test with =%s=%s in %s",
+				attr, a.Mode, titleAttr, title, file)
+		}
+		return ok
+	}
+	expected := a.Text
+	if expected == got {
+		return true
+	}
+	if updateMode() {
+		if err := updateAttr(file, title, attr, got); err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("Updated =%s= of test with =%s=%s in %s",
+			attr, titleAttr, title, file)
+		return true
+	}
+	reportDiff(t, fmt.Sprintf("=%s= differs\n%s", attr,
+		unifiedDiff("expected", "got", expected, got, 3)))
+	return false
+}
+
+// CheckDir compares directory tree gotDir with the files given by
+// attribute attr of test with given title in file, like CompareDir.
+// In update mode, as described for CheckOutput, the attribute is
+// rewritten instead with the files of gotDir in the format of
+// DirToBlocks. Leading "#ignore" lines of the attribute are kept and
+// ignored files are left out.
+// It reports whether the trees matched or file has been updated.
+func CheckDir(t testing.TB, file, title, attr, gotDir string,
+	opts ...CompareOption,
+) bool {
+	t.Helper()
+	expected, titleAttr := expectedText(t, file, title, attr)
+	c := newCompareConfig(opts)
+	diffs, err := compareDir(gotDir, expected, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diffs == nil {
+		return true
+	}
+	if updateMode() {
+		header, _ := splitIgnoreHeader(expected)
+		l, err := c.readEntries(gotDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		blocks, err := entriesToBlocks(l)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := updateAttr(file, title, attr, header+blocks); err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("Updated =%s= of test with =%s=%s in %s",
+			attr, titleAttr, title, file)
+		return true
+	}
+	for _, d := range diffs {
+		reportDiff(t, d)
+	}
+	return false
+}
+
+// expectedText returns text of attribute attr of test with given title
+// in file together with the name of the title attribute.
+func expectedText(t testing.TB, file, title, attr string) (string, string) {
+	t.Helper()
+	test, titleAttr := findTest(t, file, title)
+	text, _ := test.Get(attr)
+	return text, titleAttr
+}
+
+// findTest returns test with given title in file together with the
+// name of the title attribute.
+func findTest(t testing.TB, file, title string) (*Test, string) {
+	t.Helper()
+	updateMutex.RLock()
+	l, err := ParseTests(file)
+	updateMutex.RUnlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	titleAttr := ""
+	for _, test := range l {
+		titleAttr = test.Attrs[0].Name
+		if test.Title == title {
+			return test, titleAttr
+		}
+	}
+	t.Fatalf("%s: missing test with =%s=%s", file, titleAttr, title)
+	return nil, ""
+}
+
+// checkGolden compares got with content of golden file, given relative
+// to directory of description file. In update mode, golden file is
+// written instead.
+func checkGolden(t testing.TB, file, golden, got string) bool {
+	t.Helper()
+	golden = filepath.Join(filepath.Dir(file), filepath.FromSlash(golden))
+	data, err := os.ReadFile(golden)
+	if err != nil && !(updateMode() && errors.Is(err, fs.ErrNotExist)) {
+		t.Fatal(err)
+	}
+	if err == nil && string(data) == got {
+		return true
+	}
+	if updateMode() {
+		if err := os.MkdirAll(filepath.Dir(golden), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("Updated %s", golden)
+		return true
+	}
+	reportDiff(t, fmt.Sprintf("%s differs\n%s", golden,
+		unifiedDiff("expected", "got", string(data), got, 3)))
+	return false
+}
+
+// updateMode reports whether expected text in files should be updated.
+func updateMode() bool {
+	if on, _ := strconv.ParseBool(os.Getenv("TESTTXT_UPDATE")); on {
+		return true
+	}
+	if f := flag.Lookup("update"); f != nil {
+		on, _ := strconv.ParseBool(f.Value.String())
+		return on
+	}
+	return false
+}
+
+// Serializes updates of files from parallel tests and keeps them from
+// reading a file, while it is rewritten.
+var updateMutex sync.RWMutex
+
+// updateAttr sets text of attribute attr in test with given title
+// in file. A missing attribute is added after last attribute of test.
+func updateAttr(file, title, attr, text string) error {
+	updateMutex.Lock()
+	defer updateMutex.Unlock()
+	return RewriteAttr(file, title, attr, text)
+}
@@ -0,0 +1,463 @@
+package testtxt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// recorder is a testing.TB, that records messages instead of
+// reporting them. Other methods are delegated to the embedded test.
+type recorder struct {
+	testing.TB
+	mu     sync.Mutex
+	msgs   []string
+	failed bool
+}
+
+// record runs f with a new recorder for t and returns it.
+// Fatal errors in f stop f, but not t.
+func record(t testing.TB, f func(r *recorder)) *recorder {
+	r := &recorder{TB: t}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f(r)
+	}()
+	<-done
+	return r
+}
+
+func (r *recorder) add(kind, msg string, fail bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.msgs = append(r.msgs, kind+": "+msg)
+	r.failed = r.failed || fail
+}
+
+func (r *recorder) Helper()         {}
+func (r *recorder) Log(args ...any) { r.add("log", fmt.Sprint(args...), false) }
+func (r *recorder) Logf(format string, args ...any) {
+	r.add("log", fmt.Sprintf(format, args...), false)
+}
+func (r *recorder) Error(args ...any) { r.add("error", fmt.Sprint(args...), true) }
+func (r *recorder) Errorf(format string, args ...any) {
+	r.add("error", fmt.Sprintf(format, args...), true)
+}
+func (r *recorder) Fatal(args ...any) {
+	r.add("fatal", fmt.Sprint(args...), true)
+	runtime.Goexit()
+}
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.add("fatal", fmt.Sprintf(format, args...), true)
+	runtime.Goexit()
+}
+func (r *recorder) Failed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.failed
+}
+
+// messages returns recorded messages with dir replaced by "DIR".
+func (r *recorder) messages(dir string) []string {
+	var l []string
+	for _, m := range r.msgs {
+		l = append(l, strings.ReplaceAll(m, dir, "DIR"))
+	}
+	return l
+}
+
+func TestCheckOutput(t *testing.T) {
+	src := "# comment\n=TITLE=a\n=OUTPUT=x\n\n" +
+		"=TITLE=b\n=OUTPUT:RE=x+\n\n" +
+		"=TITLE=c\n=INPUT=i\n"
+	tests := []struct {
+		name   string
+		title  string
+		got    string
+		update bool
+		ok     bool
+		msgs   []string
+		result string // changed source in update mode
+	}{
+		{
+			name:  "equal",
+			title: "a",
+			got:   "x",
+			ok:    true,
+		},
+		{
+			name:  "differs",
+			title: "a",
+			got:   "y",
+			msgs: []string{"error: =OUTPUT= differs\n" +
+				"--- expected\n+++ got\n@@ -1 +1 @@\n-x\n\\ No newline at end of file\n" +
+				"+y\n\\ No newline at end of file\n"},
+		},
+		{
+			name:   "update",
+			title:  "a",
+			got:    "y\nz\n",
+			update: true,
+			ok:     true,
+			msgs:   []string{"log: Updated =OUTPUT= of test with =TITLE=a in DIR/x.t"},
+			result: "# comment\n=TITLE=a\n=OUTPUT=\ny\nz\n=END=\n\n" +
+				"=TITLE=b\n=OUTPUT:RE=x+\n\n" +
+				"=TITLE=c\n=INPUT=i\n",
+		},
+		{
+			name:   "add missing attribute",
+			title:  "c",
+			got:    "y\n",
+			update: true,
+			ok:     true,
+			msgs:   []string{"log: Updated =OUTPUT= of test with =TITLE=c in DIR/x.t"},
+			result: src + "=OUTPUT=\ny\n=END=\n",
+		},
+		{
+			name:  "regular expression matches",
+			title: "b",
+			got:   "xxx",
+			ok:    true,
+		},
+		{
+			name:  "regular expression doesn't match",
+			title: "b",
+			got:   "y",
+			msgs: []string{"error: OUTPUT: line 1 doesn't match regular expression\n" +
+				"pattern: \"x+\"\ngot:     \"y\""},
+		},
+		{
+			name:   "regular expression isn't updated",
+			title:  "b",
+			got:    "y",
+			update: true,
+			msgs: []string{
+				"error: OUTPUT: line 1 doesn't match regular expression\n" +
+					"pattern: \"x+\"\ngot:     \"y\"",
+				"log: Can't update =OUTPUT:RE= of test with =TITLE=b in DIR/x.t",
+			},
+		},
+		{
+			name:  "missing test",
+			title: "d",
+			got:   "x",
+			msgs:  []string{"fatal: DIR/x.t: missing test with =TITLE=d"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.update {
+				t.Setenv("TESTTXT_UPDATE", "1")
+			}
+			dir := t.TempDir()
+			file := filepath.Join(dir, "x.t")
+			if err := os.WriteFile(file, []byte(src), 0644); err != nil {
+				t.Fatal(err)
+			}
+			var ok bool
+			r := record(t, func(r *recorder) {
+				ok = CheckOutput(r, file, tc.title, "OUTPUT", tc.got)
+			})
+			if ok != tc.ok {
+				t.Errorf("got result %v", ok)
+			}
+			if d := cmp.Diff(tc.msgs, r.messages(dir)); d != "" {
+				t.Error(d)
+			}
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := tc.result
+			if want == "" {
+				want = src
+			}
+			if d := cmp.Diff(want, string(data)); d != "" {
+				t.Error(d)
+			}
+			// Updated file passes the check.
+			if tc.result != "" {
+				r := record(t, func(r *recorder) {
+					CheckOutput(r, file, tc.title, "OUTPUT", tc.got)
+				})
+				if r.msgs != nil {
+					t.Errorf("unexpected messages after update: %q", r.msgs)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckOutputParallel(t *testing.T) {
+	t.Setenv("TESTTXT_UPDATE", "1")
+	var src, want strings.Builder
+	n := 20
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&src, "=TITLE=t%d\n=INPUT=%d\n=OUTPUT=old\n\n", i, i)
+		fmt.Fprintf(&want, "=TITLE=t%d\n=INPUT=%d\n=OUTPUT=new %d\n\n", i, i, i)
+	}
+	file := filepath.Join(t.TempDir(), "x.t")
+	if err := os.WriteFile(file, []byte(src.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			r := record(t, func(r *recorder) {
+				CheckOutput(r, file, fmt.Sprintf("t%d", i), "OUTPUT",
+					fmt.Sprintf("new %d", i))
+			})
+			if r.Failed() {
+				t.Errorf("t%d: %q", i, r.msgs)
+			}
+		}(i)
+	}
+	wg.Wait()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(want.String(), string(data)); d != "" {
+		t.Error(d)
+	}
+}
+
+func TestCheckDir(t *testing.T) {
+	src := "=TITLE=a\n=FILES=\n#ignore *.log\n---- x\n1\n=END=\n\n" +
+		"=TITLE=b\n=INPUT=i\n"
+	tests := []struct {
+		name   string
+		title  string
+		update bool
+		ok     bool
+		msgs   []string
+		result string // changed source in update mode
+	}{
+		{
+			name:  "differs",
+			title: "a",
+			msgs: []string{
+				"error: --- expected/x\n+++ got/x\n@@ -1 +1 @@\n-1\n+2\n",
+				"error: unexpected directory d",
+			},
+		},
+		{
+			name:   "update",
+			title:  "a",
+			update: true,
+			ok:     true,
+			msgs:   []string{"log: Updated =FILES= of test with =TITLE=a in DIR/x.t"},
+			result: "=TITLE=a\n=FILES=\n#ignore *.log\n---- d/ (dir)\n---- x\n2\n" +
+				"=END=\n\n=TITLE=b\n=INPUT=i\n",
+		},
+		{
+			name:   "add missing attribute",
+			title:  "b",
+			update: true,
+			ok:     true,
+			msgs:   []string{"log: Updated =FILES= of test with =TITLE=b in DIR/x.t"},
+			result: src + "=FILES=\n---- a.log\n---- d/ (dir)\n---- x\n2\n=END=\n",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.update {
+				t.Setenv("TESTTXT_UPDATE", "1")
+			}
+			dir := t.TempDir()
+			file := filepath.Join(dir, "x.t")
+			if err := os.WriteFile(file, []byte(src), 0644); err != nil {
+				t.Fatal(err)
+			}
+			gotDir := filepath.Join(dir, "got")
+			_, err := PrepareInDirE(gotDir, "",
+				"---- x\n2\n---- a.log\n---- d/ (dir)\n")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ok bool
+			r := record(t, func(r *recorder) {
+				ok = CheckDir(r, file, tc.title, "FILES", gotDir)
+			})
+			if ok != tc.ok {
+				t.Errorf("got result %v", ok)
+			}
+			if d := cmp.Diff(tc.msgs, r.messages(dir)); d != "" {
+				t.Error(d)
+			}
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := tc.result
+			if want == "" {
+				want = src
+			}
+			if d := cmp.Diff(want, string(data)); d != "" {
+				t.Error(d)
+			}
+			if tc.result != "" {
+				r := record(t, func(r *recorder) {
+					CheckDir(r, file, tc.title, "FILES", gotDir)
+				})
+				if r.msgs != nil {
+					t.Errorf("unexpected messages after update: %q", r.msgs)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckDirParallel(t *testing.T) {
+	t.Setenv("TESTTXT_UPDATE", "1")
+	var src, want strings.Builder
+	n := 10
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&src, "=TITLE=t%d\n=FILES=\n---- x\n=END=\n\n", i)
+		fmt.Fprintf(&want, "=TITLE=t%d\n=FILES=\n---- x\n%d\n=END=\n\n", i, i)
+	}
+	dir := t.TempDir()
+	file := filepath.Join(dir, "x.t")
+	if err := os.WriteFile(file, []byte(src.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		gotDir := filepath.Join(dir, fmt.Sprint(i))
+		_, err := PrepareInDirE(gotDir, "", fmt.Sprintf("---- x\n%d\n", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			r := record(t, func(r *recorder) {
+				CheckDir(r, file, fmt.Sprintf("t%d", i), "FILES", gotDir)
+			})
+			if r.Failed() {
+				t.Errorf("t%d: %q", i, r.msgs)
+			}
+		}(i)
+	}
+	wg.Wait()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(want.String(), string(data)); d != "" {
+		t.Error(d)
+	}
+}
+
+func TestCheckOutputGolden(t *testing.T) {
+	src := "=TITLE=a\n=OUTPUT_FILE=golden/a.out\n\n" +
+		"=TITLE=b\n=OUTPUT=x\n=OUTPUT_FILE=b.out\n"
+	tests := []struct {
+		name   string
+		title  string
+		golden string // initial content of golden file, "-" if missing
+		update bool
+		ok     bool
+		msgs   []string
+	}{
+		{
+			name:   "equal",
+			title:  "a",
+			golden: "x\n",
+			ok:     true,
+		},
+		{
+			name:   "differs",
+			title:  "a",
+			golden: "y\n",
+			msgs: []string{"error: DIR/golden/a.out differs\n" +
+				"--- expected\n+++ got\n@@ -1 +1 @@\n-y\n+x\n"},
+		},
+		{
+			name:   "missing",
+			title:  "a",
+			golden: "-",
+			msgs: []string{
+				"fatal: open DIR/golden/a.out: no such file or directory",
+			},
+		},
+		{
+			name:   "update",
+			title:  "a",
+			golden: "y\n",
+			update: true,
+			ok:     true,
+			msgs:   []string{"log: Updated DIR/golden/a.out"},
+		},
+		{
+			name:   "create in update mode",
+			title:  "a",
+			golden: "-",
+			update: true,
+			ok:     true,
+			msgs:   []string{"log: Updated DIR/golden/a.out"},
+		},
+		{
+			name:   "both attributes",
+			title:  "b",
+			golden: "-",
+			msgs: []string{"fatal: DIR/x.t: must not use both =OUTPUT=" +
+				` and =OUTPUT_FILE= in test "b"`},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.update {
+				t.Setenv("TESTTXT_UPDATE", "1")
+			}
+			dir := t.TempDir()
+			file := filepath.Join(dir, "x.t")
+			if err := os.WriteFile(file, []byte(src), 0644); err != nil {
+				t.Fatal(err)
+			}
+			golden := filepath.Join(dir, "golden", "a.out")
+			if tc.golden != "-" {
+				_, err := PrepareInDirE(dir, "", "---- golden/a.out\n"+tc.golden)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			var ok bool
+			r := record(t, func(r *recorder) {
+				ok = CheckOutput(r, file, tc.title, "OUTPUT", "x\n")
+			})
+			if ok != tc.ok {
+				t.Errorf("got result %v", ok)
+			}
+			if d := cmp.Diff(tc.msgs, r.messages(dir)); d != "" {
+				t.Error(d)
+			}
+			if tc.update {
+				data, err := os.ReadFile(golden)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(data) != "x\n" {
+					t.Errorf("golden file has %q", data)
+				}
+			}
+			// Description file is never changed.
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != src {
+				t.Errorf("description changed to %q", data)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package testtxt
+
+// Test is a test description, that has been parsed without a target
+// struct by ParseTests.
+type Test struct {
+	Title string
+	// Attributes in order of file, starting with the title attribute.
+	Attrs []Attr
+}
+
+// Attr is an attribute of a Test.
+type Attr struct {
+	Name string
+	// Mode of attribute, e.g. "RE" from =OUTPUT:RE=, empty if not given.
+	Mode string
+	// Text after expansion of templates and substitutions.
+	Text string
+	// Line of definition =NAME= in file.
+	Line int
+}
+
+// Get returns the text of attribute name of t and reports whether it
+// is defined.
+func (t *Test) Get(name string) (string, bool) {
+	a, found := t.attr(name)
+	return a.Text, found
+}
+
+// attr returns attribute name of t and reports whether it is defined.
+func (t *Test) attr(name string) (Attr, bool) {
+	for _, a := range t.Attrs {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Attr{}, false
+}
+
+// ParseTests parses file like ParseFile, but without a target struct.
+// Each attribute is accepted. The title attribute is the first
+// attribute defined in file.
+func ParseTests(file string, opts ...Option) ([]*Test, error) {
+	var l []*Test
+	hook := func(s *state, name, mode, text string, pos int) error {
+		i := s.slice.Len() - 1
+		for len(l) <= i {
+			l = append(l, &Test{Title: s.title})
+		}
+		line, _ := s.position(pos)
+		l[i].Attrs = append(l[i].Attrs,
+			Attr{Name: name, Mode: mode, Text: text, Line: line})
+		return nil
+	}
+	var target []struct{ Title string }
+	opts = append(opts, func(c *config) { c.valueHook = hook })
+	err := ParseFile(file, &target, opts...)
+	return l, err
+}
@@ -0,0 +1,80 @@
+package testtxt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CompareYAML compares YAML stream got with expected YAML text like
+// CompareJSON. Both may consist of multiple documents, which are
+// compared in order. Paths of option IgnorePaths apply to each
+// document.
+func CompareYAML(t testing.TB, expected string, got []byte,
+	opts ...CompareOption,
+) bool {
+	t.Helper()
+	c := newCompareConfig(opts)
+	want, err := decodeYAML([]byte(expected))
+	if err != nil {
+		t.Fatalf("invalid expected YAML: %v", err)
+	}
+	have, err := decodeYAML(got)
+	if err != nil {
+		t.Errorf("invalid YAML: %v\n%s", err, got)
+		return false
+	}
+	return c.compareDocs(t, want, have, canonicalYAML)
+}
+
+// decodeYAML decodes all documents of data.
+// Keys of mappings are converted to strings.
+func decodeYAML(data []byte) ([]any, error) {
+	var l []any
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var v any
+		err := dec.Decode(&v)
+		if errors.Is(err, io.EOF) {
+			return l, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		l = append(l, stringKeys(v))
+	}
+}
+
+// stringKeys converts mappings with keys of other type than string.
+func stringKeys(v any) any {
+	switch x := v.(type) {
+	case map[string]any:
+		for k, elem := range x {
+			x[k] = stringKeys(elem)
+		}
+	case map[any]any:
+		m := make(map[string]any, len(x))
+		for k, elem := range x {
+			m[fmt.Sprint(k)] = stringKeys(elem)
+		}
+		return m
+	case []any:
+		for i, elem := range x {
+			x[i] = stringKeys(elem)
+		}
+	}
+	return v
+}
+
+// canonicalYAML returns v as YAML with sorted keys.
+func canonicalYAML(v any) string {
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return err.Error()
+	}
+	return string(b)
+}
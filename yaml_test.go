@@ -0,0 +1,74 @@
+package testtxt
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCompareYAML(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []CompareOption
+		expected string
+		got      string
+		msgs     []string
+	}{
+		{
+			name:     "order of keys and formatting",
+			expected: "a: 1\nb: [x, y]\n",
+			got:      "b:\n  - x\n  - y\na: 1\n",
+		},
+		{
+			name:     "non string keys",
+			expected: "1: a\ntrue: b\n",
+			got:      "\"1\": a\n\"true\": b\n",
+		},
+		{
+			name:     "multiple documents",
+			expected: "a: 1\n---\nb: 2\n",
+			got:      "---\na: 1\n---\nb: 2\n",
+		},
+		{
+			name:     "documents in other order",
+			expected: "a: 1\n---\nb: 2\n",
+			got:      "b: 2\n---\na: 1\n",
+			msgs: []string{"error: --- expected\n+++ got\n" +
+				"@@ -1,3 +1,3 @@\n-a: 1\n----\n b: 2\n+---\n+a: 1\n"},
+		},
+		{
+			name:     "ignore paths in each document",
+			opts:     []CompareOption{IgnorePaths("/t")},
+			expected: "a: 1\nt: 5\n---\nb: 2\n",
+			got:      "a: 1\nt: 6\n---\nb: 2\nt: 7\n",
+		},
+		{
+			name:     "invalid YAML",
+			expected: "a: 1\n",
+			got:      "a: [\n",
+			msgs: []string{"error: invalid YAML: " +
+				"yaml: line 1: did not find expected node content\na: [\n"},
+		},
+		{
+			name:     "invalid expected YAML",
+			expected: "a: [\n",
+			got:      "a: 1\n",
+			msgs: []string{"fatal: invalid expected YAML: " +
+				"yaml: line 1: did not find expected node content"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var ok bool
+			r := record(t, func(r *recorder) {
+				ok = CompareYAML(r, tc.expected, []byte(tc.got), tc.opts...)
+			})
+			if ok != (tc.msgs == nil) {
+				t.Errorf("got result %v", ok)
+			}
+			if d := cmp.Diff(tc.msgs, r.msgs); d != "" {
+				t.Error(d)
+			}
+		})
+	}
+}